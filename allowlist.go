@@ -0,0 +1,299 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+const (
+	logTag  = "log"
+	logSafe = "safe"
+)
+
+// SafeValue is value declared safe to be written in allowlist mode, see Safe.
+type SafeValue struct {
+	value interface{}
+}
+
+// Safe declare value safe to be written in allowlist mode (see WithAllowlistMode), such as
+// ToField("orderId", logger.Safe(orderID)). The whole value is written, but its `mask` tag is still masked.
+// Outside allowlist mode it is the same as the value itself.
+func Safe(v interface{}) SafeValue {
+	return SafeValue{value: v}
+}
+
+// MarshalJSON write the value itself, used when masking is disabled
+func (s SafeValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.value)
+}
+
+var typeSafeValue = reflect.TypeOf(SafeValue{})
+
+var (
+	safeFieldsMu sync.RWMutex
+	safeFields   = make(map[reflect.Type]map[string]struct{}) // registered by RegisterSafeFields
+)
+
+// RegisterSafeFields declare fields of struct type of v safe to be written in allowlist mode,
+// for type which cannot have `log:"safe"` tag such as type from other package.
+// Field is the key written in log, which is json tag name or field name. It is safe to call concurrently,
+// but usually called in init. It panics when v is not a struct or field does not exist.
+func RegisterSafeFields(v interface{}, fields ...string) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("logger: safe fields type %T is not a struct", v))
+	}
+
+	names := make(map[string]struct{})
+	for _, f := range jsonFields(t) {
+		names[f.name] = struct{}{}
+	}
+
+	safeFieldsMu.Lock()
+	defer safeFieldsMu.Unlock()
+
+	registered := make(map[string]struct{}, len(safeFields[t])+len(fields))
+	for name := range safeFields[t] {
+		registered[name] = struct{}{}
+	}
+
+	for _, name := range fields {
+		if _, ok := names[name]; !ok {
+			panic(fmt.Sprintf("logger: safe field %s.%s does not exist", t, name))
+		}
+
+		registered[name] = struct{}{}
+	}
+
+	// replaced instead of modified, so reader never need to hold lock while walking
+	safeFields[t] = registered
+}
+
+func registeredSafeFields(t reflect.Type) map[string]struct{} {
+	safeFieldsMu.RLock()
+	defer safeFieldsMu.RUnlock()
+
+	return safeFields[t]
+}
+
+// placeholder replace value which is not declared safe, so only its type is written
+func placeholder(v reflect.Value) string {
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	return "[" + v.Type().String() + "]"
+}
+
+// allowed write value in allowlist mode: struct field is written only when it is declared safe
+// and other value is replaced by placeholder, while slice, array and map is walked element by element.
+func (w *maskWalker) allowed(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	// value declared safe is masked as usual, the same as outside allowlist mode
+	if v.Type() == typeSafeValue {
+		return w.value(v)
+	}
+
+	// masked representation is walked as well, so only value declared safe in it is written.
+	// Generated OptionsMasker is walked as the struct itself, so its safe fields are the same as using reflection.
+	plan := maskPlans.plan(v.Type())
+	if plan.objectMasker || (plan.masker && !v.Type().Implements(typeOptionsMasker)) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return nil
+		}
+
+		return w.container(v, func() interface{} {
+			return w.allowed(reflect.ValueOf(w.value(v)))
+		})
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+
+		return w.allowed(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+
+		return w.reference(v, func() interface{} {
+			return w.allowed(v.Elem())
+		})
+	case reflect.Struct:
+		if plan.marshaler {
+			return placeholder(v)
+		}
+
+		return w.container(v, func() interface{} {
+			return w.allowedFields(v, plan.fields)
+		})
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+
+		return w.reference(v, func() interface{} {
+			return w.mapValues(v, w.allowed)
+		})
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return placeholder(v)
+		}
+
+		return w.reference(v, func() interface{} {
+			return w.elems(v, w.allowed)
+		})
+	case reflect.Array:
+		return w.container(v, func() interface{} {
+			return w.elems(v, w.allowed)
+		})
+	}
+
+	return placeholder(v)
+}
+
+// safe write value of safe field, but nested struct field still need to be declared safe
+func (w *maskWalker) safe(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	plan := maskPlans.plan(v.Type())
+	if plan.masker || plan.objectMasker {
+		return w.allowed(v)
+	}
+
+	if plan.marshaler {
+		return w.value(v)
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+
+		return w.safe(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+
+		return w.reference(v, func() interface{} {
+			return w.safe(v.Elem())
+		})
+	case reflect.Struct:
+		return w.allowed(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+
+		return w.reference(v, func() interface{} {
+			return w.mapValues(v, w.safe)
+		})
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+
+		return w.reference(v, func() interface{} {
+			return w.elems(v, w.safe)
+		})
+	case reflect.Array:
+		return w.container(v, func() interface{} {
+			return w.elems(v, w.safe)
+		})
+	}
+
+	return v.Interface()
+}
+
+func (w *maskWalker) allowedFields(v reflect.Value, fields []jsonField) interface{} {
+	altered := make(maskedStruct, 0, len(fields))
+	include := w.opts.unexported == UnexportedInclude
+	registered := registeredSafeFields(v.Type())
+
+	for _, f := range fields {
+		if f.unexported && !include {
+			continue
+		}
+
+		if f.readOnly && !v.CanAddr() {
+			v = addressable(v)
+		}
+
+		field, ok := readField(v, f)
+		if !ok || (f.omitEmpty && isEmptyValue(field)) {
+			continue
+		}
+
+		if _, ok = registered[f.name]; !ok && !f.safe {
+			altered = append(altered, maskedField{key: f.name, value: placeholder(field)})
+			continue
+		}
+
+		switch {
+		case f.tagged:
+			altered = append(altered, maskedField{key: f.name, value: w.tagged(field, f.tag)})
+		case f.quoted:
+			altered = append(altered, maskedField{key: f.name, value: quoteValue(field)})
+		default:
+			altered = append(altered, maskedField{key: f.name, value: w.safe(field)})
+		}
+	}
+
+	return altered
+}
+
+// allowlistReport returns safe fields of each type, written on startup in allowlist mode,
+// so reviewer can see what is written for each type. Type without safe field is written as placeholder.
+func allowlistReport(types []reflect.Type) map[string][]string {
+	safeFieldsMu.RLock()
+	all := make([]reflect.Type, 0, len(types)+len(safeFields))
+	for t := range safeFields {
+		all = append(all, t)
+	}
+	safeFieldsMu.RUnlock()
+
+	report := make(map[string][]string, len(all)+len(types))
+	for _, t := range append(all, types...) {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if t.Kind() != reflect.Struct {
+			continue
+		}
+
+		registered := registeredSafeFields(t)
+		fields := make([]string, 0)
+		for _, f := range jsonFields(t) {
+			if _, ok := registered[f.name]; ok || f.safe {
+				fields = append(fields, f.name)
+			}
+		}
+
+		sort.Strings(fields)
+		report[t.String()] = fields
+	}
+
+	return report
+}
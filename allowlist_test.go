@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type allowPayment struct {
+	ID        string            `json:"id" log:"safe"`
+	Amount    int64             `json:"amount" log:"safe"`
+	Card      string            `json:"card"`
+	Phone     string            `json:"phone" log:"safe" mask:"phone"`
+	Tags      []string          `json:"tags" log:"safe"`
+	Customer  *allowCustomer    `json:"customer" log:"safe"`
+	Meta      map[string]string `json:"meta"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Items     []allowCustomer   `json:"items" log:"safe"`
+	Note      interface{}       `json:"note"`
+}
+
+type allowCustomer struct {
+	Name string `json:"name"`
+	Tier string `json:"tier" log:"safe"`
+}
+
+// allowExternal has no log tag, like type from other package
+type allowExternal struct {
+	Code   string `json:"code"`
+	Secret string `json:"secret"`
+}
+
+func init() {
+	RegisterSafeFields(allowExternal{}, "code")
+}
+
+// allowMasker declare its own safe value in masked representation
+type allowMasker struct {
+	Card string
+}
+
+func (a allowMasker) LogMasked() interface{} {
+	return map[string]interface{}{
+		"card":   Safe("card ending " + a.Card[len(a.Card)-4:]),
+		"issuer": "visa",
+	}
+}
+
+func TestMasking_Allowlist(t *testing.T) {
+	opts := &maskOptions{allowlist: true, maxDepth: defaultMaskDepth}
+	payment := allowPayment{
+		ID:        "pay-1",
+		Amount:    10000,
+		Card:      "4111111111111111",
+		Phone:     "08123456789",
+		Tags:      []string{"a", "b"},
+		Customer:  &allowCustomer{Name: "John", Tier: "gold"},
+		Meta:      map[string]string{"k": "v"},
+		CreatedAt: time.Unix(0, 0),
+		Items:     []allowCustomer{{Name: "Jane", Tier: "silver"}},
+		Note:      "free text",
+	}
+
+	actual, err := json.Marshal(masking(payment, opts))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "pay-1",
+		"amount": 10000,
+		"card": "[string]",
+		"phone": "XXXXXXXX6789",
+		"tags": ["a", "b"],
+		"customer": {"name": "[string]", "tier": "gold"},
+		"meta": "[map[string]string]",
+		"createdAt": "[time.Time]",
+		"items": [{"name": "[string]", "tier": "silver"}],
+		"note": "[string]"
+	}`, string(actual))
+
+	t.Run("registered", func(t *testing.T) {
+		actual, err := json.Marshal(masking(&allowExternal{Code: "c", Secret: "s"}, opts))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"code": "c", "secret": "[string]"}`, string(actual))
+	})
+
+	t.Run("not struct", func(t *testing.T) {
+		assert.Equal(t, "[string]", masking("4111111111111111", opts))
+		assert.Equal(t, []interface{}{"[int]"}, masking([]int{1}, opts))
+		assert.Nil(t, masking((*allowPayment)(nil), opts))
+	})
+
+	t.Run("safe value", func(t *testing.T) {
+		assert.Equal(t, "order-1", masking(Safe("order-1"), opts))
+		assert.Equal(t, allowCustomer{Name: "John", Tier: "gold"}, masking(Safe(allowCustomer{Name: "John", Tier: "gold"}), opts))
+		actual, err := json.Marshal(masking(Safe(struct {
+			PIN string `json:"pin" mask:"pin"`
+		}{PIN: "123456"}), opts))
+		require.NoError(t, err)
+		assert.Equal(t, `{"pin":"`+pinMask+`"}`, string(actual))
+	})
+
+	t.Run("invalid registration", func(t *testing.T) {
+		assert.Panics(t, func() { RegisterSafeFields("string", "a") })
+		assert.Panics(t, func() { RegisterSafeFields(allowExternal{}, "Code") })
+	})
+}
+
+func TestDefaultLogger_AllowlistMode(t *testing.T) {
+	writer := &linesWriter{}
+	log, err := newLogger(WithCustomWriter(writer), WithAllowlistMode(allowPayment{}, &allowCustomer{}))
+	require.NoError(t, err)
+
+	t.Run("startup report", func(t *testing.T) {
+		require.NotEmpty(t, writer.lines)
+
+		var record struct {
+			SafeFields map[string][]string `json:"safeFields"`
+		}
+
+		require.NoError(t, json.Unmarshal([]byte(writer.lines[0]), &record))
+		assert.Equal(t, []string{"amount", "customer", "id", "items", "phone", "tags"}, record.SafeFields["logger.allowPayment"])
+		assert.Equal(t, []string{"tier"}, record.SafeFields["logger.allowCustomer"])
+		assert.Equal(t, []string{"code"}, record.SafeFields["logger.allowExternal"])
+	})
+
+	t.Run("field", func(t *testing.T) {
+		log.Info(ctx, logMessage,
+			ToField("customer", allowCustomer{Name: "John", Tier: "gold"}),
+			ToField("raw", "card 4111111111111111"),
+			ToField("json", `{"card":"4111111111111111"}`),
+			ToField("orderId", Safe("order-1")),
+		)
+
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(writer.lines[len(writer.lines)-1]), &record))
+		assert.Equal(t, map[string]interface{}{"name": "[string]", "tier": "gold"}, record["customer"])
+		assert.Equal(t, "[string]", record["raw"])
+		assert.Equal(t, map[string]interface{}{"card": "[string]"}, record["json"])
+		assert.Equal(t, "order-1", record["orderId"])
+	})
+
+	t.Run("string", func(t *testing.T) {
+		log.Info(ctx, logMessage,
+			ToField("number", "123"),
+			ToField("bool", "true"),
+			ToField("null", "null"),
+			ToField("array", ` [{"card":"4111111111111111"}]`),
+		)
+
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(writer.lines[len(writer.lines)-1]), &record))
+		assert.Equal(t, "[string]", record["number"])
+		assert.Equal(t, "[string]", record["bool"])
+		assert.Equal(t, "[string]", record["null"])
+		assert.Equal(t, []interface{}{map[string]interface{}{"card": "[string]"}}, record["array"])
+	})
+
+	t.Run("masker", func(t *testing.T) {
+		log.Info(ctx, logMessage,
+			ToField("masker", planMasker{Card: "4111111111111111"}),
+			ToField("objectMasker", &planObjectMasker{Account: "1234567890", Amount: 10}),
+			ToField("safeMasker", allowMasker{Card: "4111111111111111"}),
+			ToField("safeField", struct {
+				Card planMasker `json:"card" log:"safe"`
+			}{Card: planMasker{Card: "4111111111111111"}}),
+		)
+
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(writer.lines[len(writer.lines)-1]), &record))
+		assert.Equal(t, "[string]", record["masker"])
+		assert.Equal(t, map[string]interface{}{"account": "[string]", "amount": "[int64]"}, record["objectMasker"])
+		assert.Equal(t, map[string]interface{}{"card": "card ending 1111", "issuer": "[string]"}, record["safeMasker"])
+		assert.Equal(t, map[string]interface{}{"card": "[string]"}, record["safeField"])
+	})
+
+	t.Run("tdr", func(t *testing.T) {
+		log.TDR(ctx, LogTdrModel{
+			Request:        `{"card":"4111111111111111"}`,
+			Response:       allowCustomer{Name: "John", Tier: "gold"},
+			AdditionalData: map[string]interface{}{"amount": 10},
+		})
+
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(writer.lines[len(writer.lines)-1]), &record))
+		assert.Equal(t, map[string]interface{}{"card": "[string]"}, record["req"])
+		assert.Equal(t, map[string]interface{}{"name": "[string]", "tier": "gold"}, record["resp"])
+		assert.Equal(t, map[string]interface{}{"amount": "[int]"}, record["addData"])
+	})
+}
+
+func TestSafe_MaskDisabled(t *testing.T) {
+	writer := &testAssertionLogger{}
+	log, err := newLogger(WithCustomWriter(writer))
+	require.NoError(t, err)
+
+	log.Info(ctx, logMessage, ToField("orderId", Safe("order-1")))
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(writer.GetActualData(), &record))
+	assert.Equal(t, "order-1", record["orderId"])
+}
@@ -0,0 +1,237 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultBatchSize     = 500
+	defaultFlushInterval = time.Second
+	defaultBufferSize    = 10000
+	defaultMaxRetries    = 3
+	defaultRetryBackoff  = 100 * time.Millisecond
+	maxRetryBackoff      = 10 * time.Second
+)
+
+// ErrWriterClosed is returned when writing into closed batch writer.
+var ErrWriterClosed = errors.New("logger: writer is closed")
+
+// sendBatch send records to remote output, err is not nil when one of records is not sent.
+// It returns records which should be retried, such as records rejected by server because of rate limit,
+// or nil when the error is permanent, so the batch is dropped.
+type sendBatch func(records [][]byte) (retry [][]byte, err error)
+
+// batchConfig is shared by every remote output which send records in batch
+type batchConfig struct {
+	size          int           // max records of each batch
+	flushInterval time.Duration // max time record is buffered before sent
+	bufferSize    int           // max buffered records, new record is dropped when it is full
+	maxRetries    int
+	retryBackoff  time.Duration // doubled on each retry
+	onError       func(error)
+}
+
+func (c batchConfig) withDefault() batchConfig {
+	if c.size <= 0 {
+		c.size = defaultBatchSize
+	}
+
+	if c.flushInterval <= 0 {
+		c.flushInterval = defaultFlushInterval
+	}
+
+	if c.bufferSize <= 0 {
+		c.bufferSize = defaultBufferSize
+	}
+
+	if c.maxRetries < 0 {
+		c.maxRetries = 0
+	} else if c.maxRetries == 0 {
+		c.maxRetries = defaultMaxRetries
+	}
+
+	if c.retryBackoff <= 0 {
+		c.retryBackoff = defaultRetryBackoff
+	}
+
+	if c.onError == nil {
+		c.onError = func(err error) {
+			_, _ = fmt.Fprintf(os.Stderr, "logger: %s\n", err)
+		}
+	}
+
+	return c
+}
+
+// batchWriter buffer each record written by logger and send it in batch on background, so slow output
+// never block the application. When the buffer is full, new record is dropped and reported to onError.
+// Sync send buffered records and wait, it is called by zap before exit on Fatal. Close send the remaining records.
+type batchWriter struct {
+	conf batchConfig
+	send sendBatch
+
+	records chan []byte
+	syncs   chan chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	mu      sync.RWMutex // guard records channel from being written after closed
+	closed  bool
+	dropped int64
+}
+
+var _ io.WriteCloser = (*batchWriter)(nil)
+
+func newBatchWriter(conf batchConfig, send sendBatch) *batchWriter {
+	conf = conf.withDefault()
+	w := &batchWriter{
+		conf:    conf,
+		send:    send,
+		records: make(chan []byte, conf.bufferSize),
+		syncs:   make(chan chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go w.run()
+	return w
+}
+
+// Write copy p into buffer, since zap reuse p after Write returns
+func (w *batchWriter) Write(p []byte) (n int, err error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+
+	record := make([]byte, len(p))
+	copy(record, p)
+
+	select {
+	case w.records <- record:
+	default:
+		atomic.AddInt64(&w.dropped, 1)
+	}
+
+	return len(p), nil
+}
+
+func (w *batchWriter) Close() error {
+	w.once.Do(func() {
+		w.mu.Lock()
+		w.closed = true
+		close(w.records)
+		w.mu.Unlock()
+	})
+
+	<-w.done
+	return nil
+}
+
+// Sync send records written before it is called and wait until they are sent, implementing zapcore.WriteSyncer
+func (w *batchWriter) Sync() error {
+	synced := make(chan struct{})
+	select {
+	case w.syncs <- synced:
+	case <-w.done:
+		return nil
+	}
+
+	select {
+	case <-synced:
+	case <-w.done:
+	}
+
+	return nil
+}
+
+func (w *batchWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.conf.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, w.conf.size)
+	for {
+		select {
+		case record, ok := <-w.records:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+
+			batch = append(batch, record)
+			if len(batch) >= w.conf.size {
+				w.flush(batch)
+				batch = make([][]byte, 0, w.conf.size)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch)
+				batch = make([][]byte, 0, w.conf.size)
+			}
+		case synced := <-w.syncs:
+			batch = w.drain(batch)
+			w.flush(batch)
+			batch = make([][]byte, 0, w.conf.size)
+			close(synced)
+		}
+	}
+}
+
+// drain append buffered records into batch, full batch is sent, so the returned batch is the remaining records
+func (w *batchWriter) drain(batch [][]byte) [][]byte {
+	for {
+		select {
+		case record, ok := <-w.records:
+			if !ok {
+				return batch
+			}
+
+			batch = append(batch, record)
+			if len(batch) >= w.conf.size {
+				w.flush(batch)
+				batch = make([][]byte, 0, w.conf.size)
+			}
+		default:
+			return batch
+		}
+	}
+}
+
+// flush send batch, retrying records which should be retried with exponential backoff
+func (w *batchWriter) flush(batch [][]byte) {
+	if dropped := atomic.SwapInt64(&w.dropped, 0); dropped > 0 {
+		w.conf.onError(fmt.Errorf("buffer is full, %d records dropped", dropped))
+	}
+
+	if len(batch) <= 0 {
+		return
+	}
+
+	backoff := w.conf.retryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.send(batch)
+		if err == nil {
+			return
+		}
+
+		if len(retry) <= 0 || attempt >= w.conf.maxRetries {
+			w.conf.onError(fmt.Errorf("send %d records error: %w", len(batch), err))
+			return
+		}
+
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+
+		batch = retry
+	}
+}
@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+// batchRecorder record each batch sent by batch writer
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]string
+	errs    []error
+}
+
+func (r *batchRecorder) send(records [][]byte) ([][]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	batch := make([]string, len(records))
+	for i, record := range records {
+		batch[i] = string(record)
+	}
+
+	r.batches = append(r.batches, batch)
+	return nil, nil
+}
+
+func (r *batchRecorder) onError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.errs = append(r.errs, err)
+}
+
+func TestBatchWriter(t *testing.T) {
+	t.Run("flush on size and close", func(t *testing.T) {
+		r := &batchRecorder{}
+		w := newBatchWriter(batchConfig{size: 2, flushInterval: time.Hour}, r.send)
+
+		for _, record := range []string{"a", "b", "c"} {
+			_, err := w.Write([]byte(record))
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, w.Close())
+		assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, r.batches)
+
+		_, err := w.Write([]byte("d"))
+		assert.ErrorIs(t, err, ErrWriterClosed)
+		assert.NoError(t, w.Close())
+	})
+
+	t.Run("sync", func(t *testing.T) {
+		r := &batchRecorder{}
+		w := newBatchWriter(batchConfig{size: 2, flushInterval: time.Hour}, r.send)
+
+		// zap call Sync of writer before exit on Fatal
+		syncer := zapcore.AddSync(w)
+		for _, record := range []string{"a", "b", "c"} {
+			_, err := syncer.Write([]byte(record))
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, syncer.Sync())
+		r.mu.Lock()
+		assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, r.batches)
+		r.mu.Unlock()
+
+		require.NoError(t, w.Close())
+		assert.NoError(t, w.Sync())
+	})
+
+	t.Run("flush on interval", func(t *testing.T) {
+		r := &batchRecorder{}
+		w := newBatchWriter(batchConfig{flushInterval: 10 * time.Millisecond}, r.send)
+		defer w.Close()
+
+		_, _ = w.Write([]byte("a"))
+		assert.Eventually(t, func() bool {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			return len(r.batches) == 1
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("write copy record", func(t *testing.T) {
+		r := &batchRecorder{}
+		w := newBatchWriter(batchConfig{}, r.send)
+
+		p := []byte("a")
+		_, _ = w.Write(p)
+		p[0] = 'b'
+
+		require.NoError(t, w.Close())
+		assert.Equal(t, [][]string{{"a"}}, r.batches)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		r := &batchRecorder{}
+		attempts := 0
+		w := newBatchWriter(batchConfig{maxRetries: 2, retryBackoff: time.Millisecond, onError: r.onError},
+			func(records [][]byte) ([][]byte, error) {
+				attempts++
+				if attempts < 3 {
+					// only the last record is rejected
+					return records[len(records)-1:], errors.New("rejected")
+				}
+
+				return r.send(records)
+			})
+
+		_, _ = w.Write([]byte("a"))
+		_, _ = w.Write([]byte("b"))
+		require.NoError(t, w.Close())
+
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, [][]string{{"b"}}, r.batches)
+		assert.Empty(t, r.errs)
+	})
+
+	t.Run("permanent error", func(t *testing.T) {
+		r := &batchRecorder{}
+		attempts := 0
+		w := newBatchWriter(batchConfig{onError: r.onError}, func(records [][]byte) ([][]byte, error) {
+			attempts++
+			return nil, errors.New("bad request")
+		})
+
+		_, _ = w.Write([]byte("a"))
+		require.NoError(t, w.Close())
+
+		assert.Equal(t, 1, attempts)
+		require.Len(t, r.errs, 1)
+		assert.Contains(t, r.errs[0].Error(), "bad request")
+	})
+
+	t.Run("drop when buffer is full", func(t *testing.T) {
+		r := &batchRecorder{}
+		block := make(chan struct{})
+		w := newBatchWriter(batchConfig{size: 1, bufferSize: 1, onError: r.onError}, func(records [][]byte) ([][]byte, error) {
+			<-block
+			return r.send(records)
+		})
+
+		for i := 0; i < 10; i++ {
+			_, err := w.Write([]byte("a"))
+			require.NoError(t, err)
+		}
+
+		close(block)
+		require.NoError(t, w.Close())
+
+		require.NotEmpty(t, r.errs)
+		assert.Contains(t, r.errs[0].Error(), "records dropped")
+	})
+}
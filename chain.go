@@ -0,0 +1,399 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+)
+
+const (
+	LogTypeChain      = "CHAIN"
+	LogTypeCheckpoint = "CHECKPOINT"
+
+	chainHashKey   = `,"_hash":"`
+	minChainSecret = 16
+)
+
+// chainHashLen is length of hex HMAC-SHA256 written as "_hash"
+var chainHashLen = hex.EncodedLen(sha256.Size)
+
+var chainFileName = regexp.MustCompile(`\.\d{8}$`)
+
+// chainHeader is the first line of each file, prevHash link it to the last line of previous file
+type chainHeader struct {
+	LogType  string `json:"logType"`
+	File     string `json:"file"`
+	PrevHash string `json:"prevHash"`
+	Time     string `json:"xtime"`
+}
+
+// chainCheckpoint is written as the last line when file is rotated or closed, signed using HMAC-SHA256
+// of the file name and hash of the previous line, so the whole file content is signed.
+type chainCheckpoint struct {
+	LogType string `json:"logType"`
+	File    string `json:"file"`
+	Hash    string `json:"hash"`
+	Sig     string `json:"sig"`
+	Time    string `json:"xtime"`
+}
+
+// chainWriter append "_hash" into each JSON line, which is HMAC-SHA256 of previous hash and the line itself
+// keyed by the secret, so modified, removed or reordered line break the chain and it can't be recomputed
+// without the secret. See Verify.
+type chainWriter struct {
+	mu     sync.Mutex
+	out    fileWriter
+	secret []byte
+	now    func() time.Time
+
+	file string // current file, empty until the first write
+	last time.Time
+	prev []byte // hash of the last line
+}
+
+func newChainWriter(conf *OptionsFile, out fileWriter, now func() time.Time) (*chainWriter, error) {
+	if len(conf.HashChainSecret) < minChainSecret {
+		return nil, fmt.Errorf("hash chain secret must be at least %d bytes", minChainSecret)
+	}
+
+	w := &chainWriter{
+		out:    out,
+		secret: []byte(conf.HashChainSecret),
+		now:    now,
+		prev:   make([]byte, sha256.Size),
+	}
+
+	// continue the chain of the latest file, so restarting application doesn't break it
+	files, err := chainFiles(conf.FileLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) > 0 {
+		latest := files[len(files)-1]
+		line, err := out.lastLine(latest)
+		if err != nil {
+			return nil, fmt.Errorf("read hash chain of %s error: %w", latest, err)
+		}
+
+		original, hash, ok := splitChainLine(line)
+		if !ok && len(line) > 0 {
+			return nil, fmt.Errorf("last line of %s has no hash chain", latest)
+		}
+
+		if ok {
+			w.prev = hash
+			if latest == out.name(now()) {
+				w.file = latest
+			} else if chainLogType(original) != LogTypeCheckpoint {
+				// application is stopped without Close, checkpoint is written into the latest file on the next write
+				day, err := time.ParseInLocation("20060102", latest[len(latest)-8:], time.Local)
+				if err != nil {
+					return nil, err
+				}
+
+				w.file, w.last = latest, day
+			}
+		}
+	}
+
+	return w, nil
+}
+
+func (w *chainWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	if name := w.out.name(now); name != w.file {
+		if w.file != "" {
+			// checkpoint is written into previous file at the last write time
+			if err = w.checkpoint(); err != nil {
+				return 0, err
+			}
+		}
+
+		header, _ := json.Marshal(chainHeader{
+			LogType:  LogTypeChain,
+			File:     filepath.Base(name),
+			PrevHash: hex.EncodeToString(w.prev),
+			Time:     now.Format(lokiTimeLayout),
+		})
+
+		if err = w.writeLine(now, header); err != nil {
+			return 0, err
+		}
+
+		w.file = name
+	}
+
+	w.last = now
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		if err = w.writeLine(now, line); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// checkpoint write signed checkpoint as the last line of current file
+func (w *chainWriter) checkpoint() error {
+	hash := hex.EncodeToString(w.prev)
+	line, _ := json.Marshal(chainCheckpoint{
+		LogType: LogTypeCheckpoint,
+		File:    filepath.Base(w.file),
+		Hash:    hash,
+		Sig:     chainSignature(w.secret, filepath.Base(w.file), hash),
+		Time:    w.last.Format(lokiTimeLayout),
+	})
+
+	return w.writeLine(w.last, line)
+}
+
+// writeLine write JSON object line with "_hash" as its last field
+func (w *chainWriter) writeLine(t time.Time, line []byte) error {
+	if len(line) < 2 || line[0] != '{' || line[len(line)-1] != '}' {
+		return fmt.Errorf("hash chain only support JSON object line")
+	}
+
+	hash := chainHash(w.secret, w.prev, line)
+	buf := make([]byte, 0, len(line)+len(chainHashKey)+chainHashLen+3)
+	buf = append(buf, line[:len(line)-1]...)
+	buf = append(buf, chainHashKey...)
+	buf = append(buf, hex.EncodeToString(hash)...)
+	buf = append(buf, "\"}\n"...)
+
+	if err := w.out.writeAt(t, buf); err != nil {
+		return err
+	}
+
+	w.prev = hash
+	return nil
+}
+
+// Close write checkpoint, so removing the last lines of the file can be detected
+func (w *chainWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var err error
+	if w.file != "" {
+		err = w.checkpoint()
+		w.file = ""
+	}
+
+	if closeErr := w.out.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func chainHash(secret, prev, line []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(prev)
+	mac.Write(line)
+	return mac.Sum(nil)
+}
+
+func chainLogType(line []byte) string {
+	var record struct {
+		LogType string `json:"logType"`
+	}
+
+	_ = json.Unmarshal(line, &record)
+	return record.LogType
+}
+
+func chainSignature(secret []byte, file, hash string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(file + "\n" + hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// splitChainLine returns the original line and its hash
+func splitChainLine(line []byte) ([]byte, []byte, bool) {
+	suffixLen := len(chainHashKey) + chainHashLen + 2
+	if len(line) < suffixLen+1 || !bytes.HasSuffix(line, []byte(`"}`)) {
+		return nil, nil, false
+	}
+
+	suffix := line[len(line)-suffixLen:]
+	if !bytes.HasPrefix(suffix, []byte(chainHashKey)) {
+		return nil, nil, false
+	}
+
+	hash, err := hex.DecodeString(string(suffix[len(chainHashKey) : len(chainHashKey)+chainHashLen]))
+	if err != nil {
+		return nil, nil, false
+	}
+
+	original := append(line[:len(line)-suffixLen:len(line)-suffixLen], '}')
+	return original, hash, true
+}
+
+// chainFiles returns rotated files of file location sorted by date
+func chainFiles(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if chainFileName.MatchString(match) {
+			files = append(files, match)
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// ChainError is returned by Verify, Line is 1-based line number of File.
+type ChainError struct {
+	File   string
+	Line   int
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Reason)
+}
+
+// Verify check hash chain of file output with OptionsFile.HashChain enabled, path is FileLocation,
+// so every rotated FileLocation.YYYYMMDD file is checked in order, or a single file.
+// It returns *ChainError when a line is modified, removed or reordered, when a file in the middle is removed,
+// or when checkpoint signature is invalid. Every file except the latest must be ended with checkpoint.
+func Verify(path string, secret []byte) error {
+	return VerifyEncrypted(path, secret, nil)
+}
+
+// VerifyEncrypted is Verify of file output encrypted using provider, see OptionsFile.KeyProvider.
+func VerifyEncrypted(path string, secret []byte, provider KeyProvider) error {
+	files, err := chainFiles(path)
+	if err != nil {
+		return err
+	}
+
+	if len(files) <= 0 {
+		if _, err = os.Stat(path); err != nil {
+			return err
+		}
+
+		files = []string{path}
+	}
+
+	var prev []byte
+	for i, file := range files {
+		if prev, err = verifyChainFile(file, prev, secret, provider, i == len(files)-1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyChainFile returns hash of the last line, prev is nil for the first file since previous file may be
+// removed by max age, so its chain start from the header
+func verifyChainFile(file string, prev, secret []byte, provider KeyProvider, latest bool) ([]byte, error) {
+	content, err := readLogFile(file, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	fail := func(line int, reason string, args ...interface{}) ([]byte, error) {
+		return nil, &ChainError{File: file, Line: line, Reason: fmt.Sprintf(reason, args...)}
+	}
+
+	if provider == nil && bytes.HasPrefix(content, []byte(encryptMagic)) {
+		return fail(0, "file is encrypted, use VerifyEncrypted")
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(content, []byte("\n")), []byte("\n"))
+	if len(content) <= 0 {
+		lines = nil
+	}
+
+	var lastType string
+	for i, line := range lines {
+		n := i + 1
+		original, hash, ok := splitChainLine(line)
+		if !ok {
+			return fail(n, "line has no hash")
+		}
+
+		var record struct {
+			LogType  string `json:"logType"`
+			File     string `json:"file"`
+			PrevHash string `json:"prevHash"`
+			Hash     string `json:"hash"`
+			Sig      string `json:"sig"`
+		}
+
+		_ = json.Unmarshal(original, &record)
+		lastType = record.LogType
+
+		switch record.LogType {
+		case LogTypeChain:
+			if i != 0 {
+				// logger is restarted in the middle of file without reading the previous chain
+				return fail(n, "unexpected chain header")
+			}
+
+			if chainFileName.MatchString(file) && record.File != filepath.Base(file) {
+				return fail(n, "chain header of %s is found in %s", record.File, filepath.Base(file))
+			}
+
+			headerPrev, err := hex.DecodeString(record.PrevHash)
+			if err != nil {
+				return fail(n, "invalid previous hash")
+			}
+
+			if prev != nil && !bytes.Equal(prev, headerPrev) {
+				return fail(n, "previous file is modified, removed or reordered")
+			}
+
+			prev = headerPrev
+		case LogTypeCheckpoint:
+			if record.Hash != hex.EncodeToString(prev) {
+				return fail(n, "checkpoint hash mismatch")
+			}
+
+			if !hmac.Equal([]byte(record.Sig), []byte(chainSignature(secret, record.File, record.Hash))) {
+				return fail(n, "invalid checkpoint signature")
+			}
+		}
+
+		if i == 0 && record.LogType != LogTypeChain {
+			return fail(n, "file is not started with chain header")
+		}
+
+		if !bytes.Equal(hash, chainHash(secret, prev, original)) {
+			return fail(n, "hash mismatch, line is modified, removed or reordered")
+		}
+
+		prev = hash
+	}
+
+	if len(lines) <= 0 {
+		return fail(0, "file is empty")
+	}
+
+	if !latest && lastType != LogTypeCheckpoint {
+		return fail(len(lines), "file is not ended with checkpoint, the last lines may be removed")
+	}
+
+	return prev, nil
+}
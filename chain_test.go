@@ -0,0 +1,224 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const chainSecret = "my-chain-secret-key"
+
+func newTestChainWriter(conf *OptionsFile, now func() time.Time) (*chainWriter, error) {
+	out, err := newDailyFile(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return newChainWriter(conf, out, now)
+}
+
+// writeChain write log of two days and returns file location and both files
+func writeChain(t *testing.T) (string, string, string) {
+	location := filepath.Join(t.TempDir(), "audit")
+	day := time.Date(2023, 10, 1, 23, 0, 0, 0, time.Local)
+	now := day
+
+	w, err := newTestChainWriter(&OptionsFile{FileLocation: location, HashChainSecret: chainSecret}, func() time.Time { return now })
+	require.NoError(t, err)
+
+	log, err := newLogger(WithCustomWriter(w))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		log.Info(ctx, logMessage)
+	}
+
+	now = day.Add(2 * time.Hour)
+	for i := 0; i < 3; i++ {
+		log.Info(ctx, logMessage)
+	}
+
+	require.NoError(t, log.Close())
+	return location, location + ".20231001", location + ".20231002"
+}
+
+func readLines(t *testing.T, file string) [][]byte {
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	return bytes.SplitAfter(bytes.TrimSuffix(content, []byte("\n")), []byte("\n"))
+}
+
+func writeLines(t *testing.T, file string, lines [][]byte) {
+	require.NoError(t, os.WriteFile(file, bytes.Join(lines, nil), 0o644))
+}
+
+func TestChainWriter(t *testing.T) {
+	location, first, second := writeChain(t)
+	require.NoError(t, Verify(location, []byte(chainSecret)))
+
+	lines := readLines(t, first)
+	require.Len(t, lines, 5)
+	assert.Contains(t, string(lines[0]), `"logType":"CHAIN","file":"audit.20231001"`)
+	assert.Contains(t, string(lines[1]), `"message":"log message"`)
+	assert.Contains(t, string(lines[1]), `,"_hash":"`)
+	assert.Contains(t, string(lines[4]), `"logType":"CHECKPOINT"`)
+
+	lines = readLines(t, second)
+	require.Len(t, lines, 5)
+	assert.Contains(t, string(lines[0]), `"logType":"CHAIN","file":"audit.20231002"`)
+	assert.Contains(t, string(lines[4]), `"logType":"CHECKPOINT"`)
+
+	t.Run("restart", func(t *testing.T) {
+		now := time.Date(2023, 10, 2, 8, 0, 0, 0, time.Local)
+		w, err := newTestChainWriter(&OptionsFile{FileLocation: location, HashChainSecret: chainSecret}, func() time.Time { return now })
+		require.NoError(t, err)
+
+		log, err := newLogger(WithCustomWriter(w))
+		require.NoError(t, err)
+		log.Info(ctx, logMessage)
+		require.NoError(t, log.Close())
+
+		assert.Len(t, readLines(t, second), 7)
+		assert.NoError(t, Verify(location, []byte(chainSecret)))
+	})
+
+	t.Run("restart without close", func(t *testing.T) {
+		location := filepath.Join(t.TempDir(), "audit")
+		now := time.Date(2023, 10, 1, 8, 0, 0, 0, time.Local)
+		conf := &OptionsFile{FileLocation: location, HashChainSecret: chainSecret}
+
+		crashed, err := newTestChainWriter(conf, func() time.Time { return now })
+		require.NoError(t, err)
+		_, err = crashed.Write([]byte(`{"message":"log message"}` + "\n"))
+		require.NoError(t, err)
+		require.NoError(t, crashed.out.Close())
+
+		now = now.Add(24 * time.Hour)
+		w, err := newTestChainWriter(conf, func() time.Time { return now })
+		require.NoError(t, err)
+		_, err = w.Write([]byte(`{"message":"log message"}` + "\n"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		lines := readLines(t, location+".20231001")
+		require.Len(t, lines, 3)
+		assert.Contains(t, string(lines[2]), `"logType":"CHECKPOINT"`)
+		assert.NoError(t, Verify(location, []byte(chainSecret)))
+	})
+
+	t.Run("short secret", func(t *testing.T) {
+		_, err := newTestChainWriter(&OptionsFile{FileLocation: location, HashChainSecret: "short"}, time.Now)
+		assert.EqualError(t, err, "hash chain secret must be at least 16 bytes")
+	})
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("modified", func(t *testing.T) {
+		location, first, _ := writeChain(t)
+		lines := readLines(t, first)
+		lines[2] = bytes.Replace(lines[2], []byte(logMessage), []byte("log massage"), 1)
+		writeLines(t, first, lines)
+
+		err := Verify(location, []byte(chainSecret))
+		assert.Equal(t, &ChainError{File: first, Line: 3, Reason: "hash mismatch, line is modified, removed or reordered"}, err)
+	})
+
+	t.Run("rehashed without secret", func(t *testing.T) {
+		// the latest file doesn't need checkpoint, so its last lines could be rewritten with recomputed hash
+		location, _, second := writeChain(t)
+		lines := readLines(t, second)[:2]
+		_, prev, ok := splitChainLine(bytes.TrimSuffix(lines[0], []byte("\n")))
+		require.True(t, ok)
+
+		original, _, ok := splitChainLine(bytes.TrimSuffix(lines[1], []byte("\n")))
+		require.True(t, ok)
+		original = bytes.Replace(original, []byte(logMessage), []byte("log massage"), 1)
+		hash := sha256.Sum256(append(append([]byte(nil), prev...), original...))
+		lines[1] = append(original[:len(original)-1], []byte(chainHashKey+hex.EncodeToString(hash[:])+"\"}\n")...)
+		writeLines(t, second, lines)
+
+		err := Verify(location, []byte(chainSecret))
+		assert.Equal(t, &ChainError{File: second, Line: 2, Reason: "hash mismatch, line is modified, removed or reordered"}, err)
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		location, first, _ := writeChain(t)
+		lines := readLines(t, first)
+		writeLines(t, first, append(lines[:2:2], lines[3:]...))
+
+		err := Verify(location, []byte(chainSecret))
+		assert.Equal(t, &ChainError{File: first, Line: 3, Reason: "hash mismatch, line is modified, removed or reordered"}, err)
+	})
+
+	t.Run("reordered", func(t *testing.T) {
+		location, _, second := writeChain(t)
+		lines := readLines(t, second)
+		lines[1], lines[2] = lines[2], lines[1]
+		writeLines(t, second, lines)
+
+		err := Verify(location, []byte(chainSecret))
+		assert.Equal(t, &ChainError{File: second, Line: 2, Reason: "hash mismatch, line is modified, removed or reordered"}, err)
+	})
+
+	t.Run("last lines removed", func(t *testing.T) {
+		location, first, _ := writeChain(t)
+		writeLines(t, first, readLines(t, first)[:3])
+
+		err := Verify(location, []byte(chainSecret))
+		assert.Equal(t, &ChainError{File: first, Line: 3, Reason: "file is not ended with checkpoint, the last lines may be removed"}, err)
+	})
+
+	t.Run("file removed", func(t *testing.T) {
+		location, first, second := writeChain(t)
+		now := time.Date(2023, 10, 3, 8, 0, 0, 0, time.Local)
+		w, err := newTestChainWriter(&OptionsFile{FileLocation: location, HashChainSecret: chainSecret}, func() time.Time { return now })
+		require.NoError(t, err)
+		_, err = w.Write([]byte(`{"message":"log message"}` + "\n"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		require.NoError(t, Verify(location, []byte(chainSecret)))
+
+		require.NoError(t, os.Remove(second))
+		err = Verify(location, []byte(chainSecret))
+		assert.Equal(t, &ChainError{File: location + ".20231003", Line: 1, Reason: "previous file is modified, removed or reordered"}, err)
+
+		// the oldest file is removed by max age
+		require.NoError(t, os.Remove(first))
+		assert.NoError(t, Verify(location, []byte(chainSecret)))
+	})
+
+	t.Run("renamed", func(t *testing.T) {
+		location, _, second := writeChain(t)
+		require.NoError(t, os.Rename(second, location+".20231003"))
+
+		err := Verify(location, []byte(chainSecret))
+		assert.Equal(t, &ChainError{File: location + ".20231003", Line: 1, Reason: "chain header of audit.20231002 is found in audit.20231003"}, err)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		location, first, _ := writeChain(t)
+		lines := readLines(t, first)
+		lines[4] = bytes.Replace(lines[4], []byte(`"sig":"`), []byte(`"sig":"0`), 1)
+		writeLines(t, first, lines)
+
+		err := Verify(location, []byte(chainSecret))
+		assert.Equal(t, &ChainError{File: first, Line: 5, Reason: "invalid checkpoint signature"}, err)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		location, first, _ := writeChain(t)
+		err := Verify(location, []byte("other-chain-secret"))
+		assert.Equal(t, &ChainError{File: first, Line: 1, Reason: "hash mismatch, line is modified, removed or reordered"}, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		assert.Error(t, Verify(filepath.Join(t.TempDir(), "audit"), []byte(chainSecret)))
+	})
+}
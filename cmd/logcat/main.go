@@ -0,0 +1,71 @@
+// Command logcat print plain text of file output encrypted using OptionsFile.EncryptKeyEnv or
+// OptionsFile.EncryptKeyFile (see logger.NewDecryptReader):
+//
+//	LOG_ENCRYPT_KEY=... go run github.com/armiariyan/logger/cmd/logcat /var/log/app/tdr.20231001
+//
+// Key is base64 AES key read from environment variable or file, so it never ends up in shell history.
+// Files are printed in order, or stdin when no file is given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/armiariyan/logger"
+)
+
+func main() {
+	keyEnv := flag.String("key-env", "LOG_ENCRYPT_KEY", "environment variable holding the base64 encryption key")
+	keyFile := flag.String("key-file", "", "file holding the base64 encryption key, used instead of -key-env")
+	flag.Parse()
+
+	var (
+		provider logger.KeyProvider
+		err      error
+	)
+
+	if *keyFile != "" {
+		provider, err = logger.FileKeyProvider(*keyFile)
+	} else {
+		provider, err = logger.EnvKeyProvider(*keyEnv)
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	if err = run(os.Stdout, os.Stdin, provider, flag.Args()); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(w io.Writer, r io.Reader, provider logger.KeyProvider, files []string) error {
+	if len(files) <= 0 {
+		_, err := io.Copy(w, logger.NewDecryptReader(r, provider))
+		return err
+	}
+
+	for _, path := range files {
+		if err := cat(w, path, provider); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func cat(w io.Writer, path string, provider logger.KeyProvider) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, err = io.Copy(w, logger.NewDecryptReader(f, provider))
+	return err
+}
@@ -0,0 +1,67 @@
+// Command logverify check hash chain of file output configured with OptionsFile.HashChain (see logger.Verify),
+// every rotated file of the location is checked in order:
+//
+//	LOG_CHAIN_SECRET=... go run github.com/armiariyan/logger/cmd/logverify /var/log/app/audit
+//
+// Secret is read from environment variable, so it never ends up in shell history.
+// It exits with status 1 when a line is modified, removed or reordered.
+// Encrypted file output is decrypted using base64 key of -key-env or -key-file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/armiariyan/logger"
+)
+
+func main() {
+	secretEnv := flag.String("secret-env", "LOG_CHAIN_SECRET", "environment variable holding the hash chain secret")
+	keyEnv := flag.String("key-env", "", "environment variable holding the base64 encryption key of encrypted file")
+	keyFile := flag.String("key-file", "", "file holding the base64 encryption key of encrypted file")
+	flag.Parse()
+
+	secret := os.Getenv(*secretEnv)
+	if secret == "" {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s is not set\n", *secretEnv)
+		os.Exit(2)
+	}
+
+	var (
+		provider logger.KeyProvider
+		err      error
+	)
+
+	switch {
+	case *keyFile != "":
+		provider, err = logger.FileKeyProvider(*keyFile)
+	case *keyEnv != "":
+		provider, err = logger.EnvKeyProvider(*keyEnv)
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	if flag.NArg() <= 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: logverify [-secret-env name] [-key-env name | -key-file path] file-location...")
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		if err := logger.VerifyEncrypted(path, []byte(secret), provider); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%s: error: %s\n", path, err)
+			failed = true
+			continue
+		}
+
+		fmt.Printf("%s: ok\n", path)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
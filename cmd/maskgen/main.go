@@ -0,0 +1,240 @@
+// Command maskgen generate LogMasked and LogMaskedWith method (see logger.OptionsMasker) from `json` and `mask` tag
+// of struct, so the struct is masked without reflection. Field is masked using logger.FieldMasker of the logger,
+// so options such as WithMaskSecret apply the same as masking using reflection. Use it for type which is logged
+// on hot path:
+//
+//	//go:generate go run github.com/armiariyan/logger/cmd/maskgen -type Payment,Customer
+//
+// Generated file is written next to the source file with "_masked.go" suffix.
+// Embedded field and `json:",string"` option are not supported, write LogMasked manually for such type.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const loggerImport = "github.com/armiariyan/logger"
+
+func main() {
+	typeNames := flag.String("type", "", "comma separated struct type name, required")
+	file := flag.String("file", os.Getenv("GOFILE"), "source file, default to $GOFILE set by go generate")
+	output := flag.String("o", "", "output file, default to <file>_masked.go")
+	flag.Parse()
+
+	if *typeNames == "" || *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	src, err := os.ReadFile(*file)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error read source: %s\n", err)
+		os.Exit(1)
+	}
+
+	code, err := generate(*file, src, strings.Split(*typeNames, ","))
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error generate: %s\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(*file, ".go") + "_masked.go"
+	}
+
+	if err = os.WriteFile(*output, code, 0644); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error write output: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func generate(filename string, src []byte, typeNames []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	structs := make(map[string]*ast.StructType)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.TypeSpec); ok {
+			if st, ok := spec.Type.(*ast.StructType); ok {
+				structs[spec.Name.Name] = st
+			}
+		}
+
+		return true
+	})
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by maskgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "package %s\n\n", file.Name.Name)
+	fmt.Fprintf(buf, "import %q\n", loggerImport)
+
+	for _, name := range typeNames {
+		name = strings.TrimSpace(name)
+		st, ok := structs[name]
+		if !ok {
+			return nil, fmt.Errorf("struct %s not found in %s", name, filename)
+		}
+
+		if err = writeMethod(buf, name, st); err != nil {
+			return nil, fmt.Errorf("struct %s: %w", name, err)
+		}
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+func writeMethod(buf *bytes.Buffer, typeName string, st *ast.StructType) error {
+	fmt.Fprintf(buf, "\nvar _ logger.OptionsMasker = %s{}\n\n", typeName)
+	fmt.Fprintf(buf, "// LogMasked implements logger.Masker.\n")
+	fmt.Fprintf(buf, "func (x %s) LogMasked() interface{} {\n", typeName)
+	fmt.Fprintf(buf, "return x.LogMaskedWith(logger.DefaultFieldMasker())\n}\n\n")
+	fmt.Fprintf(buf, "// LogMaskedWith implements logger.OptionsMasker.\n")
+	fmt.Fprintf(buf, "func (x %s) LogMaskedWith(f logger.FieldMasker) interface{} {\n", typeName)
+	fmt.Fprintf(buf, "m := make(map[string]interface{}, %d)\n", st.Fields.NumFields())
+
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			return fmt.Errorf("embedded field %s is not supported", exprString(field.Type))
+		}
+
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			unquoted, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return err
+			}
+
+			tag = reflect.StructTag(unquoted)
+		}
+
+		jsonName, opts, _ := strings.Cut(tag.Get("json"), ",")
+		if jsonName == "-" && opts == "" {
+			continue
+		}
+
+		if hasOption(opts, "string") {
+			return fmt.Errorf("json string option is not supported")
+		}
+
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				continue
+			}
+
+			key := jsonName
+			if key == "" {
+				key = ident.Name
+			}
+
+			access := "x." + ident.Name
+			value := fieldValue(access, field.Type, tag)
+
+			if cond := emptyCheck(access, field.Type); hasOption(opts, "omitempty") && cond != "" {
+				fmt.Fprintf(buf, "if %s {\nm[%q] = %s\n}\n", cond, key, value)
+				continue
+			}
+
+			fmt.Fprintf(buf, "m[%q] = %s\n", key, value)
+		}
+	}
+
+	fmt.Fprintf(buf, "return m\n}\n")
+	return nil
+}
+
+func fieldValue(access string, expr ast.Expr, tag reflect.StructTag) string {
+	if strategy, ok := tag.Lookup("mask"); ok {
+		if ident, ok := expr.(*ast.Ident); ok && ident.Name == "string" {
+			return fmt.Sprintf("f.MaskString(%q, %s)", strategy, access)
+		}
+
+		return fmt.Sprintf("f.MaskTagged(%q, %s)", strategy, access)
+	}
+
+	if isPlain(expr) {
+		return access
+	}
+
+	// nested type may has field to be masked
+	return fmt.Sprintf("f.Mask(%s)", access)
+}
+
+// isPlain returns true for type which never has value to be masked
+func isPlain(expr ast.Expr) bool {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return isBasic(t.Name)
+	case *ast.SelectorExpr:
+		return exprString(t) == "time.Time" || exprString(t) == "time.Duration"
+	case *ast.ArrayType:
+		ident, ok := t.Elt.(*ast.Ident)
+		return ok && isBasic(ident.Name)
+	}
+
+	return false
+}
+
+func isBasic(name string) bool {
+	switch name {
+	case "string", "bool", "byte", "rune",
+		"int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+		"float32", "float64":
+		return true
+	}
+
+	return false
+}
+
+// emptyCheck returns condition of non empty value for omitempty option,
+// empty when it cannot be known from the source (named type), so the field is always written
+func emptyCheck(access string, expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return access + ` != ""`
+		case "bool":
+			return access
+		}
+
+		if isBasic(t.Name) {
+			return access + " != 0"
+		}
+	case *ast.StarExpr, *ast.InterfaceType:
+		return access + " != nil"
+	case *ast.ArrayType, *ast.MapType:
+		return "len(" + access + ") != 0"
+	}
+
+	return ""
+}
+
+func hasOption(opts, option string) bool {
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+func exprString(expr ast.Expr) string {
+	buf := &bytes.Buffer{}
+	_ = format.Node(buf, token.NewFileSet(), expr)
+	return buf.String()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Run("committed file is up to date", func(t *testing.T) {
+		src, err := os.ReadFile("../../masker_gen_test.go")
+		require.NoError(t, err)
+
+		expected, err := os.ReadFile("../../masker_gen_masked_test.go")
+		require.NoError(t, err)
+
+		actual, err := generate("masker_gen_test.go", src, []string{"genPayment", "genCustomer"})
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), string(actual), "run go generate to update masker_gen_masked_test.go")
+	})
+
+	invalid := map[string]string{
+		"type not found":  "package a\ntype B struct{}",
+		"embedded field":  "package a\ntype A struct{ B }\ntype B struct{}",
+		"string option":   "package a\ntype A struct{ N int `json:\"n,string\"` }",
+		"invalid syntax":  "package a\ntype A struct{",
+		"not struct type": "package a\ntype A string",
+	}
+
+	for name, src := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := generate("a.go", []byte(src), []string{"A"})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Command masktoken print token written by logger configured with WithMaskSecret (see logger.MaskToken),
+// so support can search log of a customer by its token:
+//
+//	LOG_MASK_SECRET=... go run github.com/armiariyan/logger/cmd/masktoken -strategy phone 08123456789
+//
+// Secret is read from environment variable, so it never ends up in shell history.
+// Value is read from arguments, or from stdin line by line when no argument is given.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/armiariyan/logger"
+)
+
+func main() {
+	strategy := flag.String("strategy", "hmac", "mask tag value of the field, such as hmac, token, phone or email")
+	secretEnv := flag.String("secret-env", "LOG_MASK_SECRET", "environment variable holding the mask secret")
+	flag.Parse()
+
+	secret := os.Getenv(*secretEnv)
+	if secret == "" {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s is not set\n", *secretEnv)
+		os.Exit(2)
+	}
+
+	if err := run(os.Stdout, os.Stdin, []byte(secret), *strategy, flag.Args()); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(w io.Writer, r io.Reader, secret []byte, strategy string, values []string) error {
+	if len(values) > 0 {
+		for _, value := range values {
+			if _, err := fmt.Fprintln(w, logger.MaskToken(secret, strategy, value)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if _, err := fmt.Fprintln(w, logger.MaskToken(secret, strategy, scanner.Text())); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
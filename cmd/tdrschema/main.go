@@ -0,0 +1,33 @@
+// Command tdrschema print JSON Schema of TDR record, used by go generate to update schema/tdr.schema.json.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/armiariyan/logger"
+)
+
+func main() {
+	output := flag.String("o", "", "output file, print to stdout when empty")
+	flag.Parse()
+
+	schema, err := logger.TdrJSONSchema()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error generate tdr schema: %s\n", err)
+		os.Exit(1)
+	}
+
+	schema = append(schema, '\n')
+
+	if *output == "" {
+		_, _ = os.Stdout.Write(schema)
+		return
+	}
+
+	if err = os.WriteFile(*output, schema, 0644); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error write tdr schema: %s\n", err)
+		os.Exit(1)
+	}
+}
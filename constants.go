@@ -6,14 +6,35 @@ import (
 )
 
 const (
-	File  = "file"
-	Queue = "queue"
+	File     = "file"
+	Queue    = "queue"
+	Syslog   = "syslog"
+	Loki     = "loki"
+	OTLP     = "otlp"
+	HTTPBulk = "httpbulk"
 )
 
 const (
-	QueueTypeKafka = "kafka"
-	LogTypeTDR     = "TDR"
-	LogTypeSYS     = "SYS"
+	QueueTypeKafka    = "kafka"
+	QueueTypeNATS     = "nats"
+	QueueTypeRabbitMQ = "rabbitmq"
+	QueueTypeRedis    = "redis"
+	LogTypeTDR        = "TDR"
+	LogTypeSYS        = "SYS"
+	LogTypeAudit      = "AUDIT"
+	LogTypeEvent      = "EVENT"
+)
+
+// StreamAudit and StreamEvent is name of named stream, its logType is the upper case name
+const (
+	StreamAudit = "audit"
+	StreamEvent = "event"
+)
+
+// TdrInbound and TdrOutbound is LogTdrModel.Direction, written by middleware of each package
+const (
+	TdrInbound  = "inbound"
+	TdrOutbound = "outbound"
 )
 
 const separator = "|"
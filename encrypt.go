@@ -0,0 +1,390 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	encryptMagic     = "LGE1"
+	encryptSaltSize  = 16
+	encryptChunkSize = 64 << 10
+	encryptKeyLabel  = "logger file encryption"
+)
+
+// ErrKeyMismatch is returned when encrypted file is not written using key of the KeyProvider.
+var ErrKeyMismatch = errors.New("encryption key mismatch")
+
+// KeyProvider provide AES key of encrypted file output, see OptionsFile.KeyProvider.
+type KeyProvider interface {
+	// DataKey returns key of a new file and its wrapped form, which is written into file header
+	DataKey() (key []byte, wrapped []byte, err error)
+	// Unwrap returns key of the wrapped form written by DataKey
+	Unwrap(wrapped []byte) ([]byte, error)
+}
+
+// staticKey use the same key for every file, wrapped form is its fingerprint
+type staticKey struct {
+	key []byte
+}
+
+// NewStaticKeyProvider returns KeyProvider using AES-128, AES-192 or AES-256 key.
+func NewStaticKeyProvider(key []byte) (KeyProvider, error) {
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, err
+	}
+
+	return &staticKey{key: key}, nil
+}
+
+// EnvKeyProvider returns KeyProvider using base64 key of environment variable name.
+func EnvKeyProvider(name string) (KeyProvider, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	return base64KeyProvider(value)
+}
+
+// FileKeyProvider returns KeyProvider using base64 key written in file path.
+func FileKeyProvider(path string) (KeyProvider, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return base64KeyProvider(string(content))
+}
+
+func base64KeyProvider(value string) (KeyProvider, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 key: %w", err)
+	}
+
+	return NewStaticKeyProvider(key)
+}
+
+func (s *staticKey) fingerprint() []byte {
+	sum := sha256.Sum256(s.key)
+	return sum[:8]
+}
+
+func (s *staticKey) DataKey() ([]byte, []byte, error) {
+	return s.key, s.fingerprint(), nil
+}
+
+func (s *staticKey) Unwrap(wrapped []byte) ([]byte, error) {
+	if !hmac.Equal(wrapped, s.fingerprint()) {
+		return nil, ErrKeyMismatch
+	}
+
+	return s.key, nil
+}
+
+// localKMS generate random data key of each file and wrap it using master key, like envelope encryption of KMS
+type localKMS struct {
+	keyID  string
+	master cipher.AEAD
+}
+
+// NewLocalKMS returns KeyProvider generating data key of each file, encrypted using master key.
+// It is a local stub of KMS, keyID is written into file header so master key can be rotated.
+func NewLocalKMS(keyID string, masterKey []byte) (KeyProvider, error) {
+	if keyID == "" || strings.Contains(keyID, "\x00") {
+		return nil, errors.New("invalid key id")
+	}
+
+	master, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &localKMS{keyID: keyID, master: master}, nil
+}
+
+func (k *localKMS) DataKey() ([]byte, []byte, error) {
+	key := make([]byte, 32)
+	nonce := make([]byte, k.master.NonceSize())
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	// key id \x00 nonce sealed key
+	wrapped := append([]byte(k.keyID), 0)
+	wrapped = append(wrapped, nonce...)
+	wrapped = k.master.Seal(wrapped, nonce, key, []byte(k.keyID))
+	return key, wrapped, nil
+}
+
+func (k *localKMS) Unwrap(wrapped []byte) ([]byte, error) {
+	i := bytes.IndexByte(wrapped, 0)
+	if i < 0 || string(wrapped[:i]) != k.keyID {
+		return nil, ErrKeyMismatch
+	}
+
+	sealed := wrapped[i+1:]
+	if len(sealed) < k.master.NonceSize() {
+		return nil, ErrKeyMismatch
+	}
+
+	key, err := k.master.Open(nil, sealed[:k.master.NonceSize()], sealed[k.master.NonceSize():], []byte(k.keyID))
+	if err != nil {
+		return nil, ErrKeyMismatch
+	}
+
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// segmentKey derive AES-256 key of a segment from data key and random salt,
+// so GCM nonce counter starting from zero is never reused with the same key
+func segmentKey(key, salt []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encryptKeyLabel))
+	mac.Write(salt)
+	return newGCM(mac.Sum(nil))
+}
+
+func frameNonce(size int, counter uint64) []byte {
+	nonce := make([]byte, size)
+	binary.BigEndian.PutUint64(nonce[size-8:], counter)
+	return nonce
+}
+
+// encryptFile encrypt file output using AES-GCM. Each file, or each start of application writing into
+// existing file, is a segment started by header:
+//
+//	"LGE1" | uint16 wrapped key length | wrapped key | salt
+//
+// followed by frames of at most 64 KiB plain text, authenticated using its order as GCM nonce:
+//
+//	uint32 length | cipher text
+type encryptFile struct {
+	out      fileWriter
+	provider KeyProvider
+
+	file    string
+	aead    cipher.AEAD
+	counter uint64
+}
+
+func newEncryptFile(out fileWriter, provider KeyProvider) *encryptFile {
+	return &encryptFile{out: out, provider: provider}
+}
+
+func (e *encryptFile) name(t time.Time) string {
+	return e.out.name(t)
+}
+
+func (e *encryptFile) writeAt(t time.Time, p []byte) error {
+	// segment state is only updated after the write succeeds, so failed write is retried using the same
+	// segment, or a new header when the file isn't started yet
+	var buf []byte
+	file, aead, counter := e.file, e.aead, e.counter
+	if name := e.out.name(t); name != file {
+		key, wrapped, err := e.provider.DataKey()
+		if err != nil {
+			return fmt.Errorf("encryption key error: %w", err)
+		}
+
+		if len(wrapped) > 0xffff {
+			return errors.New("wrapped encryption key is too long")
+		}
+
+		salt := make([]byte, encryptSaltSize)
+		if _, err = rand.Read(salt); err != nil {
+			return err
+		}
+
+		if aead, err = segmentKey(key, salt); err != nil {
+			return err
+		}
+
+		buf = append(buf, encryptMagic...)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(wrapped)))
+		buf = append(buf, wrapped...)
+		buf = append(buf, salt...)
+		file, counter = name, 0
+	}
+
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > encryptChunkSize {
+			chunk = chunk[:encryptChunkSize]
+		}
+
+		p = p[len(chunk):]
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(chunk)+aead.Overhead()))
+		buf = aead.Seal(buf, frameNonce(aead.NonceSize(), counter), chunk, nil)
+		counter++
+	}
+
+	// header and frames are written at once, so file is never started with frame of unknown key
+	if err := e.out.writeAt(t, buf); err != nil {
+		return err
+	}
+
+	e.file, e.aead, e.counter = file, aead, counter
+	return nil
+}
+
+// lastLine decrypt the whole file, since frame can only be read from the segment header
+func (e *encryptFile) lastLine(path string) ([]byte, error) {
+	content, err := readLogFile(path, e.provider)
+	if err != nil {
+		return nil, err
+	}
+
+	content = bytes.TrimRight(content, "\n")
+	return content[bytes.LastIndexByte(content, '\n')+1:], nil
+}
+
+func (e *encryptFile) Close() error {
+	e.file = ""
+	return e.out.Close()
+}
+
+// readLogFile returns plain text of file, which is encrypted when provider is not nil
+func readLogFile(path string, provider KeyProvider) ([]byte, error) {
+	if provider == nil {
+		return os.ReadFile(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	return io.ReadAll(NewDecryptReader(file, provider))
+}
+
+// decryptReader read plain text of encrypted file output
+type decryptReader struct {
+	r        *bufio.Reader
+	provider KeyProvider
+
+	aead    cipher.AEAD
+	counter uint64
+	plain   []byte
+	err     error
+}
+
+// NewDecryptReader returns reader of encrypted file output, it returns error when the file is modified,
+// frames are reordered or the last frame is partially written. Frames removed from the end of a segment
+// are not detected, OptionsFile.HashChain checkpoint detect removed last lines of rotated file.
+func NewDecryptReader(r io.Reader, provider KeyProvider) io.Reader {
+	return &decryptReader{r: bufio.NewReader(r), provider: provider}
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) <= 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+
+		d.plain, d.err = d.next()
+	}
+
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// next returns plain text of the next frame, reading segment header before it
+func (d *decryptReader) next() ([]byte, error) {
+	prefix, err := d.r.Peek(4)
+	if errors.Is(err, io.EOF) && len(prefix) == 0 {
+		return nil, io.EOF
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted log: %w", io.ErrUnexpectedEOF)
+	}
+
+	if string(prefix) == encryptMagic {
+		if err = d.header(); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	if d.aead == nil {
+		return nil, errors.New("invalid encrypted log: missing header")
+	}
+
+	var size uint32
+	if err = binary.Read(d.r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+
+	if size < uint32(d.aead.Overhead()) || size > uint32(encryptChunkSize+d.aead.Overhead()) {
+		return nil, fmt.Errorf("invalid encrypted log: frame size %d", size)
+	}
+
+	sealed := make([]byte, size)
+	if _, err = io.ReadFull(d.r, sealed); err != nil {
+		return nil, fmt.Errorf("invalid encrypted log: %w", io.ErrUnexpectedEOF)
+	}
+
+	plain, err := d.aead.Open(sealed[:0], frameNonce(d.aead.NonceSize(), d.counter), sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted log: frame %d is modified or reordered", d.counter)
+	}
+
+	d.counter++
+	return plain, nil
+}
+
+func (d *decryptReader) header() error {
+	head := make([]byte, len(encryptMagic)+2)
+	if _, err := io.ReadFull(d.r, head); err != nil {
+		return fmt.Errorf("invalid encrypted log: %w", io.ErrUnexpectedEOF)
+	}
+
+	rest := make([]byte, int(binary.BigEndian.Uint16(head[len(encryptMagic):]))+encryptSaltSize)
+	if _, err := io.ReadFull(d.r, rest); err != nil {
+		return fmt.Errorf("invalid encrypted log: %w", io.ErrUnexpectedEOF)
+	}
+
+	wrapped, salt := rest[:len(rest)-encryptSaltSize], rest[len(rest)-encryptSaltSize:]
+	key, err := d.provider.Unwrap(wrapped)
+	if err != nil {
+		return fmt.Errorf("encrypted log key error: %w", err)
+	}
+
+	if d.aead, err = segmentKey(key, salt); err != nil {
+		return err
+	}
+
+	d.counter = 0
+	return nil
+}
@@ -0,0 +1,190 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var encryptKey = bytes.Repeat([]byte("k"), 32)
+
+// writeEncrypted write lines into file output using conf, returns the file of the day
+func writeEncrypted(t *testing.T, conf *OptionsFile, lines ...string) string {
+	now := time.Date(2023, 10, 1, 8, 0, 0, 0, time.Local)
+	w, err := newFileOutput(conf, func() time.Time { return now })
+	require.NoError(t, err)
+
+	for _, line := range lines {
+		_, err = w.Write([]byte(line + "\n"))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+	return conf.FileLocation + ".20231001"
+}
+
+func decryptFile(file string, provider KeyProvider) (string, error) {
+	content, err := readLogFile(file, provider)
+	return string(content), err
+}
+
+// failingFile fails the next fail writes without writing them
+type failingFile struct {
+	fileWriter
+	fail int
+}
+
+func (f *failingFile) writeAt(t time.Time, p []byte) error {
+	if f.fail > 0 {
+		f.fail--
+		return errors.New("disk full")
+	}
+
+	return f.fileWriter.writeAt(t, p)
+}
+
+func TestEncryptFile(t *testing.T) {
+	t.Setenv("TEST_LOG_ENCRYPT_KEY", base64.StdEncoding.EncodeToString(encryptKey))
+	conf := &OptionsFile{FileLocation: filepath.Join(t.TempDir(), "tdr"), EncryptKeyEnv: "TEST_LOG_ENCRYPT_KEY"}
+	provider, err := NewStaticKeyProvider(encryptKey)
+	require.NoError(t, err)
+
+	file := writeEncrypted(t, conf, `{"message":"first"}`, `{"message":"second"}`)
+	raw, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(raw, []byte(encryptMagic)))
+	assert.NotContains(t, string(raw), "first")
+
+	plain, err := decryptFile(file, provider)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"message\":\"first\"}\n{\"message\":\"second\"}\n", plain)
+
+	t.Run("restart", func(t *testing.T) {
+		writeEncrypted(t, conf, `{"message":"third"}`)
+		plain, err := decryptFile(file, provider)
+		require.NoError(t, err)
+		assert.Equal(t, "{\"message\":\"first\"}\n{\"message\":\"second\"}\n{\"message\":\"third\"}\n", plain)
+	})
+
+	t.Run("large write", func(t *testing.T) {
+		conf := &OptionsFile{FileLocation: filepath.Join(t.TempDir(), "tdr"), KeyProvider: provider}
+		line := `{"message":"` + strings.Repeat("x", 3*encryptChunkSize) + `"}`
+		plain, err := decryptFile(writeEncrypted(t, conf, line), provider)
+		require.NoError(t, err)
+		assert.Equal(t, line+"\n", plain)
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		modified := append([]byte(nil), raw...)
+		modified[len(modified)-1] ^= 1
+		_, err := io.ReadAll(NewDecryptReader(bytes.NewReader(modified), provider))
+		assert.EqualError(t, err, "invalid encrypted log: frame 1 is modified or reordered")
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		_, err := io.ReadAll(NewDecryptReader(bytes.NewReader(raw[:len(raw)-1]), provider))
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("failed write", func(t *testing.T) {
+		conf := &OptionsFile{FileLocation: filepath.Join(t.TempDir(), "tdr")}
+		daily, err := newDailyFile(conf)
+		require.NoError(t, err)
+
+		out := &failingFile{fileWriter: daily, fail: 1}
+		w := newEncryptFile(out, provider)
+		now := time.Date(2023, 10, 1, 8, 0, 0, 0, time.Local)
+
+		// the first write of the file failed, so the retry must write the header again
+		require.Error(t, w.writeAt(now, []byte("first\n")))
+		require.NoError(t, w.writeAt(now, []byte("first\n")))
+		out.fail = 1
+		require.Error(t, w.writeAt(now, []byte("second\n")))
+		require.NoError(t, w.writeAt(now, []byte("second\n")))
+		require.NoError(t, w.Close())
+
+		plain, err := decryptFile(conf.FileLocation+".20231001", provider)
+		require.NoError(t, err)
+		assert.Equal(t, "first\nsecond\n", plain)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		other, err := NewStaticKeyProvider(bytes.Repeat([]byte("o"), 32))
+		require.NoError(t, err)
+
+		_, err = decryptFile(file, other)
+		assert.ErrorIs(t, err, ErrKeyMismatch)
+	})
+
+	t.Run("hash chain", func(t *testing.T) {
+		conf := &OptionsFile{FileLocation: filepath.Join(t.TempDir(), "audit"), KeyProvider: provider, HashChain: true, HashChainSecret: chainSecret}
+		writeEncrypted(t, conf, `{"message":"first"}`)
+		file := writeEncrypted(t, conf, `{"message":"second"}`)
+
+		plain, err := decryptFile(file, provider)
+		require.NoError(t, err)
+		assert.Equal(t, 5, strings.Count(plain, "\n"))
+		assert.NoError(t, VerifyEncrypted(conf.FileLocation, []byte(chainSecret), provider))
+		assert.Equal(t, &ChainError{File: file, Line: 0, Reason: "file is encrypted, use VerifyEncrypted"}, Verify(conf.FileLocation, []byte(chainSecret)))
+	})
+}
+
+func TestKeyProvider(t *testing.T) {
+	t.Run("local kms", func(t *testing.T) {
+		kms, err := NewLocalKMS("master-1", encryptKey)
+		require.NoError(t, err)
+
+		conf := &OptionsFile{FileLocation: filepath.Join(t.TempDir(), "tdr"), KeyProvider: kms}
+		file := writeEncrypted(t, conf, `{"message":"first"}`)
+
+		plain, err := decryptFile(file, kms)
+		require.NoError(t, err)
+		assert.Equal(t, "{\"message\":\"first\"}\n", plain)
+
+		rotated, err := NewLocalKMS("master-2", encryptKey)
+		require.NoError(t, err)
+		_, err = decryptFile(file, rotated)
+		assert.ErrorIs(t, err, ErrKeyMismatch)
+
+		other, err := NewLocalKMS("master-1", bytes.Repeat([]byte("o"), 32))
+		require.NoError(t, err)
+		_, err = decryptFile(file, other)
+		assert.ErrorIs(t, err, ErrKeyMismatch)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "key")
+		require.NoError(t, os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(encryptKey)+"\n"), 0o600))
+
+		provider, err := FileKeyProvider(path)
+		require.NoError(t, err)
+
+		key, _, err := provider.DataKey()
+		require.NoError(t, err)
+		assert.Equal(t, encryptKey, key)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := EnvKeyProvider("TEST_LOG_ENCRYPT_KEY_NOT_SET")
+		assert.EqualError(t, err, "environment variable TEST_LOG_ENCRYPT_KEY_NOT_SET is not set")
+
+		t.Setenv("TEST_LOG_ENCRYPT_KEY", "not base64")
+		_, err = EnvKeyProvider("TEST_LOG_ENCRYPT_KEY")
+		assert.ErrorContains(t, err, "invalid base64 key")
+
+		_, err = NewStaticKeyProvider([]byte("short"))
+		assert.Error(t, err)
+
+		_, err = NewLocalKMS("", encryptKey)
+		assert.EqualError(t, err, "invalid key id")
+	})
+}
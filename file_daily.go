@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	rotateLogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/lestrrat-go/strftime"
+)
+
+const dailyFileSuffix = ".%Y%m%d" // the same as file output
+
+// fileWriter write into FileLocation.YYYYMMDD at the given time, so the caller know which file is written
+type fileWriter interface {
+	name(t time.Time) string
+	writeAt(t time.Time, p []byte) error
+	// lastLine returns the last line of file without new line
+	lastLine(path string) ([]byte, error)
+	io.Closer
+}
+
+// fileClock is clock of rotate logs, it is set before each write
+type fileClock struct {
+	t time.Time
+}
+
+func (c *fileClock) Now() time.Time {
+	return c.t
+}
+
+// dailyFile is file output rotated by rotate logs using controlled clock
+type dailyFile struct {
+	out     *rotateLogs.RotateLogs
+	clock   *fileClock
+	pattern *strftime.Strftime
+}
+
+func newDailyFile(conf *OptionsFile) (*dailyFile, error) {
+	pattern, err := strftime.New(conf.FileLocation + dailyFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &dailyFile{clock: &fileClock{}, pattern: pattern}
+	f.out, err = rotateLogs.New(
+		conf.FileLocation+dailyFileSuffix,
+		rotateLogs.WithLinkName(conf.FileLocation),
+		rotateLogs.WithMaxAge(conf.FileMaxAge*24*time.Hour),
+		rotateLogs.WithRotationTime(time.Hour),
+		rotateLogs.WithClock(f.clock),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *dailyFile) name(t time.Time) string {
+	return f.pattern.FormatString(t)
+}
+
+func (f *dailyFile) writeAt(t time.Time, p []byte) error {
+	f.clock.t = t
+	_, err := f.out.Write(p)
+	return err
+}
+
+// lastLine read only the end of the file
+func (f *dailyFile) lastLine(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := info.Size()
+	for window := int64(64 << 10); ; window *= 2 {
+		if window > size {
+			window = size
+		}
+
+		buf := make([]byte, window)
+		if _, err = file.ReadAt(buf, size-window); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		buf = bytes.TrimRight(buf, "\n")
+		if i := bytes.LastIndexByte(buf, '\n'); i >= 0 {
+			return buf[i+1:], nil
+		}
+
+		if window == size {
+			return buf, nil
+		}
+	}
+}
+
+func (f *dailyFile) Close() error {
+	return f.out.Close()
+}
+
+// newFileOutput returns file output with encryption and hash chain, hash chain is computed from plain text
+func newFileOutput(conf *OptionsFile, now func() time.Time) (io.WriteCloser, error) {
+	provider, err := conf.keyProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	var out fileWriter
+	if out, err = newDailyFile(conf); err != nil {
+		return nil, err
+	}
+
+	if provider != nil {
+		out = newEncryptFile(out, provider)
+	}
+
+	if conf.HashChain {
+		return newChainWriter(conf, out, now)
+	}
+
+	return &nowWriter{out: out, now: now}, nil
+}
+
+// nowWriter write into fileWriter using current time
+type nowWriter struct {
+	mu  sync.Mutex
+	out fileWriter
+	now func() time.Time
+}
+
+func (w *nowWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.out.writeAt(w.now(), p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (w *nowWriter) Close() error {
+	return w.out.Close()
+}
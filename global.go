@@ -45,3 +45,7 @@ func Panic(ctx context.Context, message string, fields ...Field) {
 func TDR(ctx context.Context, tdr LogTdrModel) {
 	getInstance().TDR(ctx, tdr)
 }
+
+func Stream(name string) StreamLogger {
+	return StreamOf(getInstance(), name)
+}
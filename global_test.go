@@ -18,6 +18,7 @@ func TestGlobalLogger_Nil(t *testing.T) {
 	Fatal(ctx, message, fields...)
 	Panic(ctx, message, fields...)
 	TDR(ctx, GenerateLogTDR(nil))
+	Stream(StreamAudit).Log(ctx, LogAuditModel{})
 }
 
 func TestGlobalLogger_Noop(t *testing.T) {
@@ -33,4 +34,5 @@ func TestGlobalLogger_Noop(t *testing.T) {
 	Fatal(ctx, message, fields...)
 	Panic(ctx, message, fields...)
 	TDR(ctx, GenerateLogTDR(nil))
+	Stream(StreamAudit).Log(ctx, LogAuditModel{})
 }
@@ -0,0 +1,139 @@
+package grpcmw
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/armiariyan/logger"
+)
+
+// UnaryClientInterceptor returns interceptor which propagate logger.Context into outgoing metadata
+// and write TDR log for each unary call made by this service.
+func UnaryClientInterceptor(log logger.Logger, opts ...Option) grpc.UnaryClientInterceptor {
+	i := newInterceptor(log, opts...)
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := i.now()
+		ctx, ctxVal := i.clientContext(ctx)
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		i.log.TDR(ctx, i.clientTdr(ctx, ctxVal, method, cc.Target(), req, reply, err, i.now().Sub(start).Milliseconds()))
+		return err
+	}
+}
+
+// StreamClientInterceptor returns interceptor which propagate logger.Context into outgoing metadata
+// and write one TDR log when the stream is finished, either by io.EOF or error.
+func StreamClientInterceptor(log logger.Logger, opts ...Option) grpc.StreamClientInterceptor {
+	i := newInterceptor(log, opts...)
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := i.now()
+		ctx, ctxVal := i.clientContext(ctx)
+
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			i.log.TDR(ctx, i.clientTdr(ctx, ctxVal, method, cc.Target(), nil, nil, err, i.now().Sub(start).Milliseconds()))
+			return nil, err
+		}
+
+		wrapped := &clientStream{
+			ClientStream:  cs,
+			serverStreams: desc.ServerStreams,
+			recorder:      newStreamRecorder(i.maxStreamMessages),
+		}
+
+		wrapped.finish = func(err error) {
+			tdr := i.clientTdr(ctx, ctxVal, method, cc.Target(), wrapped.recorder.sent(), wrapped.recorder.received(), err, i.now().Sub(start).Milliseconds())
+			tdr.AdditionalData = wrapped.recorder.counter()
+			i.log.TDR(ctx, tdr)
+		}
+
+		return wrapped, nil
+	}
+}
+
+func (i *interceptor) clientTdr(ctx context.Context, ctxVal logger.Context, method, target string, req, resp interface{}, err error, rt int64) logger.LogTdrModel {
+	md, _ := metadata.FromOutgoingContext(ctx)
+
+	tdr := logger.LogTdrModel{
+		AppName:      ctxVal.ServiceName,
+		AppVersion:   ctxVal.ServiceVersion,
+		ThreadID:     ctxVal.ThreadID,
+		JourneyID:    ctxVal.JourneyID,
+		ChainID:      ctxVal.ChainID,
+		Path:         method,
+		Method:       "GRPC",
+		IP:           target,
+		RespTime:     rt,
+		ResponseCode: status.Code(err).String(),
+		Header:       md,
+		Request:      req,
+		Response:     resp,
+		Direction:    logger.TdrOutbound,
+	}
+
+	if err != nil {
+		tdr.Error = err.Error()
+	}
+
+	return tdr
+}
+
+// clientStream record messages and call finish exactly once when stream is done.
+type clientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	recorder      *streamRecorder
+	once          sync.Once
+	finish        func(err error)
+}
+
+func (c *clientStream) SendMsg(m interface{}) error {
+	err := c.ClientStream.SendMsg(m)
+	if err != nil {
+		// io.EOF means stream is aborted by server, the status is returned by RecvMsg
+		if err != io.EOF {
+			c.done(err)
+		}
+
+		return err
+	}
+
+	c.recorder.send(m)
+	return nil
+}
+
+func (c *clientStream) RecvMsg(m interface{}) error {
+	err := c.ClientStream.RecvMsg(m)
+	if err == io.EOF {
+		c.done(nil)
+		return err
+	}
+
+	if err != nil {
+		c.done(err)
+		return err
+	}
+
+	c.recorder.recv(m)
+
+	// non server streaming call is done after first response
+	if !c.serverStreams {
+		c.done(nil)
+	}
+
+	return nil
+}
+
+func (c *clientStream) done(err error) {
+	c.once.Do(func() {
+		c.finish(err)
+	})
+}
@@ -0,0 +1,278 @@
+package grpcmw_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/armiariyan/logger"
+	"github.com/armiariyan/logger/grpcmw"
+)
+
+// recordLogger hold TDR and error written by interceptor
+type recordLogger struct {
+	logger.NoopContextLogger
+	mu     sync.Mutex
+	tdrs   []logger.LogTdrModel
+	errors []string
+}
+
+func (r *recordLogger) TDR(_ context.Context, tdr logger.LogTdrModel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tdrs = append(r.tdrs, tdr)
+}
+
+func (r *recordLogger) Error(_ context.Context, message string, _ ...logger.Field) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, message)
+}
+
+func (r *recordLogger) all() []logger.LogTdrModel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]logger.LogTdrModel(nil), r.tdrs...)
+}
+
+// healthServer is a server under test, it record the logger context and can panic on demand
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	ctxVal logger.Context
+}
+
+func (h *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	h.ctxVal = logger.ExtractCtx(ctx)
+
+	switch req.Service {
+	case "panic":
+		panic("something wrong")
+	case "unknown":
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (h *healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	h.ctxVal = logger.ExtractCtx(stream.Context())
+
+	for i := 0; i < 3; i++ {
+		if err := stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setup(t *testing.T) (healthpb.HealthClient, *healthServer, *recordLogger, *recordLogger) {
+	serverLog := &recordLogger{}
+	clientLog := &recordLogger{}
+	client, srv := setupWith(t, serverLog, clientLog)
+	return client, srv, serverLog, clientLog
+}
+
+func setupWith(t *testing.T, serverLog, clientLog logger.Logger) (healthpb.HealthClient, *healthServer) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(grpcmw.UnaryServerInterceptor(serverLog, grpcmw.WithContext(logger.Context{ServiceName: "server"}))),
+		grpc.StreamInterceptor(grpcmw.StreamServerInterceptor(serverLog, grpcmw.WithContext(logger.Context{ServiceName: "server"}))),
+	)
+
+	srv := &healthServer{}
+	healthpb.RegisterHealthServer(server, srv)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(grpcmw.UnaryClientInterceptor(clientLog)),
+		grpc.WithStreamInterceptor(grpcmw.StreamClientInterceptor(clientLog)),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		server.Stop()
+	})
+
+	return healthpb.NewHealthClient(conn), srv
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	ctx := logger.InjectCtx(context.Background(), logger.Context{
+		ServiceName: "client",
+		ThreadID:    "thread-1",
+		ChainID:     "chain-1",
+	})
+
+	t.Run("propagate context and log TDR", func(t *testing.T) {
+		client, srv, serverLog, clientLog := setup(t)
+
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "foo"})
+		require.NoError(t, err)
+		assert.EqualValues(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+		assert.EqualValues(t, "server", srv.ctxVal.ServiceName)
+		assert.EqualValues(t, "thread-1", srv.ctxVal.ThreadID)
+		assert.EqualValues(t, "chain-1", srv.ctxVal.ChainID)
+		assert.EqualValues(t, "/grpc.health.v1.Health/Check", srv.ctxVal.ReqURI)
+
+		serverTdr := serverLog.all()
+		require.Len(t, serverTdr, 1)
+		assert.EqualValues(t, "server", serverTdr[0].AppName)
+		assert.EqualValues(t, "thread-1", serverTdr[0].ThreadID)
+		assert.EqualValues(t, "/grpc.health.v1.Health/Check", serverTdr[0].Path)
+		assert.EqualValues(t, codes.OK.String(), serverTdr[0].ResponseCode)
+		assert.EqualValues(t, "foo", serverTdr[0].Request.(*healthpb.HealthCheckRequest).Service)
+		assert.EqualValues(t, healthpb.HealthCheckResponse_SERVING, serverTdr[0].Response.(*healthpb.HealthCheckResponse).Status)
+		assert.EqualValues(t, []string{"thread-1"}, serverTdr[0].Header.(metadata.MD).Get(grpcmw.MetadataThreadID))
+
+		clientTdr := clientLog.all()
+		require.Len(t, clientTdr, 1)
+		assert.EqualValues(t, "client", clientTdr[0].AppName)
+		assert.EqualValues(t, "thread-1", clientTdr[0].ThreadID)
+		assert.EqualValues(t, "/grpc.health.v1.Health/Check", clientTdr[0].Path)
+		assert.EqualValues(t, codes.OK.String(), clientTdr[0].ResponseCode)
+		assert.EqualValues(t, "bufnet", clientTdr[0].IP)
+	})
+
+	t.Run("traceparent", func(t *testing.T) {
+		traceCtx := logger.InjectCtx(context.Background(), logger.Context{
+			TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:     "00f067aa0ba902b7",
+			TraceFlags: "01",
+		})
+
+		for name, tc := range map[string]struct {
+			ctx           context.Context
+			traceID, span string
+		}{
+			// client interceptor write traceparent from logger.Context, server interceptor parse it
+			"valid":     {ctx: traceCtx, traceID: "4bf92f3577b34da6a3ce929d0e0e4736", span: "00f067aa0ba902b7"},
+			"malformed": {ctx: metadata.AppendToOutgoingContext(ctx, grpcmw.MetadataTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-zzz-01")},
+			"missing":   {ctx: ctx},
+		} {
+			t.Run(name, func(t *testing.T) {
+				client, srv, _, _ := setup(t)
+
+				_, err := client.Check(tc.ctx, &healthpb.HealthCheckRequest{Service: "foo"})
+				require.NoError(t, err)
+				assert.EqualValues(t, tc.traceID, srv.ctxVal.TraceID)
+				assert.EqualValues(t, tc.span, srv.ctxVal.SpanID)
+			})
+		}
+	})
+
+	t.Run("log error status", func(t *testing.T) {
+		client, _, serverLog, clientLog := setup(t)
+
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"})
+		assert.EqualValues(t, codes.NotFound, status.Code(err))
+
+		assert.EqualValues(t, codes.NotFound.String(), serverLog.all()[0].ResponseCode)
+		assert.NotEmpty(t, serverLog.all()[0].Error)
+		assert.EqualValues(t, codes.NotFound.String(), clientLog.all()[0].ResponseCode)
+	})
+
+	t.Run("recover panic", func(t *testing.T) {
+		client, _, serverLog, _ := setup(t)
+
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "panic"})
+		assert.EqualValues(t, codes.Internal, status.Code(err))
+
+		assert.EqualValues(t, codes.Internal.String(), serverLog.all()[0].ResponseCode)
+		assert.Len(t, serverLog.errors, 1)
+		assert.Contains(t, serverLog.errors[0], "something wrong")
+	})
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	client, srv, serverLog, clientLog := setup(t)
+
+	ctx := logger.InjectCtx(context.Background(), logger.Context{ThreadID: "thread-2"})
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "foo"})
+	require.NoError(t, err)
+
+	received := 0
+	for {
+		_, err = stream.Recv()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+		received++
+	}
+
+	assert.EqualValues(t, 3, received)
+	assert.EqualValues(t, "thread-2", srv.ctxVal.ThreadID)
+
+	serverTdr := serverLog.all()
+	require.Len(t, serverTdr, 1)
+	assert.EqualValues(t, "/grpc.health.v1.Health/Watch", serverTdr[0].Path)
+	assert.EqualValues(t, codes.OK.String(), serverTdr[0].ResponseCode)
+	assert.Len(t, serverTdr[0].Request, 1)
+	assert.Len(t, serverTdr[0].Response, 3)
+	assert.EqualValues(t, map[string]interface{}{"recvCount": 1, "sendCount": 3}, serverTdr[0].AdditionalData)
+
+	clientTdr := clientLog.all()
+	require.Len(t, clientTdr, 1)
+	assert.EqualValues(t, "thread-2", clientTdr[0].ThreadID)
+	assert.EqualValues(t, map[string]interface{}{"recvCount": 3, "sendCount": 1}, clientTdr[0].AdditionalData)
+}
+
+func TestStreamInterceptor_Mask(t *testing.T) {
+	conf := &logger.OptionsFile{
+		FileLocation:  filepath.Join(t.TempDir(), "tdr"),
+		Mask:          true,
+		OptionsOutput: logger.OptionsOutput{MaskRules: []logger.MaskRule{{Key: "service", Strategy: "any"}}},
+	}
+
+	log := logger.SetupLoggerFile("test", conf)
+	client, _ := setupWith(t, log, log)
+
+	ctx := logger.InjectCtx(context.Background(), logger.Context{ThreadID: "thread-3"})
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unary-secret"})
+	require.NoError(t, err)
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "stream-secret"})
+	require.NoError(t, err)
+	for err == nil {
+		_, err = stream.Recv()
+	}
+
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, log.Close())
+
+	content, err := os.ReadFile(conf.FileLocation)
+	require.NoError(t, err)
+
+	// unary and stream message is masked the same, by key rules since proto message has no mask tag
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 4)
+	assert.NotContains(t, string(content), "unary-secret")
+	assert.NotContains(t, string(content), "stream-secret")
+	assert.Contains(t, string(content), `"req":[{"service":"*************"}]`)
+}
@@ -0,0 +1,132 @@
+package grpcmw
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/armiariyan/logger"
+)
+
+// metadata key must be lower case, see metadata.New
+const (
+	MetadataThreadID  = "correlation-id"
+	MetadataJourneyID = "journey-id"
+	MetadataChainID   = "chain-id"
+
+	// MetadataTraceparent is W3C trace context, see https://www.w3.org/TR/trace-context/
+	MetadataTraceparent = "traceparent"
+)
+
+// defaultMaxStreamMessages is the maximum messages kept per direction for stream TDR log
+const defaultMaxStreamMessages = 10
+
+type interceptor struct {
+	log               logger.Logger
+	ctxVal            logger.Context
+	maxStreamMessages int
+	now               func() time.Time
+}
+
+type Option func(*interceptor)
+
+// WithContext set base logging context, such as service name and version,
+// which copied into every call.
+func WithContext(ctxVal logger.Context) Option {
+	return func(i *interceptor) {
+		i.ctxVal = ctxVal
+	}
+}
+
+// WithMaxStreamMessages set maximum messages per direction written in stream TDR log.
+func WithMaxStreamMessages(n int) Option {
+	return func(i *interceptor) {
+		i.maxStreamMessages = n
+	}
+}
+
+func newInterceptor(log logger.Logger, opts ...Option) *interceptor {
+	i := &interceptor{
+		log:               log,
+		maxStreamMessages: defaultMaxStreamMessages,
+		now:               time.Now,
+	}
+
+	for _, o := range opts {
+		o(i)
+	}
+
+	if i.log == nil {
+		i.log = logger.NewNoopLogger()
+	}
+
+	return i
+}
+
+// serverContext build logging context from base context and incoming metadata,
+// thread id is generated when client doesn't send it.
+func (i *interceptor) serverContext(ctx context.Context, fullMethod string) (context.Context, logger.Context) {
+	ctxVal := i.ctxVal
+	ctxVal.ReqMethod = "GRPC"
+	ctxVal.ReqURI = fullMethod
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctxVal.ThreadID = firstValue(md, MetadataThreadID)
+	if ctxVal.ThreadID == "" {
+		ctxVal.ThreadID = fmt.Sprint(i.now().UnixNano())
+	}
+
+	ctxVal.JourneyID = firstValue(md, MetadataJourneyID)
+	ctxVal.ChainID = firstValue(md, MetadataChainID)
+
+	// malformed traceparent is ignored, the same as W3C trace context
+	if traceID, spanID, flags, ok := logger.ParseTraceparent(firstValue(md, MetadataTraceparent)); ok {
+		ctxVal.TraceID, ctxVal.SpanID, ctxVal.TraceFlags = traceID, spanID, flags
+	}
+
+	return logger.InjectCtx(ctx, ctxVal), ctxVal
+}
+
+// clientContext append logging context of caller into outgoing metadata,
+// so the server can continue the same thread and trace.
+func (i *interceptor) clientContext(ctx context.Context) (context.Context, logger.Context) {
+	ctxVal := logger.ExtractCtx(ctx)
+	if ctxVal.ServiceName == "" {
+		ctxVal.ServiceName = i.ctxVal.ServiceName
+		ctxVal.ServiceVersion = i.ctxVal.ServiceVersion
+	}
+
+	kv := make([]string, 0, 8)
+	for key, val := range map[string]string{
+		MetadataThreadID:  ctxVal.ThreadID,
+		MetadataJourneyID: ctxVal.JourneyID,
+		MetadataChainID:   ctxVal.ChainID,
+	} {
+		if val != "" {
+			kv = append(kv, key, val)
+		}
+	}
+
+	// traceparent set by caller, such as tracing interceptor, is kept
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if traceparent := logger.FormatTraceparent(ctxVal.TraceID, ctxVal.SpanID, ctxVal.TraceFlags); traceparent != "" && len(md.Get(MetadataTraceparent)) <= 0 {
+		kv = append(kv, MetadataTraceparent, traceparent)
+	}
+
+	if len(kv) <= 0 {
+		return ctx, ctxVal
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, kv...), ctxVal
+}
+
+func firstValue(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) <= 0 {
+		return ""
+	}
+
+	return values[0]
+}
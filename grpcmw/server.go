@@ -0,0 +1,193 @@
+package grpcmw
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/armiariyan/logger"
+)
+
+// UnaryServerInterceptor returns interceptor which inject logger.Context from incoming metadata,
+// recover panic as codes.Internal error and write TDR log for each unary call.
+func UnaryServerInterceptor(log logger.Logger, opts ...Option) grpc.UnaryServerInterceptor {
+	i := newInterceptor(log, opts...)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		start := i.now()
+		ctx, ctxVal := i.serverContext(ctx, info.FullMethod)
+
+		defer func() {
+			if p := recover(); p != nil {
+				err = i.recoverPanic(ctx, info.FullMethod, p)
+			}
+
+			i.log.TDR(ctx, i.serverTdr(ctx, ctxVal, info.FullMethod, req, resp, err, i.now().Sub(start).Milliseconds()))
+		}()
+
+		resp, err = handler(ctx, req)
+		return
+	}
+}
+
+// StreamServerInterceptor returns interceptor which inject logger.Context from incoming metadata,
+// recover panic as codes.Internal error and write one TDR log when stream is finished.
+// Only first messages up to WithMaxStreamMessages are written in the log.
+func StreamServerInterceptor(log logger.Logger, opts ...Option) grpc.StreamServerInterceptor {
+	i := newInterceptor(log, opts...)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		start := i.now()
+		ctx, ctxVal := i.serverContext(ss.Context(), info.FullMethod)
+
+		wrapped := &serverStream{
+			ServerStream: ss,
+			ctx:          ctx,
+			recorder:     newStreamRecorder(i.maxStreamMessages),
+		}
+
+		defer func() {
+			if p := recover(); p != nil {
+				err = i.recoverPanic(ctx, info.FullMethod, p)
+			}
+
+			tdr := i.serverTdr(ctx, ctxVal, info.FullMethod, wrapped.recorder.received(), wrapped.recorder.sent(), err, i.now().Sub(start).Milliseconds())
+			tdr.AdditionalData = wrapped.recorder.counter()
+			i.log.TDR(ctx, tdr)
+		}()
+
+		err = handler(srv, wrapped)
+		return
+	}
+}
+
+func (i *interceptor) recoverPanic(ctx context.Context, fullMethod string, p interface{}) error {
+	i.log.Error(ctx, fmt.Sprintf("panic on %s: %v", fullMethod, p),
+		logger.ToField("stack", string(debug.Stack())),
+	)
+
+	return status.Errorf(codes.Internal, "panic: %v", p)
+}
+
+func (i *interceptor) serverTdr(ctx context.Context, ctxVal logger.Context, fullMethod string, req, resp interface{}, err error, rt int64) logger.LogTdrModel {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	tdr := logger.LogTdrModel{
+		AppName:      ctxVal.ServiceName,
+		AppVersion:   ctxVal.ServiceVersion,
+		ThreadID:     ctxVal.ThreadID,
+		JourneyID:    ctxVal.JourneyID,
+		ChainID:      ctxVal.ChainID,
+		Path:         fullMethod,
+		Method:       ctxVal.ReqMethod,
+		Port:         ctxVal.ServicePort,
+		RespTime:     rt,
+		ResponseCode: status.Code(err).String(),
+		Header:       md,
+		Request:      req,
+		Response:     resp,
+		Direction:    logger.TdrInbound,
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		tdr.SrcIP = p.Addr.String()
+	}
+
+	if err != nil {
+		tdr.Error = err.Error()
+	}
+
+	return tdr
+}
+
+// serverStream override context with one containing logger.Context and record messages.
+type serverStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	recorder *streamRecorder
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.recorder.send(m)
+	}
+
+	return err
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.recorder.recv(m)
+	}
+
+	return err
+}
+
+// streamRecorder keep first n messages on each direction and count all of them.
+type streamRecorder struct {
+	mu        sync.Mutex
+	max       int
+	recvMsg   []interface{}
+	sendMsg   []interface{}
+	recvCount int
+	sendCount int
+}
+
+func newStreamRecorder(max int) *streamRecorder {
+	return &streamRecorder{max: max}
+}
+
+func (r *streamRecorder) recv(m interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.recvCount++
+	if len(r.recvMsg) < r.max {
+		r.recvMsg = append(r.recvMsg, m)
+	}
+}
+
+func (r *streamRecorder) send(m interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sendCount++
+	if len(r.sendMsg) < r.max {
+		r.sendMsg = append(r.sendMsg, m)
+	}
+}
+
+func (r *streamRecorder) received() interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.recvMsg
+}
+
+func (r *streamRecorder) sent() interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sendMsg
+}
+
+func (r *streamRecorder) counter() map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return map[string]interface{}{
+		"recvCount": r.recvCount,
+		"sendCount": r.sendCount,
+	}
+}
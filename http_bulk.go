@@ -0,0 +1,217 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+)
+
+const (
+	HTTPBulkElasticsearch = "elasticsearch"
+	HTTPBulkNDJSON        = "ndjson"
+
+	httpBulkIndexDate      = "2006.01.02"
+	defaultHTTPBulkTimeout = 10 * time.Second
+)
+
+type OptionsHTTPBulk struct {
+	URL           string            `json:"url" validate:"required,url"`
+	Format        string            `json:"format" validate:"omitempty,oneof=elasticsearch ndjson"` // default is elasticsearch
+	IndexPrefix   string            `json:"indexPrefix"`                                            // prefix of sys-YYYY.MM.DD and tdr-YYYY.MM.DD index
+	Headers       map[string]string `json:"headers"`
+	Username      string            `json:"username"`
+	Password      string            `json:"password"`
+	APIKey        string            `json:"apiKey"` // Elasticsearch API key, sent as "Authorization: ApiKey <key>"
+	Gzip          bool              `json:"gzip"`
+	BatchSize     int               `json:"batchSize"`     // default is 500 records
+	FlushInterval time.Duration     `json:"flushInterval"` // default is 1 second
+	BufferSize    int               `json:"bufferSize"`    // default is 10000 records
+	MaxRetries    int               `json:"maxRetries"`    // default is 3, negative value disable retry
+	RetryBackoff  time.Duration     `json:"retryBackoff"`  // default is 100 milliseconds, doubled on each retry
+	Timeout       time.Duration     `json:"timeout"`       // default is 10 seconds
+	Mask          bool              `json:"mask"`
+	OptionsOutput
+	Level Level `json:"level"`
+
+	// Client is used instead of default client when it is set, such as to use custom TLS config
+	Client *http.Client `json:"-"`
+	// OnError is called when records cannot be sent, default is writing it into stderr
+	OnError func(error) `json:"-"`
+}
+
+// httpBulkSender POST batch of records as NDJSON, using Elasticsearch bulk API or as is
+type httpBulkSender struct {
+	conf   *OptionsHTTPBulk
+	client *http.Client
+	now    func() time.Time
+}
+
+func newHTTPBulkWriter(conf *OptionsHTTPBulk) *batchWriter {
+	client := conf.Client
+	if client == nil {
+		timeout := conf.Timeout
+		if timeout <= 0 {
+			timeout = defaultHTTPBulkTimeout
+		}
+
+		client = &http.Client{Timeout: timeout}
+	}
+
+	sender := &httpBulkSender{conf: conf, client: client, now: time.Now}
+	return newBatchWriter(batchConfig{
+		size:          conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+		bufferSize:    conf.BufferSize,
+		maxRetries:    conf.MaxRetries,
+		retryBackoff:  conf.RetryBackoff,
+		onError:       conf.OnError,
+	}, sender.send)
+}
+
+func (s *httpBulkSender) isElasticsearch() bool {
+	return s.conf.Format == "" || s.conf.Format == HTTPBulkElasticsearch
+}
+
+func (s *httpBulkSender) send(records [][]byte) ([][]byte, error) {
+	body := &bytes.Buffer{}
+	var w io.Writer = body
+
+	var gz *gzip.Writer
+	if s.conf.Gzip {
+		gz = gzip.NewWriter(body)
+		w = gz
+	}
+
+	date := s.now().UTC().Format(httpBulkIndexDate)
+	for _, record := range records {
+		if s.isElasticsearch() {
+			_, _ = fmt.Fprintf(w, `{"index":{"_index":%q}}`+"\n", s.index(record, date))
+		}
+
+		_, _ = w.Write(record)
+		if !bytes.HasSuffix(record, []byte("\n")) {
+			_, _ = w.Write([]byte("\n"))
+		}
+	}
+
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return nil, fmt.Errorf("gzip error: %w", err)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.conf.URL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if gz != nil {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	if s.conf.Username != "" {
+		req.SetBasicAuth(s.conf.Username, s.conf.Password)
+	}
+
+	if s.conf.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+s.conf.APIKey)
+	}
+
+	for k, v := range s.conf.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return records, err
+	}
+
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return records, fmt.Errorf("http bulk status %d: %s", resp.StatusCode, truncateBody(respBody))
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("http bulk status %d: %s", resp.StatusCode, truncateBody(respBody))
+	}
+
+	if !s.isElasticsearch() {
+		return nil, nil
+	}
+
+	return bulkItemErrors(records, respBody)
+}
+
+// index returns index name of record based on its logType, such as sys-2023.01.02
+func (s *httpBulkSender) index(record []byte, date string) string {
+	var r struct {
+		LogType string `json:"logType"`
+	}
+
+	_ = json.Unmarshal(record, &r)
+	if r.LogType == "" {
+		r.LogType = LogTypeSYS
+	}
+
+	return s.conf.IndexPrefix + strings.ToLower(r.LogType) + "-" + date
+}
+
+// bulkItemErrors returns records rejected by Elasticsearch which should be retried,
+// since bulk API returns 200 even when some of the records are rejected
+func bulkItemErrors(records [][]byte, body []byte) ([][]byte, error) {
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil || !resp.Errors {
+		return nil, nil
+	}
+
+	var retry [][]byte
+	var failed int
+	var firstErr json.RawMessage
+	for i, item := range resp.Items {
+		for _, result := range item {
+			if result.Status < http.StatusMultipleChoices || i >= len(records) {
+				continue
+			}
+
+			failed++
+			if firstErr == nil {
+				firstErr = result.Error
+			}
+
+			if result.Status == http.StatusTooManyRequests || result.Status >= http.StatusInternalServerError {
+				retry = append(retry, records[i])
+			}
+		}
+	}
+
+	if failed <= 0 {
+		return nil, nil
+	}
+
+	return retry, fmt.Errorf("http bulk %d items rejected: %s", failed, truncateBody(firstErr))
+}
+
+func truncateBody(b []byte) string {
+	const max = 512
+	if len(b) > max {
+		return string(b[:max]) + "..."
+	}
+
+	return string(b)
+}
@@ -0,0 +1,215 @@
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bulkServer is fake Elasticsearch bulk API which record each NDJSON line
+type bulkServer struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	lines    [][]string
+	respond  func(attempt int, lines []string) (int, string)
+}
+
+func (s *bulkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		body = gz
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	s.lines = append(s.lines, lines)
+	attempt := len(s.requests)
+	s.mu.Unlock()
+
+	status, resp := http.StatusOK, `{"errors":false}`
+	if s.respond != nil {
+		status, resp = s.respond(attempt, lines)
+	}
+
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(resp))
+}
+
+func TestWithHTTPBulkOutput(t *testing.T) {
+	date := time.Now().UTC().Format(httpBulkIndexDate)
+
+	t.Run("elasticsearch", func(t *testing.T) {
+		s := &bulkServer{}
+		server := httptest.NewServer(s)
+		defer server.Close()
+
+		log, err := newLogger(WithHTTPBulkOutput(&OptionsHTTPBulk{
+			URL:         server.URL + "/_bulk",
+			IndexPrefix: "app-",
+			APIKey:      "key",
+			Headers:     map[string]string{"X-Tenant": "a"},
+			Gzip:        true,
+		}))
+		require.NoError(t, err)
+
+		log.Info(ctx, logMessage)
+		log.TDR(ctx, LogTdrModel{})
+		require.NoError(t, log.Close())
+
+		require.Len(t, s.requests, 1)
+		assert.Equal(t, "ApiKey key", s.requests[0].Header.Get("Authorization"))
+		assert.Equal(t, "a", s.requests[0].Header.Get("X-Tenant"))
+		assert.Equal(t, "application/x-ndjson", s.requests[0].Header.Get("Content-Type"))
+
+		lines := s.lines[0]
+		require.Len(t, lines, 4)
+		assert.Equal(t, `{"index":{"_index":"app-sys-`+date+`"}}`, lines[0])
+		assert.Contains(t, lines[1], `"message":"log message"`)
+		assert.Equal(t, `{"index":{"_index":"app-tdr-`+date+`"}}`, lines[2])
+		assert.Contains(t, lines[3], `"logType":"TDR"`)
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		s := &bulkServer{}
+		server := httptest.NewServer(s)
+		defer server.Close()
+
+		log, err := newLogger(WithHTTPBulkOutput(&OptionsHTTPBulk{
+			URL:      server.URL,
+			Format:   HTTPBulkNDJSON,
+			Username: "user",
+			Password: "pass",
+		}))
+		require.NoError(t, err)
+
+		log.Info(ctx, logMessage)
+		require.NoError(t, log.Close())
+
+		require.Len(t, s.lines, 1)
+		require.Len(t, s.lines[0], 1)
+		assert.Contains(t, s.lines[0][0], `"message":"log message"`)
+
+		user, pass, ok := s.requests[0].BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "pass", pass)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		s := &bulkServer{respond: func(attempt int, lines []string) (int, string) {
+			switch attempt {
+			case 1:
+				return http.StatusServiceUnavailable, "unavailable"
+			case 2:
+				// the second record is rejected because of rate limit
+				return http.StatusOK, `{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}}]}`
+			}
+
+			return http.StatusOK, `{"errors":false}`
+		}}
+		server := httptest.NewServer(s)
+		defer server.Close()
+
+		var errs []error
+		log, err := newLogger(WithHTTPBulkOutput(&OptionsHTTPBulk{
+			URL:          server.URL,
+			RetryBackoff: time.Millisecond,
+			OnError:      func(err error) { errs = append(errs, err) },
+		}))
+		require.NoError(t, err)
+
+		log.Info(ctx, "first")
+		log.Info(ctx, "second")
+		require.NoError(t, log.Close())
+
+		require.Len(t, s.lines, 3)
+		assert.Len(t, s.lines[0], 4)
+		assert.Len(t, s.lines[1], 4)
+		require.Len(t, s.lines[2], 2)
+		assert.Contains(t, s.lines[2][1], `"message":"second"`)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("permanent error", func(t *testing.T) {
+		s := &bulkServer{respond: func(int, []string) (int, string) {
+			return http.StatusBadRequest, "bad request"
+		}}
+		server := httptest.NewServer(s)
+		defer server.Close()
+
+		var errs []error
+		log, err := newLogger(WithHTTPBulkOutput(&OptionsHTTPBulk{
+			URL:     server.URL,
+			OnError: func(err error) { errs = append(errs, err) },
+		}))
+		require.NoError(t, err)
+
+		log.Info(ctx, logMessage)
+		require.NoError(t, log.Close())
+
+		assert.Len(t, s.requests, 1)
+		require.Len(t, errs, 1)
+		assert.True(t, strings.Contains(errs[0].Error(), "status 400"), errs[0].Error())
+	})
+
+	t.Run("combine config", func(t *testing.T) {
+		s := &bulkServer{}
+		server := httptest.NewServer(s)
+		defer server.Close()
+
+		var options Options
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"name": "my service name",
+			"sysOptions": {"type": "httpbulk", "optionsHTTPBulk": {"url": "`+server.URL+`", "format": "ndjson", "mask": true, "level": 1}},
+			"tdrOptions": {"type": "httpbulk", "optionsHTTPBulk": {"url": "`+server.URL+`", "format": "ndjson", "mask": true, "maskRules": [{"key": "token", "strategy": "any"}]}}
+		}`), &options))
+
+		log := SetupLoggerCombine(options)
+		log.Info(ctx, "below level")
+		log.Warn(ctx, logMessage, Field{Key: "card", Val: maskerData{Card: "4111111111111111"}})
+		log.TDR(ctx, LogTdrModel{Request: map[string]interface{}{"token": "secret-token"}})
+		require.NoError(t, log.Close())
+
+		var lines []string
+		for _, l := range s.lines {
+			lines = append(lines, l...)
+		}
+
+		require.Len(t, lines, 2)
+		assert.Contains(t, strings.Join(lines, "\n"), `"message":"log message"`)
+		assert.NotContains(t, strings.Join(lines, "\n"), "below level")
+		assert.NotContains(t, strings.Join(lines, "\n"), "4111111111111111")
+		assert.NotContains(t, strings.Join(lines, "\n"), "secret-token")
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := newLogger(WithHTTPBulkOutput(&OptionsHTTPBulk{URL: "not url"}))
+		assert.Error(t, err)
+
+		_, err = newLogger(WithHTTPBulkOutput(&OptionsHTTPBulk{URL: "http://localhost", Format: "xml"}))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,101 @@
+package httpmw
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"strings"
+	"sync"
+)
+
+// bodyRecorder keep first limit bytes written into it and discard the rest,
+// so large or streaming body never fully held in memory. It is safe for concurrent use, since
+// http.RoundTripper may still write request body in another goroutine after RoundTrip returns.
+type bodyRecorder struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+func newBodyRecorder(limit int64) *bodyRecorder {
+	return &bodyRecorder{limit: limit}
+}
+
+func (b *bodyRecorder) Write(p []byte) (n int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	remaining := b.limit - int64(b.buf.Len())
+	if remaining <= 0 {
+		b.truncated = b.truncated || (b.limit > 0 && len(p) > 0)
+		return len(p), nil
+	}
+
+	if int64(len(p)) > remaining {
+		b.truncated = true
+		b.buf.Write(p[:remaining])
+		return len(p), nil
+	}
+
+	b.buf.Write(p)
+	return len(p), nil
+}
+
+// String returns captured body, truncated body is suffixed with "..."
+func (b *bodyRecorder) String() string {
+	if b == nil {
+		return ""
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.buf.Len() <= 0 {
+		return ""
+	}
+
+	if b.truncated {
+		return b.buf.String() + "..."
+	}
+
+	return b.buf.String()
+}
+
+var _ io.Writer = (*bodyRecorder)(nil)
+
+// teeReadCloser record everything read by handler from request body.
+type teeReadCloser struct {
+	io.ReadCloser
+	recorder *bodyRecorder
+}
+
+func (t *teeReadCloser) Read(p []byte) (n int, err error) {
+	n, err = t.ReadCloser.Read(p)
+	if n > 0 {
+		_, _ = t.recorder.Write(p[:n])
+	}
+
+	return
+}
+
+func allowedContentType(allowlist []string, contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+
+	mediaType = strings.ToLower(mediaType)
+	for _, allowed := range allowlist {
+		allowed = strings.ToLower(allowed)
+		if mediaType == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,198 @@
+package httpmw
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/armiariyan/logger"
+)
+
+const (
+	HeaderThreadID  = "Correlation-ID"
+	HeaderJourneyID = "Journey-ID"
+	HeaderChainID   = "Chain-ID"
+
+	// HeaderTraceparent is W3C trace context header, see https://www.w3.org/TR/trace-context/
+	HeaderTraceparent = "Traceparent"
+)
+
+// defaultMaxBodySize is the maximum bytes of request and response body kept for TDR log
+const defaultMaxBodySize = 64 * 1024
+
+// defaultContentTypes is content type which body is captured when no allowlist is configured
+var defaultContentTypes = []string{
+	"application/json",
+	"application/xml",
+	"application/x-www-form-urlencoded",
+	"text/",
+}
+
+type middleware struct {
+	log          logger.Logger
+	ctxVal       logger.Context
+	maxBodySize  int64
+	contentTypes []string
+	now          func() time.Time
+}
+
+type Option func(*middleware)
+
+// WithContext set base logging context, such as service name and version,
+// which copied into every request.
+func WithContext(ctxVal logger.Context) Option {
+	return func(m *middleware) {
+		m.ctxVal = ctxVal
+	}
+}
+
+// WithMaxBodySize set maximum bytes of request and response body captured in TDR.
+// Body is still streamed as is, only the log is truncated. Zero or negative value disable body capture.
+func WithMaxBodySize(size int64) Option {
+	return func(m *middleware) {
+		m.maxBodySize = size
+	}
+}
+
+// WithContentTypes set content type prefix allowlist which body will be captured.
+// Body with content type not listed here will not be logged, such as file upload or binary stream.
+func WithContentTypes(contentTypes ...string) Option {
+	return func(m *middleware) {
+		m.contentTypes = contentTypes
+	}
+}
+
+// Middleware returns net/http middleware which inject logger.Context into request context
+// and write TDR log using log.TDR after the next handler is done.
+func Middleware(log logger.Logger, opts ...Option) func(http.Handler) http.Handler {
+	return newMiddleware(log, opts...).handler
+}
+
+func newMiddleware(log logger.Logger, opts ...Option) *middleware {
+	m := &middleware{
+		log:          log,
+		maxBodySize:  defaultMaxBodySize,
+		contentTypes: defaultContentTypes,
+		now:          time.Now,
+	}
+
+	for _, o := range opts {
+		o(m)
+	}
+
+	if m.log == nil {
+		m.log = logger.NewNoopLogger()
+	}
+
+	return m
+}
+
+func (m *middleware) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := m.now()
+
+		ctxVal := m.requestContext(r)
+		ctx := logger.InjectCtx(r.Context(), ctxVal)
+		r = r.WithContext(ctx)
+
+		reqBody := newBodyRecorder(m.limitFor(r.Header.Get("Content-Type")))
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &teeReadCloser{ReadCloser: r.Body, recorder: reqBody}
+		}
+
+		// if we want, we can also add trace id in every response header,
+		// and then easily query in kibana by "threadID"
+		w.Header().Set(HeaderThreadID, ctxVal.ThreadID)
+
+		rec := &responseRecorder{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+			limitFor:       m.limitFor,
+		}
+
+		defer func() {
+			errMsg := ""
+			p := recover()
+			if p != nil {
+				rec.status = http.StatusInternalServerError
+				errMsg = fmt.Sprint(p)
+			}
+
+			m.log.TDR(ctx, logger.LogTdrModel{
+				AppName:        ctxVal.ServiceName,
+				AppVersion:     ctxVal.ServiceVersion,
+				ThreadID:       ctxVal.ThreadID,
+				JourneyID:      ctxVal.JourneyID,
+				ChainID:        ctxVal.ChainID,
+				Path:           r.URL.Path,
+				Method:         r.Method,
+				IP:             localIP(r),
+				Port:           ctxVal.ServicePort,
+				SrcIP:          r.RemoteAddr,
+				RespTime:       m.now().Sub(start).Milliseconds(),
+				ResponseCode:   strconv.Itoa(rec.status),
+				Header:         r.Header,
+				Request:        reqBody.String(),
+				Response:       rec.body.String(),
+				Error:          errMsg,
+				AdditionalData: map[string]interface{}{},
+				Direction:      logger.TdrInbound,
+			})
+
+			// let http.Server or other recovery middleware handle the panic
+			if p != nil {
+				panic(p)
+			}
+		}()
+
+		next.ServeHTTP(wrapResponseWriter(rec), r)
+	})
+}
+
+// requestContext copy base context and fill request scoped value, such as thread id
+// from header or generate new one when not exist.
+func (m *middleware) requestContext(r *http.Request) logger.Context {
+	ctxVal := m.ctxVal
+	ctxVal.ReqMethod = r.Method
+	ctxVal.ReqURI = r.URL.Path
+
+	ctxVal.ThreadID = r.Header.Get(HeaderThreadID)
+	if ctxVal.ThreadID == "" {
+		ctxVal.ThreadID = fmt.Sprint(m.now().UnixNano())
+	}
+
+	ctxVal.JourneyID = r.Header.Get(HeaderJourneyID)
+	ctxVal.ChainID = r.Header.Get(HeaderChainID)
+
+	// malformed traceparent is ignored, the same as W3C trace context
+	if traceID, spanID, flags, ok := logger.ParseTraceparent(r.Header.Get(HeaderTraceparent)); ok {
+		ctxVal.TraceID, ctxVal.SpanID, ctxVal.TraceFlags = traceID, spanID, flags
+	}
+
+	return ctxVal
+}
+
+// limitFor returns number of body bytes captured for given content type.
+func (m *middleware) limitFor(contentType string) int64 {
+	if m.maxBodySize <= 0 || !allowedContentType(m.contentTypes, contentType) {
+		return 0
+	}
+
+	return m.maxBodySize
+}
+
+func localIP(r *http.Request) string {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok || addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
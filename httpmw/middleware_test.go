@@ -0,0 +1,210 @@
+package httpmw_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/armiariyan/logger"
+	"github.com/armiariyan/logger/httpmw"
+)
+
+// recordLogger hold TDR written by middleware
+type recordLogger struct {
+	logger.NoopContextLogger
+	mu   sync.Mutex
+	ctx  context.Context
+	tdrs []logger.LogTdrModel
+}
+
+func (r *recordLogger) TDR(ctx context.Context, tdr logger.LogTdrModel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ctx = ctx
+	r.tdrs = append(r.tdrs, tdr)
+}
+
+func (r *recordLogger) last() logger.LogTdrModel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tdrs[len(r.tdrs)-1]
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("inject context and log TDR", func(t *testing.T) {
+		log := &recordLogger{}
+		mw := httpmw.Middleware(log, httpmw.WithContext(logger.Context{
+			ServiceName:    "my app",
+			ServiceVersion: "1",
+			ServicePort:    3000,
+		}))
+
+		var handlerCtx logger.Context
+		h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handlerCtx = logger.ExtractCtx(r.Context())
+
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.EqualValues(t, `{"action":"hello"}`, string(body))
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"success":true}`))
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/hello", strings.NewReader(`{"action":"hello"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(httpmw.HeaderThreadID, "thread-1")
+		req.Header.Set(httpmw.HeaderChainID, "chain-1")
+
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, req)
+
+		assert.EqualValues(t, http.StatusCreated, resp.Code)
+		assert.EqualValues(t, `{"success":true}`, resp.Body.String())
+		assert.EqualValues(t, "thread-1", resp.Header().Get(httpmw.HeaderThreadID))
+
+		assert.EqualValues(t, "my app", handlerCtx.ServiceName)
+		assert.EqualValues(t, "thread-1", handlerCtx.ThreadID)
+		assert.EqualValues(t, "chain-1", handlerCtx.ChainID)
+		assert.EqualValues(t, http.MethodPost, handlerCtx.ReqMethod)
+		assert.EqualValues(t, "/v1/hello", handlerCtx.ReqURI)
+
+		tdr := log.last()
+		assert.EqualValues(t, handlerCtx, logger.ExtractCtx(log.ctx))
+		assert.EqualValues(t, "my app", tdr.AppName)
+		assert.EqualValues(t, "1", tdr.AppVersion)
+		assert.EqualValues(t, "thread-1", tdr.ThreadID)
+		assert.EqualValues(t, "chain-1", tdr.ChainID)
+		assert.EqualValues(t, "/v1/hello", tdr.Path)
+		assert.EqualValues(t, http.MethodPost, tdr.Method)
+		assert.EqualValues(t, "201", tdr.ResponseCode)
+		assert.EqualValues(t, `{"action":"hello"}`, tdr.Request)
+		assert.EqualValues(t, `{"success":true}`, tdr.Response)
+		assert.GreaterOrEqual(t, tdr.RespTime, int64(0))
+	})
+
+	t.Run("generate thread id when header not exist", func(t *testing.T) {
+		log := &recordLogger{}
+		h := httpmw.Middleware(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		tdr := log.last()
+		assert.NotEmpty(t, tdr.ThreadID)
+		assert.EqualValues(t, tdr.ThreadID, resp.Header().Get(httpmw.HeaderThreadID))
+		assert.EqualValues(t, "200", tdr.ResponseCode)
+		assert.Empty(t, tdr.Response)
+	})
+
+	t.Run("traceparent", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			header        string
+			traceID, span string
+		}{
+			"valid":     {header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", traceID: "4bf92f3577b34da6a3ce929d0e0e4736", span: "00f067aa0ba902b7"},
+			"malformed": {header: "00-4bf92f3577b34da6a3ce929d0e0e4736-zzz-01"},
+			"missing":   {},
+		} {
+			t.Run(name, func(t *testing.T) {
+				log := &recordLogger{}
+				h := httpmw.Middleware(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				if tc.header != "" {
+					req.Header.Set(httpmw.HeaderTraceparent, tc.header)
+				}
+
+				h.ServeHTTP(httptest.NewRecorder(), req)
+
+				ctxVal := logger.ExtractCtx(log.ctx)
+				assert.EqualValues(t, tc.traceID, ctxVal.TraceID)
+				assert.EqualValues(t, tc.span, ctxVal.SpanID)
+			})
+		}
+	})
+
+	t.Run("truncate body bigger than max size", func(t *testing.T) {
+		log := &recordLogger{}
+		h := httpmw.Middleware(log, httpmw.WithMaxBodySize(4))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("hello world"))
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("request body"))
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, req)
+
+		// client still receive full body
+		assert.EqualValues(t, "hello world", resp.Body.String())
+
+		tdr := log.last()
+		assert.EqualValues(t, "requ...", tdr.Request)
+		assert.EqualValues(t, "hell...", tdr.Response)
+	})
+
+	t.Run("skip body with content type not in allowlist", func(t *testing.T) {
+		log := &recordLogger{}
+		h := httpmw.Middleware(log, httpmw.WithContentTypes("application/json"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte{0x00, 0x01})
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("binary"))
+		req.Header.Set("Content-Type", "application/octet-stream")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		tdr := log.last()
+		assert.Empty(t, tdr.Request)
+		assert.Empty(t, tdr.Response)
+	})
+
+	t.Run("preserve optional interfaces", func(t *testing.T) {
+		log := &recordLogger{}
+
+		var isFlusher, isHijacker bool
+		h := httpmw.Middleware(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, isHijacker = w.(http.Hijacker)
+
+			var f http.Flusher
+			f, isFlusher = w.(http.Flusher)
+			_, _ = w.Write([]byte("chunk"))
+			f.Flush()
+		}))
+
+		// httptest.ResponseRecorder only implement http.Flusher
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.True(t, isFlusher)
+		assert.False(t, isHijacker)
+		assert.True(t, resp.Flushed)
+		assert.EqualValues(t, "chunk", log.last().Response)
+	})
+
+	t.Run("log TDR on panic", func(t *testing.T) {
+		log := &recordLogger{}
+		h := httpmw.Middleware(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("something wrong")
+		}))
+
+		assert.Panics(t, func() {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+
+		tdr := log.last()
+		assert.EqualValues(t, "500", tdr.ResponseCode)
+		assert.EqualValues(t, "something wrong", tdr.Error)
+	})
+}
@@ -0,0 +1,124 @@
+package httpmw
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// responseRecorder write response directly into the real writer,
+// and keep status code and first bytes of body for TDR log.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        *bodyRecorder
+	limitFor    func(contentType string) int64
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.wroteHeader = true
+		r.status = code
+	}
+
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.body == nil {
+		// same as net/http, sniff content type when handler doesn't set it
+		contentType := r.Header().Get("Content-Type")
+		if contentType == "" {
+			contentType = http.DetectContentType(p)
+		}
+
+		r.body = newBodyRecorder(r.limitFor(contentType))
+	}
+
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(p)
+	if n > 0 {
+		_, _ = r.body.Write(p[:n])
+	}
+
+	return n, err
+}
+
+// Unwrap used by http.ResponseController to reach the original writer.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+type flusher struct{ *responseRecorder }
+
+func (f flusher) Flush() {
+	f.wroteHeader = true
+	f.ResponseWriter.(http.Flusher).Flush()
+}
+
+type hijacker struct{ *responseRecorder }
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+type pusher struct{ *responseRecorder }
+
+func (p pusher) Push(target string, opts *http.PushOptions) error {
+	return p.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// wrapResponseWriter returns writer which only implement optional interfaces
+// (http.Flusher, http.Hijacker and http.Pusher) supported by the original writer,
+// so type assertion in handler behave the same as without middleware.
+func wrapResponseWriter(r *responseRecorder) http.ResponseWriter {
+	_, isFlusher := r.ResponseWriter.(http.Flusher)
+	_, isHijacker := r.ResponseWriter.(http.Hijacker)
+	_, isPusher := r.ResponseWriter.(http.Pusher)
+
+	switch {
+	case isFlusher && isHijacker && isPusher:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{r, flusher{r}, hijacker{r}, pusher{r}}
+	case isFlusher && isHijacker:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Hijacker
+		}{r, flusher{r}, hijacker{r}}
+	case isFlusher && isPusher:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Pusher
+		}{r, flusher{r}, pusher{r}}
+	case isHijacker && isPusher:
+		return struct {
+			*responseRecorder
+			http.Hijacker
+			http.Pusher
+		}{r, hijacker{r}, pusher{r}}
+	case isFlusher:
+		return struct {
+			*responseRecorder
+			http.Flusher
+		}{r, flusher{r}}
+	case isHijacker:
+		return struct {
+			*responseRecorder
+			http.Hijacker
+		}{r, hijacker{r}}
+	case isPusher:
+		return struct {
+			*responseRecorder
+			http.Pusher
+		}{r, pusher{r}}
+	}
+
+	return r
+}
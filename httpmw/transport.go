@@ -0,0 +1,139 @@
+package httpmw
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/armiariyan/logger"
+)
+
+type transport struct {
+	*middleware
+	base http.RoundTripper
+}
+
+// Transport wrap base http.RoundTripper (http.DefaultTransport when nil) to write TDR log
+// for each outbound call, and propagate thread, journey and chain id of logger.Context as request header,
+// with its trace id and span id as W3C traceparent header.
+// TDR is written when response body is closed, so the logged response contains the body read by caller.
+func Transport(log logger.Logger, base http.RoundTripper, opts ...Option) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{
+		middleware: newMiddleware(log, opts...),
+		base:       base,
+	}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := t.now()
+
+	ctxVal := logger.ExtractCtx(req.Context())
+	if ctxVal.ServiceName == "" {
+		ctxVal.ServiceName = t.ctxVal.ServiceName
+		ctxVal.ServiceVersion = t.ctxVal.ServiceVersion
+	}
+
+	// RoundTripper must not modify the request, so change the clone instead
+	req = req.Clone(req.Context())
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	setHeader(req.Header, HeaderThreadID, ctxVal.ThreadID)
+	setHeader(req.Header, HeaderJourneyID, ctxVal.JourneyID)
+	setHeader(req.Header, HeaderChainID, ctxVal.ChainID)
+	setHeader(req.Header, HeaderTraceparent, logger.FormatTraceparent(ctxVal.TraceID, ctxVal.SpanID, ctxVal.TraceFlags))
+
+	reqBody := newBodyRecorder(t.limitFor(req.Header.Get("Content-Type")))
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &teeReadCloser{ReadCloser: req.Body, recorder: reqBody}
+	}
+
+	tdr := logger.LogTdrModel{
+		AppName:    ctxVal.ServiceName,
+		AppVersion: ctxVal.ServiceVersion,
+		ThreadID:   ctxVal.ThreadID,
+		JourneyID:  ctxVal.JourneyID,
+		ChainID:    ctxVal.ChainID,
+		Path:       urlWithoutQuery(req.URL),
+		Method:     req.Method,
+		IP:         req.URL.Hostname(),
+		Header:     req.Header,
+		Direction:  logger.TdrOutbound,
+	}
+
+	if port, err := strconv.Atoi(req.URL.Port()); err == nil {
+		tdr.Port = port
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	tdr.RespTime = t.now().Sub(start).Milliseconds()
+
+	if err != nil {
+		tdr.Request = reqBody.String()
+		tdr.Error = err.Error()
+		t.log.TDR(req.Context(), tdr)
+		return nil, err
+	}
+
+	tdr.ResponseCode = strconv.Itoa(resp.StatusCode)
+	respBody := newBodyRecorder(t.limitFor(resp.Header.Get("Content-Type")))
+
+	if resp.Body == nil || resp.Body == http.NoBody {
+		tdr.Request = reqBody.String()
+		t.log.TDR(req.Context(), tdr)
+		return resp, nil
+	}
+
+	resp.Body = &logOnCloseBody{
+		teeReadCloser: teeReadCloser{ReadCloser: resp.Body, recorder: respBody},
+		onClose: func() {
+			// request body is read here, since transport may write it until the response is received
+			tdr.Request = reqBody.String()
+			tdr.Response = respBody.String()
+			t.log.TDR(req.Context(), tdr)
+		},
+	}
+
+	return resp, nil
+}
+
+// logOnCloseBody record response body read by caller and write TDR once it is closed.
+type logOnCloseBody struct {
+	teeReadCloser
+	once    sync.Once
+	onClose func()
+}
+
+func (b *logOnCloseBody) Close() error {
+	err := b.teeReadCloser.Close()
+	b.once.Do(b.onClose)
+	return err
+}
+
+var _ io.ReadCloser = (*logOnCloseBody)(nil)
+
+// urlWithoutQuery returns URL without user info, query and fragment, which may contain credential
+func urlWithoutQuery(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	clean.RawQuery = ""
+	clean.ForceQuery = false
+	clean.Fragment = ""
+	clean.RawFragment = ""
+	return clean.String()
+}
+
+func setHeader(header http.Header, key, value string) {
+	if value == "" || header.Get(key) != "" {
+		return
+	}
+
+	header.Set(key, value)
+}
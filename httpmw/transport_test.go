@@ -0,0 +1,168 @@
+package httpmw_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armiariyan/logger"
+	"github.com/armiariyan/logger/httpmw"
+)
+
+// lateBodyRoundTripper returns response before request body is written, like http.Transport writing
+// request body in another goroutine
+type lateBodyRoundTripper struct{}
+
+func (lateBodyRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = io.Copy(io.Discard, req.Body)
+		_ = req.Body.Close()
+		_, _ = pw.Write([]byte(`{"status":"00"}`))
+		_ = pw.Close()
+	}()
+
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"application/json"}}, Body: pr}, nil
+}
+
+// headerRoundTripper record request header and returns empty response
+type headerRoundTripper struct {
+	header http.Header
+}
+
+func (h *headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.header = req.Header
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+}
+
+type errRoundTripper struct{}
+
+func (errRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestTransport(t *testing.T) {
+	ctx := logger.InjectCtx(context.Background(), logger.Context{
+		ServiceName: "caller",
+		ThreadID:    "thread-1",
+		JourneyID:   "journey-1",
+		ChainID:     "chain-1",
+	})
+
+	t.Run("propagate context and log TDR after body closed", func(t *testing.T) {
+		// partner service also use middleware, so both side can be traced with same chain id
+		partnerLog := &recordLogger{}
+		partner := httptest.NewServer(httpmw.Middleware(partnerLog)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"status":"00"}`))
+		})))
+		defer partner.Close()
+
+		log := &recordLogger{}
+		client := &http.Client{Transport: httpmw.Transport(log, nil)}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, partner.URL+"/v1/pay?token=secret", strings.NewReader(`{"amount":1000}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+
+		// TDR is not written until body is closed
+		assert.Empty(t, log.tdrs)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.EqualValues(t, `{"status":"00"}`, string(body))
+
+		// original request must not be modified
+		assert.Empty(t, req.Header.Get(httpmw.HeaderChainID))
+
+		tdr := log.last()
+		assert.EqualValues(t, "caller", tdr.AppName)
+		assert.EqualValues(t, "thread-1", tdr.ThreadID)
+		assert.EqualValues(t, "chain-1", tdr.ChainID)
+		assert.EqualValues(t, http.MethodPost, tdr.Method)
+		assert.EqualValues(t, partner.URL+"/v1/pay", tdr.Path)
+		assert.EqualValues(t, logger.TdrOutbound, tdr.Direction)
+		assert.EqualValues(t, "127.0.0.1", tdr.IP)
+		assert.NotZero(t, tdr.Port)
+		assert.EqualValues(t, "202", tdr.ResponseCode)
+		assert.EqualValues(t, `{"amount":1000}`, tdr.Request)
+		assert.EqualValues(t, `{"status":"00"}`, tdr.Response)
+
+		partnerTdr := partnerLog.last()
+		assert.EqualValues(t, logger.TdrInbound, partnerTdr.Direction)
+		assert.EqualValues(t, "thread-1", partnerTdr.ThreadID)
+		assert.EqualValues(t, "journey-1", partnerTdr.JourneyID)
+		assert.EqualValues(t, "chain-1", partnerTdr.ChainID)
+		assert.EqualValues(t, `{"amount":1000}`, partnerTdr.Request)
+	})
+
+	t.Run("request body written after round trip", func(t *testing.T) {
+		log := &recordLogger{}
+		client := &http.Client{Transport: httpmw.Transport(log, lateBodyRoundTripper{})}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://partner/v1/pay", strings.NewReader(`{"amount":1000}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		assert.EqualValues(t, `{"amount":1000}`, log.last().Request)
+	})
+
+	t.Run("propagate traceparent", func(t *testing.T) {
+		rt := &headerRoundTripper{}
+		client := &http.Client{Transport: httpmw.Transport(&recordLogger{}, rt)}
+
+		traceCtx := logger.InjectCtx(context.Background(), logger.Context{
+			TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:     "00f067aa0ba902b7",
+			TraceFlags: "01",
+		})
+
+		req, err := http.NewRequestWithContext(traceCtx, http.MethodGet, "http://partner/v1/inquiry", nil)
+		require.NoError(t, err)
+		_, err = client.Do(req)
+		require.NoError(t, err)
+		assert.EqualValues(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", rt.header.Get(httpmw.HeaderTraceparent))
+
+		// no trace in context, so nothing is propagated
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, "http://partner/v1/inquiry", nil)
+		require.NoError(t, err)
+		_, err = client.Do(req)
+		require.NoError(t, err)
+		assert.Empty(t, rt.header.Get(httpmw.HeaderTraceparent))
+	})
+
+	t.Run("log TDR on transport error", func(t *testing.T) {
+		log := &recordLogger{}
+		client := &http.Client{Transport: httpmw.Transport(log, errRoundTripper{})}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://partner.local/v1/inquiry", nil)
+		require.NoError(t, err)
+
+		_, err = client.Do(req)
+		assert.Error(t, err)
+
+		tdr := log.last()
+		assert.EqualValues(t, "partner.local", tdr.IP)
+		assert.EqualValues(t, "connection refused", tdr.Error)
+		assert.Empty(t, tdr.ResponseCode)
+	})
+}
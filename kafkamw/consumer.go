@@ -0,0 +1,225 @@
+package kafkamw
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/armiariyan/logger"
+)
+
+type consumerGroupHandler struct {
+	sarama.ConsumerGroupHandler
+	*instrument
+}
+
+// WrapConsumerGroupHandler wrap handler to write one TDR log for each consumed message.
+//
+// Message is considered done when the handler call session.MarkMessage for it, or when handler
+// take the next message from claim, or when ConsumeClaim returns, whichever come first.
+// Use ExtractCtx inside handler to get logger.Context propagated by producer.
+func WrapConsumerGroupHandler(log logger.Logger, handler sarama.ConsumerGroupHandler, opts ...Option) sarama.ConsumerGroupHandler {
+	return &consumerGroupHandler{
+		ConsumerGroupHandler: handler,
+		instrument:           newInstrument(log, opts...),
+	}
+}
+
+func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	tracker := &claimTracker{instrument: h.instrument, ctx: session.Context()}
+	messages := make(chan *sarama.ConsumerMessage)
+	done := make(chan struct{})
+
+	forwarded := make(chan struct{})
+
+	go func() {
+		defer close(forwarded)
+		defer close(messages)
+
+		for {
+			var msg *sarama.ConsumerMessage
+			var ok bool
+
+			select {
+			case msg, ok = <-claim.Messages():
+				if !ok {
+					return
+				}
+			case <-done:
+				return
+			}
+
+			// track before handing over, since handler may mark the message right after receiving it
+			tracker.offer(msg)
+
+			select {
+			case messages <- msg:
+				tracker.delivered(msg)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := h.ConsumerGroupHandler.ConsumeClaim(
+		&consumerGroupSession{ConsumerGroupSession: session, tracker: tracker},
+		&consumerGroupClaim{ConsumerGroupClaim: claim, messages: messages},
+	)
+
+	// wait last delivered message is tracked
+	close(done)
+	<-forwarded
+
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+
+	tracker.finishAll(errMsg)
+	return err
+}
+
+type consumerGroupSession struct {
+	sarama.ConsumerGroupSession
+	tracker *claimTracker
+}
+
+func (s *consumerGroupSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.ConsumerGroupSession.MarkMessage(msg, metadata)
+	s.tracker.finish(msg)
+}
+
+type consumerGroupClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *consumerGroupClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+// claimTracker hold messages being processed by handler in a claim.
+type claimTracker struct {
+	*instrument
+	ctx     context.Context
+	mu      sync.Mutex
+	pending []pendingMessage
+}
+
+type pendingMessage struct {
+	msg       *sarama.ConsumerMessage
+	start     time.Time
+	delivered bool
+}
+
+func (t *claimTracker) offer(msg *sarama.ConsumerMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = append(t.pending, pendingMessage{msg: msg, start: t.now()})
+}
+
+// delivered reset start time of msg to the time handler receive it, and finish messages before it
+// since handler consume sequentially, so taking next message means previous one is done.
+func (t *claimTracker) delivered(msg *sarama.ConsumerMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	remaining := t.pending[:0]
+	for _, p := range t.pending {
+		switch {
+		case p.msg == msg:
+			p.start = t.now()
+			p.delivered = true
+		case samePartition(p.msg, msg) && p.msg.Offset < msg.Offset:
+			t.write(p, "")
+			continue
+		}
+
+		remaining = append(remaining, p)
+	}
+
+	t.pending = remaining
+}
+
+// finish write TDR of msg and all messages before it in the same partition of the same topic
+func (t *claimTracker) finish(msg *sarama.ConsumerMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	remaining := t.pending[:0]
+	for _, p := range t.pending {
+		if samePartition(p.msg, msg) && p.msg.Offset <= msg.Offset {
+			t.write(p, "")
+			continue
+		}
+
+		remaining = append(remaining, p)
+	}
+
+	t.pending = remaining
+}
+
+// samePartition returns true when both messages are from the same partition of the same topic,
+// since a claim of consumer group may cover partitions of several topics
+func samePartition(a, b *sarama.ConsumerMessage) bool {
+	return a.Topic == b.Topic && a.Partition == b.Partition
+}
+
+// finishAll write TDR of all messages received by handler, message not yet handed over is dropped
+func (t *claimTracker) finishAll(errMsg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, p := range t.pending {
+		if p.delivered {
+			t.write(p, errMsg)
+		}
+	}
+
+	t.pending = nil
+}
+
+func (t *claimTracker) write(p pendingMessage, errMsg string) {
+	ctx := t.consumerContext(t.ctx, p.msg)
+	ctxVal := logger.ExtractCtx(ctx)
+
+	headers := make(map[string]string, len(p.msg.Headers))
+	for _, h := range p.msg.Headers {
+		if h != nil {
+			headers[string(h.Key)] = string(h.Value)
+		}
+	}
+
+	t.log.TDR(ctx, logger.LogTdrModel{
+		AppName:      ctxVal.ServiceName,
+		AppVersion:   ctxVal.ServiceVersion,
+		ThreadID:     ctxVal.ThreadID,
+		JourneyID:    ctxVal.JourneyID,
+		ChainID:      ctxVal.ChainID,
+		Path:         p.msg.Topic,
+		Method:       ctxVal.ReqMethod,
+		RespTime:     t.now().Sub(p.start).Milliseconds(),
+		ResponseCode: responseCode(errMsg),
+		Header:       headers,
+		Request:      t.payload(p.msg.Value),
+		Error:        errMsg,
+		Direction:    logger.TdrInbound,
+		AdditionalData: map[string]interface{}{
+			"topic":     p.msg.Topic,
+			"partition": p.msg.Partition,
+			"offset":    p.msg.Offset,
+			"key":       string(p.msg.Key),
+			"timestamp": p.msg.Timestamp,
+		},
+	})
+}
+
+func responseCode(errMsg string) string {
+	if errMsg != "" {
+		return "ERROR"
+	}
+
+	return "OK"
+}
@@ -0,0 +1,177 @@
+package kafkamw_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armiariyan/logger"
+	"github.com/armiariyan/logger/kafkamw"
+)
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	marked []int64
+}
+
+func (s *fakeSession) Context() context.Context { return context.Background() }
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+// handler mark every message except offset 2 and return error on offset 3
+type handler struct {
+	ctxVal []logger.Context
+}
+
+func (h *handler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (h *handler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (h *handler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		h.ctxVal = append(h.ctxVal, logger.ExtractCtx(kafkamw.ExtractCtx(session.Context(), msg)))
+
+		switch msg.Offset {
+		case 2:
+			continue
+		case 3:
+			return errors.New("invalid message")
+		}
+
+		session.MarkMessage(msg, "")
+	}
+
+	return nil
+}
+
+// funcHandler is ConsumerGroupHandler using the function as ConsumeClaim
+type funcHandler func(sarama.ConsumerGroupSession, sarama.ConsumerGroupClaim) error
+
+func (funcHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (funcHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (h funcHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	return h(session, claim)
+}
+
+func newClaim(messages ...*sarama.ConsumerMessage) *fakeClaim {
+	claim := &fakeClaim{messages: make(chan *sarama.ConsumerMessage, len(messages))}
+	for _, msg := range messages {
+		claim.messages <- msg
+	}
+
+	close(claim.messages)
+	return claim
+}
+
+func TestWrapConsumerGroupHandler(t *testing.T) {
+	t.Run("extract context and log TDR per message", func(t *testing.T) {
+		log := &recordLogger{}
+		h := &handler{}
+		wrapped := kafkamw.WrapConsumerGroupHandler(log, h, kafkamw.WithContext(logger.Context{ServiceName: "consumer"}))
+
+		session := &fakeSession{}
+		claim := newClaim(
+			&sarama.ConsumerMessage{
+				Topic:     "payment",
+				Partition: 1,
+				Offset:    0,
+				Key:       []byte("order-1"),
+				Value:     []byte(`{"amount":1000}`),
+				Headers: []*sarama.RecordHeader{
+					{Key: []byte(kafkamw.HeaderThreadID), Value: []byte("thread-1")},
+					{Key: []byte(kafkamw.HeaderChainID), Value: []byte("chain-1")},
+				},
+			},
+			&sarama.ConsumerMessage{Topic: "payment", Partition: 1, Offset: 1, Value: []byte("no header")},
+		)
+
+		require.NoError(t, wrapped.ConsumeClaim(session, claim))
+		assert.EqualValues(t, []int64{0, 1}, session.marked)
+
+		// handler get same context as TDR
+		require.Len(t, h.ctxVal, 2)
+		assert.EqualValues(t, "thread-1", h.ctxVal[0].ThreadID)
+		assert.EqualValues(t, "chain-1", h.ctxVal[0].ChainID)
+		assert.EqualValues(t, "payment-1-1", h.ctxVal[1].ThreadID)
+
+		tdrs := log.all()
+		require.Len(t, tdrs, 2)
+		assert.EqualValues(t, "consumer", tdrs[0].AppName)
+		assert.EqualValues(t, "thread-1", tdrs[0].ThreadID)
+		assert.EqualValues(t, "chain-1", tdrs[0].ChainID)
+		assert.EqualValues(t, "payment", tdrs[0].Path)
+		assert.EqualValues(t, "CONSUME", tdrs[0].Method)
+		assert.EqualValues(t, "OK", tdrs[0].ResponseCode)
+		assert.EqualValues(t, `{"amount":1000}`, tdrs[0].Request)
+		assert.EqualValues(t, map[string]string{kafkamw.HeaderThreadID: "thread-1", kafkamw.HeaderChainID: "chain-1"}, tdrs[0].Header)
+		assert.EqualValues(t, int32(1), tdrs[0].AdditionalData.(map[string]interface{})["partition"])
+		assert.EqualValues(t, int64(0), tdrs[0].AdditionalData.(map[string]interface{})["offset"])
+		assert.EqualValues(t, "order-1", tdrs[0].AdditionalData.(map[string]interface{})["key"])
+		assert.EqualValues(t, "payment-1-1", tdrs[1].ThreadID)
+	})
+
+	t.Run("log unmarked message and handler error", func(t *testing.T) {
+		log := &recordLogger{}
+		wrapped := kafkamw.WrapConsumerGroupHandler(log, &handler{})
+
+		claim := newClaim(
+			&sarama.ConsumerMessage{Topic: "payment", Offset: 2},
+			&sarama.ConsumerMessage{Topic: "payment", Offset: 3},
+			&sarama.ConsumerMessage{Topic: "payment", Offset: 4},
+		)
+
+		err := wrapped.ConsumeClaim(&fakeSession{}, claim)
+		assert.Error(t, err)
+
+		// offset 4 is never received by handler
+		tdrs := log.all()
+		require.Len(t, tdrs, 2)
+		assert.EqualValues(t, "OK", tdrs[0].ResponseCode)
+		assert.EqualValues(t, int64(2), tdrs[0].AdditionalData.(map[string]interface{})["offset"])
+		assert.EqualValues(t, "ERROR", tdrs[1].ResponseCode)
+		assert.EqualValues(t, "invalid message", tdrs[1].Error)
+		assert.EqualValues(t, int64(3), tdrs[1].AdditionalData.(map[string]interface{})["offset"])
+	})
+
+	t.Run("topics sharing partition number", func(t *testing.T) {
+		log := &recordLogger{}
+		var afterOrder []logger.LogTdrModel
+		wrapped := kafkamw.WrapConsumerGroupHandler(log, funcHandler(func(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+			order := <-claim.Messages()
+			payment := <-claim.Messages()
+
+			// payment has lower offset in the same partition number, but it is another topic
+			session.MarkMessage(order, "")
+			afterOrder = log.all()
+			session.MarkMessage(payment, "")
+			return nil
+		}))
+
+		claim := newClaim(
+			&sarama.ConsumerMessage{Topic: "order", Partition: 0, Offset: 9},
+			&sarama.ConsumerMessage{Topic: "payment", Partition: 0, Offset: 3},
+		)
+
+		require.NoError(t, wrapped.ConsumeClaim(&fakeSession{}, claim))
+
+		require.Len(t, afterOrder, 1)
+		assert.EqualValues(t, "order", afterOrder[0].Path)
+
+		tdrs := log.all()
+		require.Len(t, tdrs, 2)
+		assert.EqualValues(t, "payment", tdrs[1].Path)
+	})
+}
@@ -0,0 +1,167 @@
+package kafkamw
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/armiariyan/logger"
+)
+
+// record header key used to propagate logger.Context between producer and consumer
+const (
+	HeaderThreadID  = "correlation-id"
+	HeaderJourneyID = "journey-id"
+	HeaderChainID   = "chain-id"
+)
+
+// defaultMaxPayloadSize is the maximum bytes of message value written in TDR log
+const defaultMaxPayloadSize = 64 * 1024
+
+type instrument struct {
+	log            logger.Logger
+	ctxVal         logger.Context
+	maxPayloadSize int
+	now            func() time.Time
+}
+
+type Option func(*instrument)
+
+// WithContext set base logging context, such as service name and version,
+// which copied into every consumed message.
+func WithContext(ctxVal logger.Context) Option {
+	return func(i *instrument) {
+		i.ctxVal = ctxVal
+	}
+}
+
+// WithMaxPayloadSize set maximum bytes of message value written in TDR log.
+// Zero or negative value disable payload logging.
+func WithMaxPayloadSize(size int) Option {
+	return func(i *instrument) {
+		i.maxPayloadSize = size
+	}
+}
+
+func newInstrument(log logger.Logger, opts ...Option) *instrument {
+	i := &instrument{
+		log:            log,
+		maxPayloadSize: defaultMaxPayloadSize,
+		now:            time.Now,
+	}
+
+	for _, o := range opts {
+		o(i)
+	}
+
+	if i.log == nil {
+		i.log = logger.NewNoopLogger()
+	}
+
+	return i
+}
+
+// InjectHeaders append thread, journey and chain id of logger.Context in ctx into message header,
+// header already exist in message is not replaced.
+func InjectHeaders(ctx context.Context, msg *sarama.ProducerMessage) {
+	if msg == nil {
+		return
+	}
+
+	ctxVal := logger.ExtractCtx(ctx)
+	for _, h := range []sarama.RecordHeader{
+		{Key: []byte(HeaderThreadID), Value: []byte(ctxVal.ThreadID)},
+		{Key: []byte(HeaderJourneyID), Value: []byte(ctxVal.JourneyID)},
+		{Key: []byte(HeaderChainID), Value: []byte(ctxVal.ChainID)},
+	} {
+		if len(h.Value) <= 0 || hasHeader(msg.Headers, string(h.Key)) {
+			continue
+		}
+
+		msg.Headers = append(msg.Headers, h)
+	}
+}
+
+// ExtractCtx returns ctx with logger.Context in ctx, such as service name, combined with
+// thread, journey and chain id from consumed message header.
+// Thread id is generated from topic, partition and offset when producer doesn't send it.
+func ExtractCtx(ctx context.Context, msg *sarama.ConsumerMessage) context.Context {
+	return newInstrument(nil, WithContext(logger.ExtractCtx(ctx))).consumerContext(ctx, msg)
+}
+
+func (i *instrument) consumerContext(ctx context.Context, msg *sarama.ConsumerMessage) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ctxVal := i.ctxVal
+	ctxVal.ThreadID, ctxVal.JourneyID, ctxVal.ChainID = "", "", ""
+	ctxVal.ReqMethod = "CONSUME"
+
+	if msg != nil {
+		ctxVal.ReqURI = msg.Topic
+		for _, h := range msg.Headers {
+			if h == nil {
+				continue
+			}
+
+			switch string(h.Key) {
+			case HeaderThreadID:
+				ctxVal.ThreadID = string(h.Value)
+			case HeaderJourneyID:
+				ctxVal.JourneyID = string(h.Value)
+			case HeaderChainID:
+				ctxVal.ChainID = string(h.Value)
+			}
+		}
+	}
+
+	// use message coordinate so handler and TDR get the same thread id
+	if ctxVal.ThreadID == "" && msg != nil {
+		ctxVal.ThreadID = fmt.Sprintf("%s-%d-%d", msg.Topic, msg.Partition, msg.Offset)
+	}
+
+	if ctxVal.ThreadID == "" {
+		ctxVal.ThreadID = fmt.Sprint(i.now().UnixNano())
+	}
+
+	return logger.InjectCtx(ctx, ctxVal)
+}
+
+// payload returns message value as string, truncated to maxPayloadSize and suffixed with "..."
+func (i *instrument) payload(value []byte) string {
+	if i.maxPayloadSize <= 0 || len(value) <= 0 {
+		return ""
+	}
+
+	if len(value) > i.maxPayloadSize {
+		return string(value[:i.maxPayloadSize]) + "..."
+	}
+
+	return string(value)
+}
+
+func hasHeader(headers []sarama.RecordHeader, key string) bool {
+	for _, h := range headers {
+		if string(h.Key) == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func encode(encoder sarama.Encoder) []byte {
+	if encoder == nil {
+		return nil
+	}
+
+	b, err := encoder.Encode()
+	if err != nil {
+		return nil
+	}
+
+	return b
+}
@@ -0,0 +1,273 @@
+package kafkamw
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/armiariyan/logger"
+)
+
+// SyncProducer wrap sarama.SyncProducer to propagate logger.Context in message header
+// and write one TDR log for each produced message.
+type SyncProducer struct {
+	sarama.SyncProducer
+	*instrument
+}
+
+var _ sarama.SyncProducer = (*SyncProducer)(nil)
+
+func WrapSyncProducer(log logger.Logger, producer sarama.SyncProducer, opts ...Option) *SyncProducer {
+	return &SyncProducer{
+		SyncProducer: producer,
+		instrument:   newInstrument(log, opts...),
+	}
+}
+
+// SendMessage same as SendMessageContext without logger.Context.
+func (p *SyncProducer) SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
+	return p.SendMessageContext(context.Background(), msg)
+}
+
+// SendMessageContext inject logger.Context in ctx into message header and produce it.
+func (p *SyncProducer) SendMessageContext(ctx context.Context, msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
+	start := p.now()
+	InjectHeaders(ctx, msg)
+
+	partition, offset, err = p.SyncProducer.SendMessage(msg)
+	msg.Partition, msg.Offset = partition, offset
+
+	p.write(ctx, msg, err, start)
+	return
+}
+
+// SendMessages same as SendMessagesContext without logger.Context.
+func (p *SyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	return p.SendMessagesContext(context.Background(), msgs)
+}
+
+// SendMessagesContext inject logger.Context in ctx into all messages header and produce them.
+func (p *SyncProducer) SendMessagesContext(ctx context.Context, msgs []*sarama.ProducerMessage) error {
+	start := p.now()
+	for _, msg := range msgs {
+		InjectHeaders(ctx, msg)
+	}
+
+	err := p.SyncProducer.SendMessages(msgs)
+
+	// match error of each message, so only failed message written with error
+	failed := make(map[*sarama.ProducerMessage]error)
+	if errs, ok := err.(sarama.ProducerErrors); ok {
+		for _, e := range errs {
+			failed[e.Msg] = e.Err
+		}
+	} else if err != nil {
+		for _, msg := range msgs {
+			failed[msg] = err
+		}
+	}
+
+	for _, msg := range msgs {
+		p.write(ctx, msg, failed[msg], start)
+	}
+
+	return err
+}
+
+// AsyncProducer wrap sarama.AsyncProducer to propagate logger.Context in message header
+// and write one TDR log for each produced message when it is returned in Successes or Errors channel.
+// Enable Producer.Return.Successes in sarama config to log success message.
+type AsyncProducer struct {
+	sarama.AsyncProducer
+	*instrument
+
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+	closeOnce sync.Once
+	inputDone chan struct{}
+	forwards  sync.WaitGroup
+}
+
+var _ sarama.AsyncProducer = (*AsyncProducer)(nil)
+
+// asyncMetadata replace message Metadata while message is in flight, original Metadata is restored
+// before message is returned.
+type asyncMetadata struct {
+	ctx      context.Context
+	start    time.Time
+	metadata interface{}
+}
+
+func WrapAsyncProducer(log logger.Logger, producer sarama.AsyncProducer, opts ...Option) *AsyncProducer {
+	p := &AsyncProducer{
+		AsyncProducer: producer,
+		instrument:    newInstrument(log, opts...),
+		input:         make(chan *sarama.ProducerMessage),
+		successes:     make(chan *sarama.ProducerMessage),
+		errors:        make(chan *sarama.ProducerError),
+		inputDone:     make(chan struct{}),
+	}
+
+	p.forwards.Add(2)
+	go p.forwardInput()
+	go p.forwardSuccesses()
+	go p.forwardErrors()
+	return p
+}
+
+// Input same as SendContext without logger.Context.
+func (p *AsyncProducer) Input() chan<- *sarama.ProducerMessage {
+	return p.input
+}
+
+// SendContext inject logger.Context in ctx into message header and put it into Input channel.
+func (p *AsyncProducer) SendContext(ctx context.Context, msg *sarama.ProducerMessage) {
+	InjectHeaders(ctx, msg)
+	msg.Metadata = &asyncMetadata{ctx: ctx, start: p.now(), metadata: msg.Metadata}
+	p.input <- msg
+}
+
+func (p *AsyncProducer) Successes() <-chan *sarama.ProducerMessage {
+	return p.successes
+}
+
+func (p *AsyncProducer) Errors() <-chan *sarama.ProducerError {
+	return p.errors
+}
+
+// AsyncClose stop accepting message and close the underlying producer once all input is forwarded.
+func (p *AsyncProducer) AsyncClose() {
+	p.closeOnce.Do(func() {
+		close(p.input)
+	})
+
+	go func() {
+		<-p.inputDone
+		p.AsyncProducer.AsyncClose()
+	}()
+}
+
+// Close stop accepting message and close the underlying producer, it waits all buffered messages to be flushed
+// and written as TDR. Remaining errors are returned as sarama.ProducerErrors, the same as sarama.
+func (p *AsyncProducer) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.input)
+	})
+
+	<-p.inputDone
+
+	// underlying Close drain its channels, so forwarder would miss them, close it asynchronously instead
+	p.AsyncProducer.AsyncClose()
+
+	go func() {
+		for range p.successes {
+		}
+	}()
+
+	var errs sarama.ProducerErrors
+	for e := range p.errors {
+		errs = append(errs, e)
+	}
+
+	p.forwards.Wait()
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *AsyncProducer) forwardInput() {
+	defer close(p.inputDone)
+
+	for msg := range p.input {
+		if _, ok := msg.Metadata.(*asyncMetadata); !ok {
+			msg.Metadata = &asyncMetadata{ctx: context.Background(), start: p.now(), metadata: msg.Metadata}
+		}
+
+		p.AsyncProducer.Input() <- msg
+	}
+}
+
+func (p *AsyncProducer) forwardSuccesses() {
+	defer p.forwards.Done()
+	defer close(p.successes)
+
+	for msg := range p.AsyncProducer.Successes() {
+		ctx, start := p.restore(msg)
+		p.write(ctx, msg, nil, start)
+		p.successes <- msg
+	}
+}
+
+func (p *AsyncProducer) forwardErrors() {
+	defer p.forwards.Done()
+	defer close(p.errors)
+
+	for e := range p.AsyncProducer.Errors() {
+		ctx, start := p.restore(e.Msg)
+		p.write(ctx, e.Msg, e.Err, start)
+		p.errors <- e
+	}
+}
+
+func (p *AsyncProducer) restore(msg *sarama.ProducerMessage) (context.Context, time.Time) {
+	if msg == nil {
+		return context.Background(), p.now()
+	}
+
+	meta, ok := msg.Metadata.(*asyncMetadata)
+	if !ok {
+		return context.Background(), p.now()
+	}
+
+	msg.Metadata = meta.metadata
+	return meta.ctx, meta.start
+}
+
+func (i *instrument) write(ctx context.Context, msg *sarama.ProducerMessage, err error, start time.Time) {
+	if msg == nil {
+		return
+	}
+
+	ctxVal := logger.ExtractCtx(ctx)
+	if ctxVal.ServiceName == "" {
+		ctxVal.ServiceName = i.ctxVal.ServiceName
+		ctxVal.ServiceVersion = i.ctxVal.ServiceVersion
+	}
+
+	headers := make(map[string]string, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers[string(h.Key)] = string(h.Value)
+	}
+
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+
+	i.log.TDR(ctx, logger.LogTdrModel{
+		AppName:      ctxVal.ServiceName,
+		AppVersion:   ctxVal.ServiceVersion,
+		ThreadID:     ctxVal.ThreadID,
+		JourneyID:    ctxVal.JourneyID,
+		ChainID:      ctxVal.ChainID,
+		Path:         msg.Topic,
+		Method:       "PRODUCE",
+		RespTime:     i.now().Sub(start).Milliseconds(),
+		ResponseCode: responseCode(errMsg),
+		Header:       headers,
+		Request:      i.payload(encode(msg.Value)),
+		Error:        errMsg,
+		Direction:    logger.TdrOutbound,
+		AdditionalData: map[string]interface{}{
+			"topic":     msg.Topic,
+			"partition": msg.Partition,
+			"offset":    msg.Offset,
+			"key":       string(encode(msg.Key)),
+		},
+	})
+}
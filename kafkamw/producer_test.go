@@ -0,0 +1,183 @@
+package kafkamw_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/armiariyan/logger"
+	"github.com/armiariyan/logger/kafkamw"
+)
+
+// recordLogger hold TDR written by wrapper
+type recordLogger struct {
+	logger.NoopContextLogger
+	mu   sync.Mutex
+	tdrs []logger.LogTdrModel
+}
+
+func (r *recordLogger) TDR(_ context.Context, tdr logger.LogTdrModel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tdrs = append(r.tdrs, tdr)
+}
+
+func (r *recordLogger) all() []logger.LogTdrModel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]logger.LogTdrModel(nil), r.tdrs...)
+}
+
+var ctx = logger.InjectCtx(context.Background(), logger.Context{
+	ServiceName: "producer",
+	ThreadID:    "thread-1",
+	ChainID:     "chain-1",
+})
+
+func header(headers []sarama.RecordHeader, key string) string {
+	for _, h := range headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+func TestSyncProducer(t *testing.T) {
+	t.Run("inject header and log TDR", func(t *testing.T) {
+		mock := mocks.NewSyncProducer(t, nil)
+		mock.ExpectSendMessageAndSucceed()
+
+		log := &recordLogger{}
+		producer := kafkamw.WrapSyncProducer(log, mock)
+		defer func() {
+			assert.NoError(t, producer.Close())
+		}()
+
+		msg := &sarama.ProducerMessage{
+			Topic: "payment",
+			Key:   sarama.StringEncoder("order-1"),
+			Value: sarama.StringEncoder(`{"amount":1000}`),
+		}
+
+		_, offset, err := producer.SendMessageContext(ctx, msg)
+		require.NoError(t, err)
+
+		assert.EqualValues(t, "thread-1", header(msg.Headers, kafkamw.HeaderThreadID))
+		assert.EqualValues(t, "chain-1", header(msg.Headers, kafkamw.HeaderChainID))
+		assert.Empty(t, header(msg.Headers, kafkamw.HeaderJourneyID))
+
+		tdrs := log.all()
+		require.Len(t, tdrs, 1)
+		assert.EqualValues(t, "producer", tdrs[0].AppName)
+		assert.EqualValues(t, "thread-1", tdrs[0].ThreadID)
+		assert.EqualValues(t, "payment", tdrs[0].Path)
+		assert.EqualValues(t, "PRODUCE", tdrs[0].Method)
+		assert.EqualValues(t, "OK", tdrs[0].ResponseCode)
+		assert.EqualValues(t, `{"amount":1000}`, tdrs[0].Request)
+		assert.EqualValues(t, offset, tdrs[0].AdditionalData.(map[string]interface{})["offset"])
+		assert.EqualValues(t, "order-1", tdrs[0].AdditionalData.(map[string]interface{})["key"])
+	})
+
+	t.Run("log error", func(t *testing.T) {
+		mock := mocks.NewSyncProducer(t, nil)
+		mock.ExpectSendMessageAndFail(sarama.ErrOutOfBrokers)
+		mock.ExpectSendMessageAndSucceed()
+		mock.ExpectSendMessageAndSucceed()
+
+		log := &recordLogger{}
+		producer := kafkamw.WrapSyncProducer(log, mock, kafkamw.WithMaxPayloadSize(3))
+		defer func() {
+			assert.NoError(t, producer.Close())
+		}()
+
+		_, _, err := producer.SendMessage(&sarama.ProducerMessage{Topic: "payment", Value: sarama.StringEncoder("payload")})
+		assert.Error(t, err)
+
+		err = producer.SendMessagesContext(ctx, []*sarama.ProducerMessage{
+			{Topic: "payment", Value: sarama.StringEncoder("one")},
+			{Topic: "payment", Value: sarama.StringEncoder("two")},
+		})
+		assert.NoError(t, err)
+
+		tdrs := log.all()
+		require.Len(t, tdrs, 3)
+		assert.EqualValues(t, "ERROR", tdrs[0].ResponseCode)
+		assert.EqualValues(t, sarama.ErrOutOfBrokers.Error(), tdrs[0].Error)
+		assert.EqualValues(t, "pay...", tdrs[0].Request)
+		assert.EqualValues(t, "OK", tdrs[1].ResponseCode)
+		assert.EqualValues(t, "thread-1", tdrs[2].ThreadID)
+	})
+}
+
+func TestAsyncProducer(t *testing.T) {
+	config := sarama.NewConfig()
+	config.Producer.Return.Successes = true
+
+	mock := mocks.NewAsyncProducer(t, config)
+	mock.ExpectInputAndSucceed()
+	mock.ExpectInputAndFail(errors.New("broker down"))
+
+	log := &recordLogger{}
+	producer := kafkamw.WrapAsyncProducer(log, mock)
+
+	producer.SendContext(ctx, &sarama.ProducerMessage{Topic: "payment", Value: sarama.StringEncoder("one"), Metadata: "my-metadata"})
+	producer.Input() <- &sarama.ProducerMessage{Topic: "payment", Value: sarama.StringEncoder("two")}
+
+	successes := []*sarama.ProducerMessage{<-producer.Successes()}
+	failures := []*sarama.ProducerError{<-producer.Errors()}
+	require.NoError(t, producer.Close())
+
+	require.Len(t, successes, 1)
+	require.Len(t, failures, 1)
+
+	// original metadata is restored
+	assert.EqualValues(t, "my-metadata", successes[0].Metadata)
+	assert.Nil(t, failures[0].Msg.Metadata)
+	assert.EqualValues(t, "thread-1", header(successes[0].Headers, kafkamw.HeaderThreadID))
+
+	tdrs := log.all()
+	require.Len(t, tdrs, 2)
+	for _, tdr := range tdrs {
+		switch tdr.Request {
+		case "one":
+			assert.EqualValues(t, "OK", tdr.ResponseCode)
+			assert.EqualValues(t, "thread-1", tdr.ThreadID)
+		case "two":
+			assert.EqualValues(t, "ERROR", tdr.ResponseCode)
+			assert.EqualValues(t, "broker down", tdr.Error)
+		default:
+			t.Errorf("unexpected TDR %v", tdr)
+		}
+	}
+}
+
+func TestAsyncProducerClose(t *testing.T) {
+	config := sarama.NewConfig()
+	config.Producer.Return.Successes = true
+
+	mock := mocks.NewAsyncProducer(t, config)
+	mock.ExpectInputAndSucceed()
+	mock.ExpectInputAndFail(errors.New("broker down"))
+
+	log := &recordLogger{}
+	producer := kafkamw.WrapAsyncProducer(log, mock)
+
+	// nobody read Successes and Errors, Close drain them like sarama and still write every TDR
+	producer.SendContext(ctx, &sarama.ProducerMessage{Topic: "payment", Value: sarama.StringEncoder("one")})
+	producer.SendContext(ctx, &sarama.ProducerMessage{Topic: "payment", Value: sarama.StringEncoder("two")})
+
+	err := producer.Close()
+	var errs sarama.ProducerErrors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0].Err, "broker down")
+	assert.Len(t, log.all(), 2)
+}
@@ -32,6 +32,9 @@ type Context struct {
 	JourneyID      string `json:"_app_journey_id"`
 	ChainID        string `json:"_app_chain_id"`
 	Tag            string `json:"_app_tag"`
+	TraceID        string `json:"_app_trace_id,omitempty"` // W3C trace id in hex, set by httpmw and grpcmw from traceparent header
+	SpanID         string `json:"_app_span_id,omitempty"`
+	TraceFlags     string `json:"-"` // W3C trace flags in hex, only propagated in outgoing traceparent
 
 	ReqMethod string `json:"_app_method"`
 	ReqURI    string `json:"_app_uri"`
@@ -3,23 +3,91 @@ package logger
 import (
 	"context"
 	"fmt"
+
+	"github.com/segmentio/encoding/json"
 )
 
 type Options struct {
 	Name       string        `json:"name"`
 	SysOptions OptionsLogger `json:"sysOptions"`
 	TdrOptions OptionsLogger `json:"tdrOptions"`
+
+	// Streams is output of named stream such as audit, stream which is not configured is written into SysOptions
+	// regardless of its level
+	Streams map[string]OptionsLogger `json:"streams"`
 }
 
 type OptionsLogger struct {
-	Type         string       `json:"type"`
-	OptionsFile  OptionsFile  `json:"optionsFile"`
-	OptionsQueue OptionsQueue `json:"optionsQueue"`
+	Type            string          `json:"type"`
+	OptionsFile     OptionsFile     `json:"optionsFile"`
+	OptionsQueue    OptionsQueue    `json:"optionsQueue"`
+	OptionsSyslog   OptionsSyslog   `json:"optionsSyslog"`
+	OptionsLoki     OptionsLoki     `json:"optionsLoki"`
+	OptionsOTLP     OptionsOTLP     `json:"optionsOTLP"`
+	OptionsHTTPBulk OptionsHTTPBulk `json:"optionsHTTPBulk"`
+
+	// OptionsSink is config of sink type added using RegisterSink, it is passed into the sink factory as is
+	OptionsSink json.RawMessage `json:"optionsSink"`
+
+	// Outputs is used instead of Type when it is set, each output has its own type, level and mask setting,
+	// such as [{"type": "file", ...}, {"type": "queue", ...}] to write into file and kafka at once
+	Outputs []OptionsLogger `json:"outputs"`
+}
+
+// OptionsOutput is mask, header and scrub option shared by every output, it is embedded
+// in each output options next to its Mask and Level, so its fields are written at the same level in JSON config.
+type OptionsOutput struct {
+	MaskRules       []MaskRule `json:"maskRules"`
+	MaskSecret      string     `json:"maskSecret"`
+	MaskTokenize    []string   `json:"maskTokenize"`
+	AllowlistMode   bool       `json:"allowlistMode"`
+	HeaderDenylist  []string   `json:"headerDenylist"`
+	HeaderAllowlist []string   `json:"headerAllowlist"`
+	Scrub           bool       `json:"scrub"`
+}
+
+// options returns logger options of o with mask and level of the output, the output option is appended by caller
+func (o *OptionsOutput) options(mask bool, level Level) []Option {
+	opt := []Option{WithLevel(level)}
+	if mask {
+		opt = append(opt, MaskEnabled())
+	}
+
+	if len(o.MaskRules) > 0 {
+		opt = append(opt, WithMaskRules(o.MaskRules...))
+	}
+
+	if o.MaskSecret != "" {
+		opt = append(opt, WithMaskSecret([]byte(o.MaskSecret)))
+	}
+
+	if len(o.MaskTokenize) > 0 {
+		opt = append(opt, WithMaskTokenize(o.MaskTokenize...))
+	}
+
+	if o.AllowlistMode {
+		opt = append(opt, WithAllowlistMode())
+	}
+
+	if len(o.HeaderDenylist) > 0 {
+		opt = append(opt, WithHeaderDenylist(o.HeaderDenylist...))
+	}
+
+	if len(o.HeaderAllowlist) > 0 {
+		opt = append(opt, WithHeaderAllowlist(o.HeaderAllowlist...))
+	}
+
+	if o.Scrub {
+		opt = append(opt, WithScrubber())
+	}
+
+	return opt
 }
 
 type combineLogger struct {
-	sysLog Logger
-	tdrLog Logger
+	sysLog  Logger
+	tdrLog  Logger
+	streams map[string]Logger
 }
 
 func (c *combineLogger) Debug(ctx context.Context, message string, fields ...Field) {
@@ -61,34 +129,56 @@ func (c *combineLogger) Close() error {
 		err = fmt.Errorf("error close tdrlog: %w", _err)
 	}
 
+	for name, l := range c.streams {
+		if _err := l.Close(); _err != nil {
+			err = fmt.Errorf("error close %s stream: %w", name, _err)
+		}
+	}
+
 	return err
 }
 
 func SetupLoggerCombine(options Options) Logger {
 	fmt.Println("Try newLogger ...")
 
-	var sysLog Logger
-	switch options.SysOptions.Type {
-	case Queue:
-		sysLog = SetupLoggerQueue(options.Name, &options.SysOptions.OptionsQueue)
-	case File:
-		sysLog = SetupLoggerFile(options.Name, &options.SysOptions.OptionsFile)
-	default:
-		panic("syslog not found")
+	streams := make(map[string]Logger, len(options.Streams))
+	for name, streamOptions := range options.Streams {
+		streamOptions := streamOptions
+		streams[name] = setupOutput(options.Name, &streamOptions)
+	}
+
+	return &combineLogger{
+		sysLog:  setupOutput(options.Name, &options.SysOptions),
+		tdrLog:  setupOutput(options.Name, &options.TdrOptions),
+		streams: streams,
 	}
+}
 
-	var tdrLog Logger
-	switch options.TdrOptions.Type {
+// setupOutput returns Logger of options.Type, or Logger writing into every output when Outputs is set
+func setupOutput(name string, options *OptionsLogger) Logger {
+	if len(options.Outputs) > 0 {
+		loggers := make([]Logger, 0, len(options.Outputs))
+		for i := range options.Outputs {
+			loggers = append(loggers, setupOutput(name, &options.Outputs[i]))
+		}
+
+		return NewMultiLogger(loggers...)
+	}
+
+	switch options.Type {
 	case Queue:
-		tdrLog = SetupLoggerQueue(options.Name, &options.TdrOptions.OptionsQueue)
+		return SetupLoggerQueue(name, &options.OptionsQueue)
 	case File:
-		tdrLog = SetupLoggerFile(options.Name, &options.TdrOptions.OptionsFile)
+		return SetupLoggerFile(name, &options.OptionsFile)
+	case Syslog:
+		return SetupLoggerSyslog(name, &options.OptionsSyslog)
+	case Loki:
+		return SetupLoggerLoki(name, &options.OptionsLoki)
+	case OTLP:
+		return SetupLoggerOTLP(name, &options.OptionsOTLP)
+	case HTTPBulk:
+		return SetupLoggerHTTPBulk(name, &options.OptionsHTTPBulk)
 	default:
-		panic("tdrLog not found")
-	}
-
-	return &combineLogger{
-		sysLog: sysLog,
-		tdrLog: tdrLog,
+		return SetupLoggerSink(name, options.Type, options.OptionsSink)
 	}
 }
@@ -6,6 +6,9 @@ import (
 	"io"
 	"os"
 	"reflect"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/segmentio/encoding/json"
@@ -17,9 +20,20 @@ type defaultLogger struct {
 	// used by options
 	writers     []io.Writer // sys and tdr mus have different channel of writer
 	maskEnabled bool
+	maskRules   []compiledMaskRule
+	headers     *headerRedactor
+	scrubber    *scrubber // nil when disabled
 	noopLogger  bool
 	closer      []io.Closer
 
+	maskOpts       maskOptions
+	allowlistTypes []reflect.Type // written in allowlist report
+	unknownMaskTag UnknownMaskTag
+	warnedMaskTags sync.Map // unknown tag already warned
+
+	tdrValidation bool
+	tdrValidators []TdrValidator
+
 	// initiated by this application newLogger
 	zapLogger *zap.Logger
 	level     Level
@@ -28,17 +42,31 @@ type defaultLogger struct {
 var _ Logger = (*defaultLogger)(nil)
 
 func newLogger(opts ...Option) (Logger, error) {
+	maskRules, err := compileMaskRules(DefaultMaskRules)
+	if err != nil {
+		return nil, fmt.Errorf("default mask rules error: %w", err)
+	}
+
 	defaultLogger := &defaultLogger{
 		writers:     make([]io.Writer, 0),
 		maskEnabled: false,
+		maskRules:   maskRules,
+		headers:     newHeaderRedactor(DefaultRedactedHeaders),
+		maskOpts:    defaultMaskOptions,
 	}
 
+	defaultLogger.maskOpts.unknown = defaultLogger.unknownMask
+
 	for _, o := range opts {
 		if err := o(defaultLogger); err != nil {
 			return nil, err
 		}
 	}
 
+	if t := defaultLogger.maskOpts.tokenizer; t != nil && len(t.secret) <= 0 {
+		return nil, fmt.Errorf("mask tokenize needs mask secret")
+	}
+
 	// set logger here instead in options to make easy and consistent initiation
 	// set multiple writer as already set in options
 	defaultLogger.zapLogger = NewZapLogger(defaultLogger.level, defaultLogger.writers...)
@@ -53,6 +81,11 @@ func newLogger(opts ...Option) (Logger, error) {
 		defaultLogger.zapLogger = zap.NewNop()
 	}
 
+	if defaultLogger.maskOpts.allowlist {
+		defaultLogger.Info(context.Background(), "allowlist mode enabled, only safe field is written",
+			ToField("safeFields", Safe(allowlistReport(defaultLogger.allowlistTypes))))
+	}
+
 	return defaultLogger, nil
 }
 
@@ -81,7 +114,7 @@ func (d *defaultLogger) Debug(ctx context.Context, message string, fields ...Fie
 		zap.String("level", "debug"),
 	}
 
-	zapLogs = append(zapLogs, formatLogs(ctx, message, d.maskEnabled, fields...)...)
+	zapLogs = append(zapLogs, d.formatLogs(ctx, message, d.maskEnabled, fields...)...)
 	d.zapLogger.Debug(separator, zapLogs...)
 }
 
@@ -91,7 +124,7 @@ func (d *defaultLogger) Info(ctx context.Context, message string, fields ...Fiel
 		zap.String("level", "info"),
 	}
 
-	zapLogs = append(zapLogs, formatLogs(ctx, message, d.maskEnabled, fields...)...)
+	zapLogs = append(zapLogs, d.formatLogs(ctx, message, d.maskEnabled, fields...)...)
 	d.zapLogger.Info(separator, zapLogs...)
 }
 
@@ -101,7 +134,7 @@ func (d *defaultLogger) Warn(ctx context.Context, message string, fields ...Fiel
 		zap.String("level", "warn"),
 	}
 
-	zapLogs = append(zapLogs, formatLogs(ctx, message, d.maskEnabled, fields...)...)
+	zapLogs = append(zapLogs, d.formatLogs(ctx, message, d.maskEnabled, fields...)...)
 	d.zapLogger.Warn(separator, zapLogs...)
 }
 
@@ -111,7 +144,7 @@ func (d *defaultLogger) Error(ctx context.Context, message string, fields ...Fie
 		zap.String("level", "error"),
 	}
 
-	zapLogs = append(zapLogs, formatLogs(ctx, message, d.maskEnabled, fields...)...)
+	zapLogs = append(zapLogs, d.formatLogs(ctx, message, d.maskEnabled, fields...)...)
 	d.zapLogger.Error(separator, zapLogs...)
 }
 
@@ -121,7 +154,7 @@ func (d *defaultLogger) Fatal(ctx context.Context, message string, fields ...Fie
 		zap.String("level", "fatal"),
 	}
 
-	zapLogs = append(zapLogs, formatLogs(ctx, message, d.maskEnabled, fields...)...)
+	zapLogs = append(zapLogs, d.formatLogs(ctx, message, d.maskEnabled, fields...)...)
 	d.zapLogger.Fatal(separator, zapLogs...)
 }
 
@@ -131,18 +164,43 @@ func (d *defaultLogger) Panic(ctx context.Context, message string, fields ...Fie
 		zap.String("level", "panic"),
 	}
 
-	zapLogs = append(zapLogs, formatLogs(ctx, message, d.maskEnabled, fields...)...)
+	zapLogs = append(zapLogs, d.formatLogs(ctx, message, d.maskEnabled, fields...)...)
 	d.zapLogger.Panic(separator, zapLogs...)
 }
 
+// writeEntry write SYS log through zap core, so fatal and panic log is written without exit or panic
+func (d *defaultLogger) writeEntry(ctx context.Context, level zapcore.Level, message string, fields ...Field) {
+	ce := d.zapLogger.Core().Check(zapcore.Entry{Level: level, Time: time.Now(), Message: separator}, nil)
+	if ce == nil {
+		return
+	}
+
+	zapLogs := []zap.Field{
+		zap.String("logType", LogTypeSYS),
+		zap.String("level", level.String()),
+	}
+
+	zapLogs = append(zapLogs, d.formatLogs(ctx, message, d.maskEnabled, fields...)...)
+	ce.Write(zapLogs...)
+}
+
 func (d *defaultLogger) TDR(ctx context.Context, tdr LogTdrModel) {
+	tdr = fillTdr(ctx, tdr)
 
 	fields := make([]zap.Field, 0)
 	fields = append(fields, zap.String("logType", LogTypeTDR))
 	fields = append(fields, zap.String("level", "info"))
+	fields = append(fields, zap.String("schemaVersion", TdrSchemaVersion))
+
+	// invalid TDR is still written, so no transaction record is lost
+	if d.tdrValidation {
+		if err := validateTdr(tdr, d.tdrValidators...); err != nil {
+			fields = append(fields, zap.String("schemaError", err.Error()))
+		}
+	}
 
 	// add this first, so global context value still logged
-	fields = append(fields, formatLogs(ctx, separator, d.maskEnabled)...)
+	fields = append(fields, d.formatLogs(ctx, separator, d.maskEnabled)...)
 
 	fields = append(fields, zap.String("app", tdr.AppName))
 	fields = append(fields, zap.String("ver", tdr.AppVersion))
@@ -158,22 +216,28 @@ func (d *defaultLogger) TDR(ctx context.Context, tdr LogTdrModel) {
 	fields = append(fields, zap.Int64("rt", tdr.RespTime))
 	fields = append(fields, zap.String("rc", tdr.ResponseCode))
 
-	fields = append(fields, formatLog("header", tdr.Header, false))
-	fields = append(fields, formatLog("req", tdr.Request, d.maskEnabled))
-	fields = append(fields, formatLog("resp", tdr.Response, d.maskEnabled))
-	fields = append(fields, zap.String("error", tdr.Error))
+	// direction is only written when it is set, so TDR written directly keep the same shape
+	if tdr.Direction != "" {
+		fields = append(fields, zap.String("direction", tdr.Direction))
+	}
+
+	// header is never masked, but sensitive header such as Authorization is always redacted
+	fields = append(fields, d.formatLog("header", d.headers.redact(tdr.Header), false))
+	fields = append(fields, d.formatLog("req", tdr.Request, d.maskEnabled))
+	fields = append(fields, d.formatLog("resp", tdr.Response, d.maskEnabled))
+	fields = append(fields, zap.String("error", d.scrubber.scrub(tdr.Error)))
 
-	fields = append(fields, formatLog("addData", tdr.AdditionalData, d.maskEnabled))
+	fields = append(fields, d.formatLog("addData", tdr.AdditionalData, d.maskEnabled))
 
 	// exclusive: this must be write only in TDR log file
 	d.zapLogger.Info(separator, fields...)
 }
 
-func formatLogs(ctx context.Context, msg string, mask bool, fields ...Field) (logRecord []zap.Field) {
+func (d *defaultLogger) formatLogs(ctx context.Context, msg string, mask bool, fields ...Field) (logRecord []zap.Field) {
 	ctxVal := ExtractCtx(ctx)
 
 	// add global value from context that must be exist on all logs!
-	logRecord = append(logRecord, zap.String("message", msg))
+	logRecord = append(logRecord, zap.String("message", d.scrubber.scrub(msg)))
 
 	logRecord = append(logRecord, zap.String("_app_name", ctxVal.ServiceName))
 	logRecord = append(logRecord, zap.String("_app_version", ctxVal.ServiceVersion))
@@ -185,54 +249,102 @@ func formatLogs(ctx context.Context, msg string, mask bool, fields ...Field) (lo
 	logRecord = append(logRecord, zap.String("_app_method", ctxVal.ReqMethod))
 	logRecord = append(logRecord, zap.String("_app_uri", ctxVal.ReqURI))
 
+	// trace id is only written when it is set, so log without tracing keep the same shape
+	if ctxVal.TraceID != "" {
+		logRecord = append(logRecord, zap.String("_app_trace_id", ctxVal.TraceID))
+	}
+
+	if ctxVal.SpanID != "" {
+		logRecord = append(logRecord, zap.String("_app_span_id", ctxVal.SpanID))
+	}
+
 	// add additional data that available across all log, such as user_id
 	if ctxVal.AdditionalData != nil {
 		logRecord = append(logRecord, zap.Any("_app_data", ctxVal.AdditionalData))
 	}
 
 	for _, field := range fields {
-		logRecord = append(logRecord, formatLog(field.Key, field.Val, mask))
+		logRecord = append(logRecord, d.formatLog(field.Key, field.Val, mask))
 	}
 
 	return
 }
 
-func formatLog(key string, msg interface{}, mask bool) (logRecord zap.Field) {
+func (d *defaultLogger) formatLog(key string, msg interface{}, mask bool) (logRecord zap.Field) {
 	if msg == nil {
 		logRecord = zap.Any(key, struct{}{})
 		return
 	}
 
+	// type which own its masked representation never walked using reflection,
+	// ObjectMasker wins over Masker, the same as nested value (see maskWalker.value).
+	// In allowlist mode it is walked by maskWalker.allowed, so only value declared safe is written.
+	if mask && !d.maskOpts.allowlist && !isNilPointer(msg) {
+		switch m := msg.(type) {
+		case ObjectMasker:
+			logRecord = zap.Object(key, zapcore.ObjectMarshalerFunc(m.MarshalMaskedLogObject))
+			return
+		case Masker:
+			// walked once, so OptionsMasker is masked using options of this logger
+			logRecord = zap.Any(key, masking(m, &d.maskOpts))
+			return
+		}
+	}
+
+	if mask && d.maskOpts.allowlist {
+		logRecord = zap.Any(key, d.allowlisted(msg))
+		return
+	}
+
 	// handle proto message
 	p, ok := msg.(proto.Message)
 	if ok {
-		b, _err := json.Marshal(p)
-		if _err != nil {
-			logRecord = zap.Any(key, p.String())
+		data, ok := protoJSON(p)
+		if !ok {
+			// string cannot be masked, so only try to marshal as json object
+			logRecord = zap.Any(key, data)
 			return
 		}
 
-		var data interface{}
-		if _err = json.Unmarshal(b, &data); _err != nil {
-			// string cannot be masked, so only try to marshal as json object
-			logRecord = zap.Any(key, p.String())
-			return
+		// use object json, masked by key rules since proto message has no mask tag
+		if mask {
+			data = maskJSON(data, d.maskRules, &d.maskOpts)
 		}
 
-		// use object json
 		logRecord = zap.Any(key, data)
 		return
 	}
 
-	// handle string, string is cannot be masked, just write it
-	// but try to parse as json object if possible
+	// json object built manually, such as messages of gRPC stream, may contain proto message or struct,
+	// each of them is written the same as a single value
+	switch msg.(type) {
+	case map[string]interface{}, []interface{}:
+		data, _ := d.jsonElems(msg, mask)
+		if mask {
+			data = maskJSON(data, d.maskRules, &d.maskOpts)
+		}
+
+		logRecord = zap.Any(key, data)
+		return
+	}
+
+	// handle string, plain string is cannot be masked, just write it
+	// but try to parse as json object if possible, and then mask it by key rules
 	if str, ok := msg.(string); ok {
+		// free-form string may contain sensitive data, such as card number in error message
+		str = d.scrubber.scrub(str)
+
+		// only object or array is parsed, so string such as "123" or "true" is never written as other type
 		var data interface{}
-		if _err := json.Unmarshal([]byte(str), &data); _err != nil {
+		if !isJSONText(str) || json.Unmarshal([]byte(str), &data) != nil {
 			logRecord = zap.String(key, str)
 			return
 		}
 
+		if mask {
+			data = maskJSON(data, d.maskRules, &d.maskOpts)
+		}
+
 		logRecord = zap.Any(key, data)
 		return
 	}
@@ -245,17 +357,144 @@ func formatLog(key string, msg interface{}, mask bool) (logRecord zap.Field) {
 
 	// if masking is enabled and one of type supported by masking function
 	switch reflect.ValueOf(msg).Kind() {
-	case reflect.Ptr, reflect.Slice, reflect.Struct:
-		msgMasking := masking(msg)
-
-		if convert, ok := msgMasking.(reflect.Value); ok {
-			value := convert.Interface()
-			logRecord = zap.Any(key, value)
-			return
-		}
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		logRecord = zap.Any(key, masking(msg, &d.maskOpts))
+		return
 	}
 
-	// not masked since it failed to convert to reflect.Value above
+	// scalar has no field to be masked
 	logRecord = zap.Any(key, msg)
 	return
 }
+
+// allowlisted returns msg where only value declared safe is written, see WithAllowlistMode.
+// JSON string and proto message has no declaration, so only its key is written.
+func (d *defaultLogger) allowlisted(msg interface{}) interface{} {
+	var b []byte
+	switch m := msg.(type) {
+	case string:
+		if !isJSONText(m) {
+			return placeholder(reflect.ValueOf(msg))
+		}
+
+		b = []byte(m)
+	case proto.Message:
+		var err error
+		if b, err = json.Marshal(m); err != nil {
+			return placeholder(reflect.ValueOf(msg))
+		}
+	default:
+		return masking(msg, &d.maskOpts)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return placeholder(reflect.ValueOf(msg))
+	}
+
+	return masking(data, &d.maskOpts)
+}
+
+// unknownMask handle value which `mask` tag has no masker, based on UnknownMaskTag option
+func (d *defaultLogger) unknownMask(tag, value string) string {
+	switch d.unknownMaskTag {
+	case UnknownMaskTagWarn:
+		if _, warned := d.warnedMaskTags.LoadOrStore(tag, struct{}{}); !warned {
+			d.Warn(context.Background(), "unknown mask tag, value is not masked", ToField("tag", tag))
+		}
+	case UnknownMaskTagFail:
+		return maskString(maskAny, value, nil)
+	}
+
+	return value
+}
+
+// isJSONText returns true when s may be JSON object or array, so other string is never decoded
+func isJSONText(s string) bool {
+	s = strings.TrimLeft(s, " \t\r\n")
+	return s != "" && (s[0] == '{' || s[0] == '[')
+}
+
+// protoJSON returns proto message as JSON object, or its string when it cannot be marshalled
+func protoJSON(p proto.Message) (interface{}, bool) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return p.String(), false
+	}
+
+	var data interface{}
+	if err = json.Unmarshal(b, &data); err != nil {
+		return p.String(), false
+	}
+
+	return data, true
+}
+
+// jsonElems returns object or array where proto message is replaced by its JSON object, and value
+// with mask tag is masked when mask is true. It is copied only when one of its elements is changed.
+func (d *defaultLogger) jsonElems(data interface{}, mask bool) (interface{}, bool) {
+	switch val := data.(type) {
+	case nil, string, bool, float64, int, int64, json.Number:
+		return data, false
+	case map[string]interface{}:
+		var altered map[string]interface{}
+		for k, v := range val {
+			elem, changed := d.jsonElems(v, mask)
+			if changed && altered == nil {
+				altered = make(map[string]interface{}, len(val))
+				for k, v := range val {
+					altered[k] = v
+				}
+			}
+
+			if changed {
+				altered[k] = elem
+			}
+		}
+
+		if altered == nil {
+			return data, false
+		}
+
+		return altered, true
+	case []interface{}:
+		var altered []interface{}
+		for i, v := range val {
+			elem, changed := d.jsonElems(v, mask)
+			if changed && altered == nil {
+				altered = append([]interface{}(nil), val...)
+			}
+
+			if changed {
+				altered[i] = elem
+			}
+		}
+
+		if altered == nil {
+			return data, false
+		}
+
+		return altered, true
+	case proto.Message:
+		if isNilPointer(val) {
+			return nil, true
+		}
+
+		elem, _ := protoJSON(val)
+		return elem, true
+	}
+
+	if mask {
+		switch reflect.ValueOf(data).Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+			return masking(data, &d.maskOpts), true
+		}
+	}
+
+	return data, false
+}
+
+func isNilPointer(v interface{}) bool {
+	val := reflect.ValueOf(v)
+	return val.Kind() == reflect.Ptr && val.IsNil()
+}
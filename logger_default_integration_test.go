@@ -94,7 +94,7 @@ var complexObject = Main{
 }
 
 // data
-const message = "log message"
+const logMessage = "log message"
 
 var ctxValue = Context{
 	ServiceName:    "my service name",
@@ -186,7 +186,7 @@ func TestDefaultLogger_Integration(t *testing.T) {
 
 		for _, tc := range testCases {
 			t.Run(tc.Level, func(t *testing.T) {
-				tc.Func(ctx, message, fields...) // call func
+				tc.Func(ctx, logMessage, fields...) // call func
 
 				// get data from log
 				actualString := writer.GetActualData()
@@ -203,7 +203,7 @@ func TestDefaultLogger_Integration(t *testing.T) {
 				assert.NoError(t, err)
 				assert.EqualValues(t, LogTypeSYS, logMsgAndFields.LogType)
 				assert.EqualValues(t, tc.Level, logMsgAndFields.Level) // assert expected level
-				assert.EqualValues(t, message, logMsgAndFields.Message)
+				assert.EqualValues(t, logMessage, logMsgAndFields.Message)
 				assert.EqualValues(t, "string", logMsgAndFields.String)
 				assert.EqualValues(t, 0, logMsgAndFields.Int)
 				assert.EqualValues(t, int32(32), logMsgAndFields.Int32)
@@ -225,7 +225,7 @@ func TestDefaultLogger_Integration(t *testing.T) {
 			}
 		}()
 
-		log.Panic(ctx, message, fields...)
+		log.Panic(ctx, logMessage, fields...)
 
 		// get data from log
 		actualString := writer.GetActualData()
@@ -242,7 +242,7 @@ func TestDefaultLogger_Integration(t *testing.T) {
 		assert.NoError(t, err)
 		assert.EqualValues(t, LogTypeSYS, logMsgAndFields.LogType)
 		assert.EqualValues(t, "panic", logMsgAndFields.Level) // assert expected level
-		assert.EqualValues(t, message, logMsgAndFields.Message)
+		assert.EqualValues(t, logMessage, logMsgAndFields.Message)
 		assert.EqualValues(t, "string", logMsgAndFields.String)
 		assert.EqualValues(t, 0, logMsgAndFields.Int)
 		assert.EqualValues(t, int32(32), logMsgAndFields.Int32)
@@ -579,15 +579,20 @@ func TestDefaultLoggerTDR_Integration(t *testing.T) {
 		assert.EqualValues(t, tdrData.RespTime, logTdrData.RespTime)
 		assert.EqualValues(t, tdrData.ResponseCode, logTdrData.ResponseCode)
 
-		// for valid json string, it will never be masked since Map is not supported by masking function
+		// valid json string is masked by key rules, "mobile" is one of DefaultMaskRules
 		jsonMap := map[string]interface{}{}
 		err = json.Unmarshal([]byte(jsonString), &jsonMap)
 		assert.NoError(t, err)
 
+		maskedJsonMap := map[string]interface{}{}
+		err = json.Unmarshal([]byte(jsonString), &maskedJsonMap)
+		assert.NoError(t, err)
+		maskedJsonMap["data"].(map[string]interface{})["mobile"] = MaskingPhoneNumber("6281297191466")
+
 		// header also converted to map if possible, but it never masked
 		assert.EqualValues(t, jsonMap, logTdrData.Header)
-		assert.EqualValues(t, jsonMap, logTdrData.Request)
-		assert.EqualValues(t, jsonMap, logTdrData.Response)
+		assert.EqualValues(t, maskedJsonMap, logTdrData.Request)
+		assert.EqualValues(t, maskedJsonMap, logTdrData.Response)
 
 		assert.EqualValues(t, tdrData.Error, logTdrData.Error)
 		assert.EqualValues(t, tdrData.AdditionalData, logTdrData.AdditionalData)
@@ -678,7 +683,7 @@ func TestDefaultLoggerTDR_Integration(t *testing.T) {
 		assert.EqualValues(t, header, logTdrData.Header)
 
 		// try to mask json data and compare to what returned by log
-		maskedJsonData := masking(jsonData)
+		maskedJsonData := masking(jsonData, nil)
 		if convert, ok := maskedJsonData.(reflect.Value); ok {
 			maskedJsonData = convert.Interface()
 		}
@@ -191,3 +191,41 @@ func BenchmarkDefaultLogger_TDR(b *testing.B) {
 		log.TDR(ctx, GenerateLogTDR(nil))
 	}
 }
+
+func TestDefaultLogger_JSONElems(t *testing.T) {
+	w := &linesWriter{}
+	log, err := newLogger(WithCustomWriter(w), MaskEnabled())
+	assert.NoError(t, err)
+
+	// struct in manually built array is masked by its mask tag, the same as written on its own
+	log.Info(ctx, logMessage, ToField("items", []interface{}{
+		maskerData{Card: "4111111111111111"},
+		map[string]interface{}{"card": maskerData{Card: "4111111111111111"}, "note": "plain"},
+	}))
+
+	assert.Len(t, w.lines, 1)
+	assert.NotContains(t, w.lines[0], "4111111111111111")
+	assert.Contains(t, w.lines[0], `"note":"plain"`)
+}
+
+func TestDefaultLogger_StringField(t *testing.T) {
+	w := &linesWriter{}
+	log, err := newLogger(WithCustomWriter(w), MaskEnabled())
+	assert.NoError(t, err)
+
+	// only JSON object or array is decoded, other string is written as it is
+	log.Info(ctx, logMessage,
+		ToField("number", "123"),
+		ToField("bool", "true"),
+		ToField("null", "null"),
+		ToField("text", "not {json}"),
+		ToField("json", ` {"note":"plain"}`),
+	)
+
+	assert.Len(t, w.lines, 1)
+	assert.Contains(t, w.lines[0], `"number":"123"`)
+	assert.Contains(t, w.lines[0], `"bool":"true"`)
+	assert.Contains(t, w.lines[0], `"null":"null"`)
+	assert.Contains(t, w.lines[0], `"text":"not {json}"`)
+	assert.Contains(t, w.lines[0], `"json":{"note":"plain"}`)
+}
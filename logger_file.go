@@ -10,7 +10,33 @@ type OptionsFile struct {
 	FileLocation string        `json:"fileLocation"`
 	FileMaxAge   time.Duration `json:"fileMaxAge"`
 	Mask         bool          `json:"mask"`
-	Level        Level         `json:"level"`
+	OptionsOutput
+	Level Level `json:"level"`
+
+	// HashChain append "_hash" into each line and signed checkpoint at rotation, see Verify.
+	// HashChainSecret sign the checkpoint, it must be at least 16 bytes.
+	HashChain       bool   `json:"hashChain"`
+	HashChainSecret string `json:"hashChainSecret"`
+
+	// EncryptKeyEnv or EncryptKeyFile is base64 AES key used to encrypt the file using AES-GCM, read it using
+	// NewDecryptReader or cmd/logcat. KeyProvider is used instead when it is set, such as NewLocalKMS.
+	EncryptKeyEnv  string      `json:"encryptKeyEnv"`
+	EncryptKeyFile string      `json:"encryptKeyFile"`
+	KeyProvider    KeyProvider `json:"-"`
+}
+
+// keyProvider returns nil when encryption is not enabled
+func (o *OptionsFile) keyProvider() (KeyProvider, error) {
+	switch {
+	case o.KeyProvider != nil:
+		return o.KeyProvider, nil
+	case o.EncryptKeyEnv != "":
+		return EnvKeyProvider(o.EncryptKeyEnv)
+	case o.EncryptKeyFile != "":
+		return FileKeyProvider(o.EncryptKeyFile)
+	default:
+		return nil, nil
+	}
 }
 
 // SetupLoggerFile will return legacy Logger using File interface with new logic using Logger
@@ -21,10 +47,7 @@ func SetupLoggerFile(serviceName string, config *OptionsFile) Logger {
 		panic("legacy logger file config is nil")
 	}
 
-	var opt = make([]Option, 0)
-	if config.Mask {
-		opt = append(opt, MaskEnabled())
-	}
+	opt := config.options(config.Mask, config.Level)
 
 	if config.Stdout {
 		opt = append(opt, WithStdout())
@@ -32,8 +55,6 @@ func SetupLoggerFile(serviceName string, config *OptionsFile) Logger {
 		opt = append(opt, WithFileOutput(config))
 	}
 
-	opt = append(opt, WithLevel(config.Level))
-
 	log, err := newLogger(opt...)
 	if err != nil {
 		panic(fmt.Errorf("init legacy logger with mode %s error: %w", File, err))
@@ -0,0 +1,25 @@
+package logger
+
+import (
+	"fmt"
+)
+
+// SetupLoggerHTTPBulk will return Logger sending log in batch into Elasticsearch/OpenSearch bulk API or generic endpoint
+func SetupLoggerHTTPBulk(serviceName string, config *OptionsHTTPBulk) Logger {
+	fmt.Println("Try newLogger HTTP bulk...")
+
+	if config == nil {
+		panic("logger http bulk config is nil")
+	}
+
+	opt := config.options(config.Mask, config.Level)
+
+	opt = append(opt, WithHTTPBulkOutput(config))
+
+	log, err := newLogger(opt...)
+	if err != nil {
+		panic(fmt.Errorf("init logger with mode %s error: %w", HTTPBulk, err))
+	}
+
+	return log
+}
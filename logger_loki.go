@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type OptionsLoki struct {
+	URL            string            `json:"url" validate:"required,url"`                       // push API, such as http://loki:3100/loki/api/v1/push
+	Encoding       string            `json:"encoding" validate:"omitempty,oneof=protobuf json"` // default is snappy compressed protobuf
+	TenantID       string            `json:"tenantId"`                                          // sent as X-Scope-OrgID header
+	Labels         map[string]string `json:"labels"`                                            // log field to label name, default is DefaultLokiLabels
+	ExternalLabels map[string]string `json:"externalLabels"`                                    // static label added to every stream, such as env
+	Headers        map[string]string `json:"headers"`
+	Username       string            `json:"username"`
+	Password       string            `json:"password"`
+	BatchSize      int               `json:"batchSize"`     // default is 500 records
+	FlushInterval  time.Duration     `json:"flushInterval"` // default is 1 second
+	BufferSize     int               `json:"bufferSize"`    // default is 10000 records
+	MaxRetries     int               `json:"maxRetries"`    // default is 3, negative value disable retry
+	RetryBackoff   time.Duration     `json:"retryBackoff"`  // default is 100 milliseconds, doubled on each retry
+	Timeout        time.Duration     `json:"timeout"`       // default is 10 seconds
+	Mask           bool              `json:"mask"`
+	OptionsOutput
+	Level Level `json:"level"`
+
+	// Client is used instead of default client when it is set, such as to use custom TLS config
+	Client *http.Client `json:"-"`
+	// OnError is called when records cannot be sent, default is writing it into stderr
+	OnError func(error) `json:"-"`
+}
+
+// SetupLoggerLoki will return Logger pushing log into Grafana Loki
+func SetupLoggerLoki(serviceName string, config *OptionsLoki) Logger {
+	fmt.Println("Try newLogger Loki...")
+
+	if config == nil {
+		panic("logger loki config is nil")
+	}
+
+	opt := config.options(config.Mask, config.Level)
+
+	opt = append(opt, WithLokiOutput(config))
+
+	log, err := newLogger(opt...)
+	if err != nil {
+		panic(fmt.Errorf("init logger with mode %s error: %w", Loki, err))
+	}
+
+	return log
+}
@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// entryWriter write SYS log of level without exit or panic, so every output of multiLogger
+// receive fatal and panic log before the last output exit or panic
+type entryWriter interface {
+	writeEntry(ctx context.Context, level zapcore.Level, message string, fields ...Field)
+}
+
+// multiLogger write every log into each logger, such as file and kafka at once,
+// each with its own level and mask setting
+type multiLogger struct {
+	loggers []Logger
+}
+
+// NewMultiLogger returns Logger writing into every logger, Close close all of them.
+func NewMultiLogger(loggers ...Logger) Logger {
+	if len(loggers) == 1 {
+		return loggers[0]
+	}
+
+	return &multiLogger{loggers: loggers}
+}
+
+func (m *multiLogger) Debug(ctx context.Context, message string, fields ...Field) {
+	for _, l := range m.loggers {
+		l.Debug(ctx, message, fields...)
+	}
+}
+
+func (m *multiLogger) Info(ctx context.Context, message string, fields ...Field) {
+	for _, l := range m.loggers {
+		l.Info(ctx, message, fields...)
+	}
+}
+
+func (m *multiLogger) Warn(ctx context.Context, message string, fields ...Field) {
+	for _, l := range m.loggers {
+		l.Warn(ctx, message, fields...)
+	}
+}
+
+func (m *multiLogger) Error(ctx context.Context, message string, fields ...Field) {
+	for _, l := range m.loggers {
+		l.Error(ctx, message, fields...)
+	}
+}
+
+// Fatal write log into every logger and close it, since the last logger exit the application
+func (m *multiLogger) Fatal(ctx context.Context, message string, fields ...Field) {
+	if len(m.loggers) <= 0 {
+		return
+	}
+
+	last := len(m.loggers) - 1
+	for _, l := range m.loggers[:last] {
+		if w, ok := l.(entryWriter); ok {
+			w.writeEntry(ctx, zapcore.FatalLevel, message, fields...)
+		}
+
+		_ = l.Close()
+	}
+
+	m.loggers[last].Fatal(ctx, message, fields...)
+}
+
+// Panic write log into every logger, then the last logger panics
+func (m *multiLogger) Panic(ctx context.Context, message string, fields ...Field) {
+	if len(m.loggers) <= 0 {
+		return
+	}
+
+	last := len(m.loggers) - 1
+	for _, l := range m.loggers[:last] {
+		if w, ok := l.(entryWriter); ok {
+			w.writeEntry(ctx, zapcore.PanicLevel, message, fields...)
+		}
+	}
+
+	m.loggers[last].Panic(ctx, message, fields...)
+}
+
+func (m *multiLogger) writeEntry(ctx context.Context, level zapcore.Level, message string, fields ...Field) {
+	for _, l := range m.loggers {
+		if w, ok := l.(entryWriter); ok {
+			w.writeEntry(ctx, level, message, fields...)
+		}
+	}
+}
+
+func (m *multiLogger) TDR(ctx context.Context, tdr LogTdrModel) {
+	for _, l := range m.loggers {
+		l.TDR(ctx, tdr)
+	}
+}
+
+func (m *multiLogger) Close() error {
+	var err error
+	for i, l := range m.loggers {
+		if _err := l.Close(); _err != nil && err == nil {
+			err = fmt.Errorf("error close output %d: %w", i, _err)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fatalRecorder record Fatal call instead of exit
+type fatalRecorder struct {
+	NoopContextLogger
+	fatal []string
+}
+
+func (f *fatalRecorder) Fatal(_ context.Context, message string, _ ...Field) {
+	f.fatal = append(f.fatal, message)
+}
+
+func TestSetupLoggerCombineOutputs(t *testing.T) {
+	log := SetupLoggerCombine(Options{
+		Name: "my service name",
+		SysOptions: OptionsLogger{
+			Outputs: []OptionsLogger{
+				{Type: "test-memory", OptionsSink: json.RawMessage(`{"name": "outputs-all"}`)},
+				{Type: "test-memory", OptionsSink: json.RawMessage(`{"name": "outputs-error", "mask": true, "level": 2}`)},
+			},
+		},
+		TdrOptions: OptionsLogger{
+			Type:        "test-memory",
+			OptionsSink: json.RawMessage(`{"name": "outputs-tdr"}`),
+		},
+	})
+
+	card := Field{Key: "card", Val: maskerData{Card: "4111111111111111"}}
+	log.Info(ctx, "info", card)
+	log.Error(ctx, "error", card)
+	log.TDR(ctx, LogTdrModel{})
+	require.NoError(t, log.Close())
+
+	all := memorySinks["outputs-all"].lines
+	require.Len(t, all, 2)
+	assert.Contains(t, all[0], `"message":"info"`)
+	assert.Contains(t, all[1], "4111111111111111")
+
+	errorOnly := memorySinks["outputs-error"].lines
+	require.Len(t, errorOnly, 1)
+	assert.Contains(t, errorOnly[0], `"message":"error"`)
+	assert.NotContains(t, errorOnly[0], "4111111111111111")
+
+	require.Len(t, memorySinks["outputs-tdr"].lines, 1)
+}
+
+func TestMultiLogger(t *testing.T) {
+	newLines := func() (Logger, *linesWriter) {
+		w := &linesWriter{}
+		log, err := newLogger(WithCustomWriter(w))
+		require.NoError(t, err)
+		return log, w
+	}
+
+	t.Run("single", func(t *testing.T) {
+		log, _ := newLines()
+		assert.Same(t, log, NewMultiLogger(log))
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		first, firstLines := newLines()
+		second, secondLines := newLines()
+		log := NewMultiLogger(first, second)
+
+		assert.Panics(t, func() { log.Panic(ctx, logMessage) })
+		require.Len(t, firstLines.lines, 1)
+		require.Len(t, secondLines.lines, 1)
+		assert.Contains(t, firstLines.lines[0], `"level":"panic"`)
+		assert.Contains(t, secondLines.lines[0], `"level":"panic"`)
+	})
+
+	t.Run("fatal", func(t *testing.T) {
+		first, firstLines := newLines()
+		last := &fatalRecorder{}
+		log := NewMultiLogger(first, last)
+
+		log.Fatal(ctx, logMessage)
+		require.Len(t, firstLines.lines, 1)
+		assert.Contains(t, firstLines.lines[0], `"level":"fatal"`)
+		assert.Contains(t, firstLines.lines[0], `"message":"log message"`)
+		assert.Equal(t, []string{logMessage}, last.fatal)
+	})
+}
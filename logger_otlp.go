@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+type OptionsOTLP struct {
+	Protocol           string            `json:"protocol" validate:"omitempty,oneof=http grpc"` // default is http
+	Endpoint           string            `json:"endpoint" validate:"required"`                  // http://collector:4318/v1/logs for http, collector:4317 for grpc
+	Insecure           bool              `json:"insecure"`                                      // grpc without TLS
+	Headers            map[string]string `json:"headers"`                                       // sent as http header or grpc metadata
+	ResourceAttributes map[string]string `json:"resourceAttributes"`                            // added to service.name and service.version, such as deployment.environment
+	BatchSize          int               `json:"batchSize"`                                     // default is 500 records
+	FlushInterval      time.Duration     `json:"flushInterval"`                                 // default is 1 second
+	BufferSize         int               `json:"bufferSize"`                                    // default is 10000 records
+	MaxRetries         int               `json:"maxRetries"`                                    // default is 3, negative value disable retry
+	RetryBackoff       time.Duration     `json:"retryBackoff"`                                  // default is 100 milliseconds, doubled on each retry
+	Timeout            time.Duration     `json:"timeout"`                                       // default is 10 seconds
+	Mask               bool              `json:"mask"`
+	OptionsOutput
+	Level Level `json:"level"`
+
+	// Client is used instead of default client of http protocol when it is set, such as to use custom TLS config
+	Client *http.Client `json:"-"`
+	// DialOptions is added when dialing collector using grpc protocol
+	DialOptions []grpc.DialOption `json:"-"`
+	// OnError is called when records cannot be sent, default is writing it into stderr
+	OnError func(error) `json:"-"`
+}
+
+// SetupLoggerOTLP will return Logger exporting log into OpenTelemetry Collector
+func SetupLoggerOTLP(serviceName string, config *OptionsOTLP) Logger {
+	fmt.Println("Try newLogger OTLP...")
+
+	if config == nil {
+		panic("logger otlp config is nil")
+	}
+
+	opt := config.options(config.Mask, config.Level)
+
+	opt = append(opt, WithOTLPOutput(config))
+
+	log, err := newLogger(opt...)
+	if err != nil {
+		panic(fmt.Errorf("init logger with mode %s error: %w", OTLP, err))
+	}
+
+	return log
+}
@@ -2,20 +2,33 @@ package logger
 
 import (
 	"fmt"
+	"time"
 )
 
 type OptionsQueue struct {
-	Type     string          `json:"type"`
-	Topic    string          `json:"topic"`
+	Type     string          `json:"type" validate:"omitempty,oneof=kafka nats rabbitmq redis"`
+	Topic    string          `json:"topic" validate:"required"` // kafka topic, nats subject, rabbitmq routing key or redis stream key
+	Exchange string          `json:"exchange"`                  // rabbitmq exchange, default exchange route into queue named Topic
 	Producer ProducerOptions `json:"producer"`
 	Mask     bool            `json:"mask"`
-	Level    Level           `json:"level"`
+	OptionsOutput
+	Level Level `json:"level"`
+
+	// OnError is called when records cannot be sent, default is writing it into stderr
+	OnError func(error) `json:"-"`
 }
 
 type ProducerOptions struct {
-	Address         string `json:"address"`
-	RetryMax        int    `json:"retryMax"`
-	ReturnSuccesses bool   `json:"returnSuccesses"`
+	Address         string        `json:"address" validate:"required"` // comma separated kafka brokers, nats url, amqp url or redis host:port
+	Password        string        `json:"password"`                    // redis password
+	MaxLen          int64         `json:"maxLen"`                      // redis stream is trimmed approximately into this length, default is unlimited
+	RetryMax        int           `json:"retryMax"`                    // default is 3, negative value disable retry
+	ReturnSuccesses bool          `json:"returnSuccesses"`
+	BatchSize       int           `json:"batchSize"`     // default is 500 records
+	FlushInterval   time.Duration `json:"flushInterval"` // default is 1 second
+	BufferSize      int           `json:"bufferSize"`    // default is 10000 records, new record is dropped and reported to OnError when it is full
+	RetryBackoff    time.Duration `json:"retryBackoff"`  // default is 100 milliseconds, doubled on each retry
+	Timeout         time.Duration `json:"timeout"`       // default is 10 seconds
 }
 
 // SetupLoggerQueue will return legacy Logger using Queue interface with new logic using Logger
@@ -26,18 +39,9 @@ func SetupLoggerQueue(serviceName string, config *OptionsQueue) Logger {
 		panic("legacy logger queue config is nil")
 	}
 
-	if config.Type != QueueTypeKafka {
-		panic(fmt.Errorf("legacy logger queue unsupported queue type %s", config.Type))
-	}
-
-	var opt = make([]Option, 0)
-
-	if config.Mask {
-		opt = append(opt, MaskEnabled())
-	}
+	opt := config.options(config.Mask, config.Level)
 
-	opt = append(opt, WithKafkaOutput(config))
-	opt = append(opt, WithLevel(config.Level))
+	opt = append(opt, WithQueueOutput(config))
 
 	log, err := newLogger(opt...)
 	if err != nil {
@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+)
+
+type OptionsSyslog struct {
+	Network            string            `json:"network" validate:"required,oneof=udp tcp tls unix unixgram"`
+	Address            string            `json:"address" validate:"required"`
+	Facility           string            `json:"facility"` // such as user or local0, default is user
+	AppName            string            `json:"appName"`  // default is _app_name of each record
+	Hostname           string            `json:"hostname"` // default is os.Hostname
+	SDID               string            `json:"sdId"`     // name@enterprise number, default is DefaultSyslogSDID
+	StructuredData     map[string]string `json:"structuredData"`
+	CAFile             string            `json:"caFile"`
+	InsecureSkipVerify bool              `json:"insecureSkipVerify"`
+	WriteTimeout       time.Duration     `json:"writeTimeout"`  // default is 5 seconds
+	BatchSize          int               `json:"batchSize"`     // default is 500 records
+	FlushInterval      time.Duration     `json:"flushInterval"` // default is 1 second
+	BufferSize         int               `json:"bufferSize"`    // default is 10000 records
+	MaxRetries         int               `json:"maxRetries"`    // default is 3, negative value disable retry
+	RetryBackoff       time.Duration     `json:"retryBackoff"`  // default is 100 milliseconds, doubled on each retry
+	Mask               bool              `json:"mask"`
+	OptionsOutput
+	Level Level `json:"level"`
+
+	// OnError is called when records cannot be sent, default is writing it into stderr
+	OnError func(error) `json:"-"`
+}
+
+// SetupLoggerSyslog will return Logger writing RFC 5424 message into syslog server such as rsyslog
+func SetupLoggerSyslog(serviceName string, config *OptionsSyslog) Logger {
+	fmt.Println("Try newLogger Syslog...")
+
+	if config == nil {
+		panic("logger syslog config is nil")
+	}
+
+	opt := config.options(config.Mask, config.Level)
+
+	opt = append(opt, WithSyslogOutput(config))
+
+	log, err := newLogger(opt...)
+	if err != nil {
+		panic(fmt.Errorf("init logger with mode %s error: %w", Syslog, err))
+	}
+
+	return log
+}
@@ -0,0 +1,288 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/segmentio/encoding/json"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	LokiProtobuf = "protobuf"
+	LokiJSON     = "json"
+
+	lokiTimeLayout = "2006-01-02 15:04:05.999" // the same as timeEncoder
+)
+
+// DefaultLokiLabels map low cardinality log field into Loki stream label, other field is kept in the line.
+var DefaultLokiLabels = map[string]string{
+	"_app_name": "app",
+	"logType":   "log_type",
+	"level":     "level",
+	"_app_tag":  "tag",
+}
+
+var lokiLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// lokiStream is entries of the same labels, in the same order as it is written
+type lokiStream struct {
+	labels   map[string]string
+	selector string // labels in Prometheus format such as {app="svc", level="info"}
+	entries  []lokiEntry
+}
+
+type lokiEntry struct {
+	ts   time.Time
+	line string
+}
+
+// lokiSender push batch of records into Loki push API
+type lokiSender struct {
+	conf   *OptionsLoki
+	client *http.Client
+	labels map[string]string // record key to label name
+}
+
+func newLokiWriter(conf *OptionsLoki) (*batchWriter, error) {
+	labels := conf.Labels
+	if labels == nil {
+		labels = DefaultLokiLabels
+	}
+
+	for _, name := range labels {
+		if !lokiLabelName.MatchString(name) {
+			return nil, fmt.Errorf("invalid loki label name %q", name)
+		}
+	}
+
+	for name := range conf.ExternalLabels {
+		if !lokiLabelName.MatchString(name) {
+			return nil, fmt.Errorf("invalid loki label name %q", name)
+		}
+	}
+
+	client := conf.Client
+	if client == nil {
+		timeout := conf.Timeout
+		if timeout <= 0 {
+			timeout = defaultHTTPBulkTimeout
+		}
+
+		client = &http.Client{Timeout: timeout}
+	}
+
+	sender := &lokiSender{conf: conf, client: client, labels: labels}
+	return newBatchWriter(batchConfig{
+		size:          conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+		bufferSize:    conf.BufferSize,
+		maxRetries:    conf.MaxRetries,
+		retryBackoff:  conf.RetryBackoff,
+		onError:       conf.OnError,
+	}, sender.send), nil
+}
+
+func (s *lokiSender) send(records [][]byte) ([][]byte, error) {
+	streams := s.streams(records)
+
+	var body []byte
+	contentType := "application/x-protobuf"
+	if s.conf.Encoding == LokiJSON {
+		body = encodeLokiJSON(streams)
+		contentType = "application/json"
+	} else {
+		body = snappy.Encode(nil, encodeLokiProtobuf(streams))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	if s.conf.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", s.conf.TenantID)
+	}
+
+	if s.conf.Username != "" {
+		req.SetBasicAuth(s.conf.Username, s.conf.Password)
+	}
+
+	for k, v := range s.conf.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return records, err
+	}
+
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	// Loki returns 429 when ingestion rate limit is reached, so the batch is retried with backoff
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return records, fmt.Errorf("loki status %d: %s", resp.StatusCode, truncateBody(respBody))
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("loki status %d: %s", resp.StatusCode, truncateBody(respBody))
+	}
+
+	return nil, nil
+}
+
+// streams group records by its labels, label field is removed from the line
+func (s *lokiSender) streams(records [][]byte) []*lokiStream {
+	streams := make([]*lokiStream, 0)
+	byLabels := make(map[string]*lokiStream)
+
+	for _, record := range records {
+		labels, entry := s.entry(record)
+		selector := formatLokiLabels(labels)
+		stream, ok := byLabels[selector]
+		if !ok {
+			stream = &lokiStream{labels: labels, selector: selector}
+			byLabels[selector] = stream
+			streams = append(streams, stream)
+		}
+
+		stream.entries = append(stream.entries, entry)
+	}
+
+	return streams
+}
+
+func (s *lokiSender) entry(record []byte) (map[string]string, lokiEntry) {
+	record = bytes.TrimRight(record, "\n")
+
+	labels := make(map[string]string, len(s.labels)+len(s.conf.ExternalLabels))
+	for name, value := range s.conf.ExternalLabels {
+		labels[name] = value
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(record, &fields); err != nil {
+		return labels, lokiEntry{ts: time.Now(), line: string(record)}
+	}
+
+	for key, name := range s.labels {
+		raw, ok := fields[key]
+		if !ok {
+			continue
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			value = string(raw)
+		}
+
+		if value != "" {
+			labels[name] = value
+		}
+
+		delete(fields, key)
+	}
+
+	ts := time.Now()
+	var xtime string
+	if err := json.Unmarshal(fields["xtime"], &xtime); err == nil {
+		if t, err := time.ParseInLocation(lokiTimeLayout, xtime, time.Local); err == nil {
+			ts = t
+		}
+	}
+
+	line, err := json.Marshal(fields)
+	if err != nil {
+		line = record
+	}
+
+	return labels, lokiEntry{ts: ts, line: string(line)}
+}
+
+// formatLokiLabels returns labels in Prometheus format, sorted by name
+func formatLokiLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.Quote(labels[name])
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// encodeLokiJSON encode streams as JSON push request, label is written as object
+func encodeLokiJSON(streams []*lokiStream) []byte {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+
+	req := struct {
+		Streams []stream `json:"streams"`
+	}{Streams: make([]stream, 0, len(streams))}
+
+	for _, s := range streams {
+		values := make([][2]string, len(s.entries))
+		for i, e := range s.entries {
+			values[i] = [2]string{strconv.FormatInt(e.ts.UnixNano(), 10), e.line}
+		}
+
+		req.Streams = append(req.Streams, stream{Stream: s.labels, Values: values})
+	}
+
+	b, _ := json.Marshal(req)
+	return b
+}
+
+// encodeLokiProtobuf encode streams as logproto.PushRequest:
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func encodeLokiProtobuf(streams []*lokiStream) []byte {
+	var req []byte
+	for _, s := range streams {
+		var stream []byte
+		stream = protowire.AppendTag(stream, 1, protowire.BytesType)
+		stream = protowire.AppendString(stream, s.selector)
+
+		for _, e := range s.entries {
+			var ts []byte
+			ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.ts.Unix()))
+			ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.ts.Nanosecond()))
+
+			var entry []byte
+			entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+			entry = protowire.AppendBytes(entry, ts)
+			entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+			entry = protowire.AppendString(entry, e.line)
+
+			stream = protowire.AppendTag(stream, 2, protowire.BytesType)
+			stream = protowire.AppendBytes(stream, entry)
+		}
+
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, stream)
+	}
+
+	return req
+}
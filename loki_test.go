@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// lokiPushServer is fake Loki push API, it decodes both protobuf and JSON push request
+type lokiPushServer struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	streams  map[string][]string // selector or JSON labels to lines
+	status   []int               // response status of each request, default is 204
+}
+
+func (s *lokiPushServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r)
+	if len(s.requests) <= len(s.status) {
+		w.WriteHeader(s.status[len(s.requests)-1])
+		return
+	}
+
+	if s.streams == nil {
+		s.streams = make(map[string][]string)
+	}
+
+	if r.Header.Get("Content-Type") == "application/json" {
+		var req struct {
+			Streams []struct {
+				Stream map[string]string `json:"stream"`
+				Values [][2]string       `json:"values"`
+			} `json:"streams"`
+		}
+
+		if err := json.Unmarshal(body, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		for _, stream := range req.Streams {
+			selector := formatLokiLabels(stream.Stream)
+			for _, v := range stream.Values {
+				s.streams[selector] = append(s.streams[selector], v[1])
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	decoded, err := snappy.Decode(nil, body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	for _, stream := range protoFields(decoded, 1) {
+		selector := string(protoFields(stream, 1)[0])
+		for _, entry := range protoFields(stream, 2) {
+			s.streams[selector] = append(s.streams[selector], string(protoFields(entry, 2)[0]))
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// protoFields returns value of bytes field number num
+func protoFields(b []byte, num protowire.Number) [][]byte {
+	var values [][]byte
+	for len(b) > 0 {
+		n, typ, size := protowire.ConsumeTag(b)
+		b = b[size:]
+
+		if typ != protowire.BytesType {
+			_, size = protowire.ConsumeVarint(b)
+			b = b[size:]
+			continue
+		}
+
+		value, size := protowire.ConsumeBytes(b)
+		b = b[size:]
+		if n == num {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+func TestWithLokiOutput(t *testing.T) {
+	for _, encoding := range []string{LokiProtobuf, LokiJSON} {
+		t.Run(encoding, func(t *testing.T) {
+			s := &lokiPushServer{}
+			server := httptest.NewServer(s)
+			defer server.Close()
+
+			log, err := newLogger(WithLokiOutput(&OptionsLoki{
+				URL:            server.URL + "/loki/api/v1/push",
+				Encoding:       encoding,
+				TenantID:       "tenant",
+				ExternalLabels: map[string]string{"env": "test"},
+			}))
+			require.NoError(t, err)
+
+			log.Info(ctx, "first")
+			log.Error(ctx, "second")
+			log.Info(ctx, "third")
+			require.NoError(t, log.Close())
+
+			require.Len(t, s.requests, 1)
+			assert.Equal(t, "tenant", s.requests[0].Header.Get("X-Scope-OrgID"))
+
+			info := `{app="my service name", env="test", level="info", log_type="SYS", tag="my-tag"}`
+			errorSelector := `{app="my service name", env="test", level="error", log_type="SYS", tag="my-tag"}`
+			require.Len(t, s.streams, 2)
+			require.Len(t, s.streams[info], 2)
+			require.Len(t, s.streams[errorSelector], 1)
+
+			var line map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(s.streams[info][1]), &line))
+			assert.Equal(t, "third", line["message"])
+			assert.Equal(t, "/my-uri", line["_app_uri"]) // not a label, kept in the line
+			assert.NotContains(t, line, "level")
+			assert.NotContains(t, line, "_app_name")
+		})
+	}
+
+	t.Run("rate limited", func(t *testing.T) {
+		s := &lokiPushServer{status: []int{http.StatusTooManyRequests}}
+		server := httptest.NewServer(s)
+		defer server.Close()
+
+		var errs []error
+		log, err := newLogger(WithLokiOutput(&OptionsLoki{
+			URL:          server.URL,
+			RetryBackoff: time.Millisecond,
+			OnError:      func(err error) { errs = append(errs, err) },
+		}))
+		require.NoError(t, err)
+
+		log.Info(ctx, logMessage)
+		require.NoError(t, log.Close())
+
+		assert.Len(t, s.requests, 2)
+		assert.Len(t, s.streams, 1)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := newLogger(WithLokiOutput(&OptionsLoki{URL: "http://localhost", Encoding: "xml"}))
+		assert.Error(t, err)
+
+		_, err = newLogger(WithLokiOutput(&OptionsLoki{URL: "http://localhost", Labels: map[string]string{"level": "log-level"}}))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,201 @@
+package logger
+
+import (
+	"reflect"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Masker is implemented by type which build its own masked representation, so masking never walk it
+// using reflection. Value returned by LogMasked is written as is.
+// Use cmd/maskgen to generate it from `mask` tag for type which is logged on hot path.
+type Masker interface {
+	LogMasked() interface{}
+}
+
+// OptionsMasker is Masker which mask its field using FieldMasker of the logger, so its masked value is the same
+// as masked using reflection with the logger options (WithMaskSecret, WithMaskTokenize, WithUnknownMaskTag).
+// It is generated by cmd/maskgen, LogMasked is called with DefaultFieldMasker when it is masked outside logger.
+type OptionsMasker interface {
+	Masker
+	LogMaskedWith(m FieldMasker) interface{}
+}
+
+// FieldMasker mask field value of OptionsMasker.
+type FieldMasker interface {
+	// MaskString mask value using strategy of `mask` tag value
+	MaskString(strategy, value string) string
+	// MaskTagged mask value as if it is field with `mask:"<strategy>"` tag
+	MaskTagged(strategy string, value interface{}) interface{}
+	// Mask returns masked copy of value using its `mask` tag
+	Mask(value interface{}) interface{}
+}
+
+// ObjectMasker is like zapcore.ObjectMarshaler, but only used when masking is enabled,
+// so type can write its masked field one by one without building intermediate value.
+type ObjectMasker interface {
+	MarshalMaskedLogObject(enc zapcore.ObjectEncoder) error
+}
+
+var (
+	typeMasker        = reflect.TypeOf((*Masker)(nil)).Elem()
+	typeObjectMasker  = reflect.TypeOf((*ObjectMasker)(nil)).Elem()
+	typeOptionsMasker = reflect.TypeOf((*OptionsMasker)(nil)).Elem()
+)
+
+// UnexportedFields is how masking handle unexported struct field.
+type UnexportedFields int
+
+const (
+	// UnexportedOmit never write unexported field, the same as encoding/json. This is the default.
+	UnexportedOmit UnexportedFields = iota
+	// UnexportedInclude write unexported field using its json tag name or field name, masked by its `mask` tag.
+	UnexportedInclude
+)
+
+// defaultMaskDepth stop masking deeply nested value, which is most likely a bug in the logged value
+const defaultMaskDepth = 32
+
+// maskOptions is set once by logger options and shared by every masking call
+type maskOptions struct {
+	unknown    maskFallback
+	unexported UnexportedFields
+	maxDepth   int        // 0 is unlimited
+	maxElems   int        // max element written of each slice, array and map, 0 is unlimited
+	tokenizer  *tokenizer // nil when no secret, see WithMaskSecret
+	allowlist  bool       // only write field declared safe, see WithAllowlistMode
+}
+
+var defaultMaskOptions = maskOptions{maxDepth: defaultMaskDepth}
+
+// maskPlan is computed once for each type, so masking does not parse tag
+// nor walk type which has nothing to be masked on every log call
+type maskPlan struct {
+	masker       bool        // type implements Masker
+	objectMasker bool        // type implements ObjectMasker
+	marshaler    bool        // type implements json.Marshaler or encoding.TextMarshaler, written as is
+	mask         bool        // type, or its nested type, may has value to be masked
+	unexported   bool        // the same as mask, but including unexported field
+	fields       []jsonField // struct only
+}
+
+func (p *maskPlan) needsMask(policy UnexportedFields) bool {
+	if policy == UnexportedInclude {
+		return p.unexported
+	}
+
+	return p.mask
+}
+
+type maskPlanCache struct {
+	plans sync.Map // map[reflect.Type]*maskPlan
+}
+
+var maskPlans = &maskPlanCache{}
+
+func (c *maskPlanCache) plan(t reflect.Type) *maskPlan {
+	if p, ok := c.plans.Load(t); ok {
+		return p.(*maskPlan)
+	}
+
+	p := &maskPlan{
+		masker:       t.Implements(typeMasker),
+		objectMasker: t.Implements(typeObjectMasker),
+		marshaler:    isMarshaler(t),
+		mask:         needsMask(t, false, make(map[reflect.Type]bool)),
+		unexported:   needsMask(t, true, make(map[reflect.Type]bool)),
+	}
+
+	if t.Kind() == reflect.Struct {
+		p.fields = jsonFields(t)
+	}
+
+	actual, _ := c.plans.LoadOrStore(t, p)
+	return actual.(*maskPlan)
+}
+
+// needsMask returns false when value of t can be written as is: no `mask` tag, no Masker
+// and no interface which dynamic value may need to be masked. When unexported is true,
+// struct with unexported field also need to be walked, since encoding/json never write it.
+func needsMask(t reflect.Type, unexported bool, visited map[reflect.Type]bool) bool {
+	// safe value is a marshaler, but its value may need to be masked
+	if t.Implements(typeMasker) || t.Implements(typeObjectMasker) || t == typeSafeValue {
+		return true
+	}
+
+	if isMarshaler(t) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return needsMask(t.Elem(), unexported, visited)
+	case reflect.Struct:
+		// recursive type is only need mask when one of its field need it
+		if visited[t] {
+			return false
+		}
+
+		visited[t] = true
+		for _, f := range jsonFields(t) {
+			if f.unexported && !unexported {
+				continue
+			}
+
+			if f.unexported || f.tagged || needsMask(t.FieldByIndex(f.index).Type, unexported, visited) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// maskObject returns fields written by ObjectMasker, so it can be nested in masked value
+func maskObject(m ObjectMasker) interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	if err := m.MarshalMaskedLogObject(enc); err != nil {
+		enc.Fields["error"] = err.Error()
+	}
+
+	return enc.Fields
+}
+
+// DefaultFieldMasker returns FieldMasker using the default options, the same as MaskString, MaskTagged and Mask.
+func DefaultFieldMasker() FieldMasker {
+	return defaultFieldMasker{}
+}
+
+type defaultFieldMasker struct{}
+
+func (defaultFieldMasker) MaskString(strategy, value string) string {
+	return MaskString(strategy, value)
+}
+
+func (defaultFieldMasker) MaskTagged(strategy string, value interface{}) interface{} {
+	return MaskTagged(strategy, value)
+}
+
+func (defaultFieldMasker) Mask(value interface{}) interface{} {
+	return Mask(value)
+}
+
+// MaskString mask value using strategy of `mask` tag value with the default options, it ignores options
+// of any logger. Value of unknown strategy is returned as is, and value of hmac and token strategy is
+// fully masked since it has no logger secret.
+func MaskString(strategy, value string) string {
+	return maskString(strategy, value, nil)
+}
+
+// MaskTagged mask value as if it is field with `mask:"<strategy>"` tag with the default options.
+func MaskTagged(strategy string, value interface{}) interface{} {
+	return (&maskWalker{opts: &defaultMaskOptions}).tagged(reflect.ValueOf(value), strategy)
+}
+
+// Mask returns masked copy of value using its `mask` tag with the default options.
+func Mask(value interface{}) interface{} {
+	return masking(value, nil)
+}
@@ -0,0 +1,321 @@
+package logger
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+type planNode struct {
+	Name string    `json:"name"`
+	Next *planNode `json:"next"`
+}
+
+type planTaggedNode struct {
+	Pin  string          `json:"pin" mask:"pin"`
+	Next *planTaggedNode `json:"next"`
+}
+
+type planMasker struct {
+	Card string
+}
+
+func (p planMasker) LogMasked() interface{} {
+	return "card ending " + p.Card[len(p.Card)-4:]
+}
+
+func TestMaskPlan(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    interface{}
+		needMask bool
+	}
+
+	testCases := []testCase{
+		{name: "string", input: "", needMask: false},
+		{name: "untagged struct", input: ProducerOptions{}, needMask: false},
+		{name: "recursive untagged struct", input: planNode{}, needMask: false},
+		{name: "recursive tagged struct", input: &planTaggedNode{}, needMask: true},
+		{name: "marshaler", input: time.Time{}, needMask: false},
+		{name: "interface", input: []interface{}{}, needMask: true},
+		{name: "tagged struct in map", input: map[string]Object{}, needMask: true},
+		{name: "masker", input: planMasker{}, needMask: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := (&maskPlanCache{}).plan(reflect.TypeOf(tc.input))
+			assert.Equal(t, tc.needMask, plan.mask)
+		})
+	}
+
+	t.Run("plan is cached", func(t *testing.T) {
+		cache := &maskPlanCache{}
+		assert.Same(t, cache.plan(reflect.TypeOf(Object{})), cache.plan(reflect.TypeOf(Object{})))
+	})
+
+	t.Run("value without mask is not copied", func(t *testing.T) {
+		input := &planNode{Name: "a", Next: &planNode{Name: "b"}}
+		assert.Same(t, input, masking(input, nil))
+	})
+
+	t.Run("masker", func(t *testing.T) {
+		assert.Equal(t, "card ending 1111", masking(planMasker{Card: "4111111111111111"}, nil))
+		assert.Equal(t, []interface{}{"card ending 1111", nil}, masking([]interface{}{planMasker{Card: "4111111111111111"}, (*planMasker)(nil)}, nil))
+	})
+}
+
+func BenchmarkMasking(b *testing.B) {
+	input := Main{
+		Object:             Object{FirstName: "Johnny", PIN: "123456", PhoneNumber: "6281297191466"},
+		MapObject:          map[string]Object{"o": {PIN: "1234"}},
+		SliceObjectPointer: []*Object{{FullName: "Johnny Depp"}},
+		SliceInteger:       []int{1, 2, 3},
+	}
+
+	untagged := []ProducerOptions{{Address: "localhost:9092"}}
+
+	// before is masking implementation before plan is cached, see legacyMaskValue
+	b.Run("before", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			legacyMaskValue(reflect.ValueOf(input))
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			masking(input, nil)
+		}
+	})
+
+	b.Run("untagged before", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			legacyMaskValue(reflect.ValueOf(untagged))
+		}
+	})
+
+	b.Run("untagged", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			masking(untagged, nil)
+		}
+	})
+}
+
+// legacyMaskValue is masking before plan is cached, kept only to benchmark against it:
+// struct fields are parsed and every value is copied on every call.
+func legacyMaskValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	if isMarshaler(v.Type()) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return v.Interface()
+		}
+
+		return legacyMaskValue(v.Elem())
+	case reflect.Struct:
+		return legacyMaskStruct(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return v.Interface()
+		}
+
+		return legacyMaskMap(v, legacyMaskValue)
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+
+		return legacyMaskElems(v, legacyMaskValue)
+	case reflect.Array:
+		return legacyMaskElems(v, legacyMaskValue)
+	}
+
+	return v.Interface()
+}
+
+func legacyMaskTagged(v reflect.Value, tag string) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	mask := func(elem reflect.Value) interface{} {
+		return legacyMaskTagged(elem, tag)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return v.Interface()
+		}
+
+		return legacyMaskTagged(v.Elem(), tag)
+	case reflect.String:
+		return maskString(tag, v.String(), nil)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return maskString(tag, strconv.FormatInt(v.Int(), 10), nil)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return maskString(tag, strconv.FormatUint(v.Uint(), 10), nil)
+	case reflect.Float32, reflect.Float64:
+		return maskString(tag, strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil)
+	case reflect.Slice:
+		if v.IsNil() {
+			return v.Interface()
+		}
+
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return []byte(sliceByteMask)
+		}
+
+		return legacyMaskElems(v, mask)
+	case reflect.Array:
+		return legacyMaskElems(v, mask)
+	case reflect.Map:
+		if v.IsNil() {
+			return v.Interface()
+		}
+
+		return legacyMaskMap(v, mask)
+	}
+
+	return legacyMaskValue(v)
+}
+
+func legacyMaskStruct(v reflect.Value) interface{} {
+	fields := jsonFields(v.Type())
+	altered := make(map[string]interface{}, len(fields))
+
+	for _, f := range fields {
+		if f.unexported {
+			continue
+		}
+
+		field, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(field)) {
+			continue
+		}
+
+		switch {
+		case f.tagged:
+			altered[f.name] = legacyMaskTagged(field, f.tag)
+		case f.quoted:
+			altered[f.name] = quoteValue(field)
+		default:
+			altered[f.name] = legacyMaskValue(field)
+		}
+	}
+
+	return altered
+}
+
+func legacyMaskElems(v reflect.Value, mask func(elem reflect.Value) interface{}) interface{} {
+	altered := make([]interface{}, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		altered[i] = mask(v.Index(i))
+	}
+
+	return altered
+}
+
+func legacyMaskMap(v reflect.Value, mask func(elem reflect.Value) interface{}) interface{} {
+	altered := make(map[string]interface{}, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, ok := mapKey(iter.Key())
+		if !ok {
+			return v.Interface()
+		}
+
+		altered[key] = mask(iter.Value())
+	}
+
+	return altered
+}
+
+type planObjectMasker struct {
+	Account string
+	Amount  int64
+}
+
+func (p *planObjectMasker) MarshalMaskedLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("account", MaskingPhoneNumber(p.Account))
+	enc.AddInt64("amount", p.Amount)
+	return nil
+}
+
+// planBothMasker implements both ObjectMasker and Masker, ObjectMasker wins
+type planBothMasker struct {
+	Pin string
+}
+
+func (p planBothMasker) MarshalMaskedLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("pin", pinMask)
+	return nil
+}
+
+func (p planBothMasker) LogMasked() interface{} {
+	return "masker"
+}
+
+func TestDefaultLogger_Masker(t *testing.T) {
+	type wrapper struct {
+		Masker *planObjectMasker `json:"masker"`
+		Card   planMasker        `json:"card"`
+	}
+
+	type bothWrapper struct {
+		Both planBothMasker `json:"both"`
+	}
+
+	type testCase struct {
+		name     string
+		mask     bool
+		input    interface{}
+		expected string
+	}
+
+	testCases := []testCase{
+		{name: "masker", mask: true, input: planMasker{Card: "4111111111111111"}, expected: `"card ending 1111"`},
+		{name: "object masker", mask: true, input: &planObjectMasker{Account: "1234567890", Amount: 10}, expected: `{"account":"XXXXXX7890","amount":10}`},
+		{name: "nested", mask: true, input: wrapper{Masker: &planObjectMasker{Account: "1234567890"}, Card: planMasker{Card: "4111111111111111"}}, expected: `{"masker":{"account":"XXXXXX7890","amount":0},"card":"card ending 1111"}`},
+		{name: "nil pointer", mask: true, input: (*planObjectMasker)(nil), expected: `null`},
+		{name: "both maskers", mask: true, input: planBothMasker{Pin: "1234"}, expected: `{"pin":"******"}`},
+		{name: "nested both maskers", mask: true, input: bothWrapper{Both: planBothMasker{Pin: "1234"}}, expected: `{"both":{"pin":"******"}}`},
+		{name: "masking disabled", mask: false, input: &planObjectMasker{Account: "1234567890", Amount: 10}, expected: `{"Account":"1234567890","Amount":10}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			writer := &testAssertionLogger{}
+			opts := []Option{WithCustomWriter(writer)}
+			if tc.mask {
+				opts = append(opts, MaskEnabled())
+			}
+
+			log, err := newLogger(opts...)
+			require.NoError(t, err)
+
+			log.Info(ctx, logMessage, ToField("data", tc.input))
+
+			var record map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(writer.GetActualData(), &record))
+			assert.JSONEq(t, tc.expected, string(record["data"]))
+		})
+	}
+}
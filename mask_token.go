@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+)
+
+const (
+	maskHMAC  = "hmac"
+	maskToken = "token"
+
+	// minMaskSecret is minimum secret length, so token cannot be brute forced by guessing the secret
+	minMaskSecret = 16
+	// hmacTokenSize is number of HMAC byte written by hmac strategy, 128 bit is enough to avoid collision
+	hmacTokenSize = 16
+)
+
+// tokenizer replace value with stable keyed token, set by WithMaskSecret
+type tokenizer struct {
+	secret []byte
+	tokens map[string]struct{} // strategy replaced by token, set by WithMaskTokenize
+}
+
+// replaces returns true when value of strategy is replaced by token instead of its masker
+func (t *tokenizer) replaces(strategy string) bool {
+	if t == nil {
+		return false
+	}
+
+	if isTokenStrategy(strategy) {
+		return true
+	}
+
+	_, ok := t.tokens[strategy]
+	return ok && isTokenizable(strategy)
+}
+
+// isTokenizable returns false for strategy which value has few possibilities, such as 6 digits PIN,
+// so its token can be reversed by trying every value
+func isTokenizable(strategy string) bool {
+	switch strategy {
+	case maskPIN, maskAny, maskBase64:
+		return false
+	}
+
+	return true
+}
+
+// MaskToken returns token written by logger configured with WithMaskSecret(secret) for value
+// with `mask:"<strategy>"` tag, so support can search log of a customer without the raw value ever stored:
+// "hmac" and "hash" returns hex of keyed hash, any other strategy (including "token") returns format-preserving token.
+// Value of phone and email strategy is normalized first, so "08123" and "+628123" has the same token.
+// See also cmd/masktoken.
+func MaskToken(secret []byte, strategy, value string) string {
+	if len(value) <= 0 {
+		return value
+	}
+
+	value = normalizeTokenValue(strategy, value)
+	if strategy == maskHMAC || strategy == maskHash {
+		return hex.EncodeToString(tokenBlock(secret, 0, value)[:hmacTokenSize])
+	}
+
+	return formatPreservingToken(secret, value)
+}
+
+func normalizeTokenValue(strategy, value string) string {
+	switch strategy {
+	case maskPhone:
+		return SanitizePhoneNumber(strings.TrimPrefix(strings.TrimSpace(value), "+"))
+	case maskEmail:
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+
+	return value
+}
+
+// formatPreservingToken replace each digit with digit and each letter with letter of the same case,
+// while other character such as "@", "." and "-" is kept, so token still looks like the original value.
+// Token is not reversible, it is derived from keyed hash of the whole value.
+func formatPreservingToken(secret []byte, value string) string {
+	runes := []rune(value)
+
+	var block []byte
+	for i, r := range runes {
+		if i%sha256.Size == 0 {
+			block = tokenBlock(secret, uint32(i/sha256.Size), value)
+		}
+
+		b := block[i%sha256.Size]
+		switch {
+		case unicode.IsDigit(r):
+			runes[i] = rune('0' + b%10)
+		case unicode.IsUpper(r):
+			runes[i] = rune('A' + b%26)
+		case unicode.IsLetter(r):
+			runes[i] = rune('a' + b%26)
+		}
+	}
+
+	return string(runes)
+}
+
+// tokenBlock returns n-th block of keyed hash of value, so value longer than one block has distinct byte
+func tokenBlock(secret []byte, n uint32, value string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+	_, _ = mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
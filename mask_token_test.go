@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var tokenSecret = []byte("0123456789abcdef")
+
+type tokenData struct {
+	CustomerID string `json:"customerId" mask:"hmac"`
+	Account    int64  `json:"account" mask:"token"`
+	Phone      string `json:"phone" mask:"phone"`
+	Email      string `json:"email" mask:"email"`
+}
+
+func TestMaskToken(t *testing.T) {
+	t.Run("hmac", func(t *testing.T) {
+		token := MaskToken(tokenSecret, maskHMAC, "cust-1")
+		assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{32}$`), token)
+		assert.Equal(t, token, MaskToken(tokenSecret, maskHMAC, "cust-1"))
+		assert.NotEqual(t, token, MaskToken(tokenSecret, maskHMAC, "cust-2"))
+		assert.NotEqual(t, token, MaskToken([]byte("fedcba9876543210"), maskHMAC, "cust-1"))
+
+		// hash is keyed the same as hmac, so it cannot be brute forced without the secret
+		assert.Equal(t, token, MaskToken(tokenSecret, maskHash, "cust-1"))
+	})
+
+	t.Run("format preserving", func(t *testing.T) {
+		token := MaskToken(tokenSecret, maskToken, "AB-1234-cd")
+		assert.Regexp(t, regexp.MustCompile(`^[A-Z]{2}-[0-9]{4}-[a-z]{2}$`), token)
+		assert.NotEqual(t, "AB-1234-cd", token)
+
+		// value longer than one hash block
+		long := "1234567890123456789012345678901234567890"
+		assert.Regexp(t, regexp.MustCompile(`^[0-9]{40}$`), MaskToken(tokenSecret, maskToken, long))
+	})
+
+	t.Run("normalized", func(t *testing.T) {
+		expected := MaskToken(tokenSecret, maskPhone, "628123456789")
+		assert.Equal(t, expected, MaskToken(tokenSecret, maskPhone, "08123456789"))
+		assert.Equal(t, expected, MaskToken(tokenSecret, maskPhone, "+628123456789"))
+		assert.Equal(t, MaskToken(tokenSecret, maskEmail, "john@doe.com"), MaskToken(tokenSecret, maskEmail, " John@Doe.com"))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		assert.Empty(t, MaskToken(tokenSecret, maskHMAC, ""))
+	})
+}
+
+func TestDefaultLogger_MaskSecret(t *testing.T) {
+	data := tokenData{
+		CustomerID: "cust-1",
+		Account:    1234567890,
+		Phone:      "08123456789",
+		Email:      "john@doe.com",
+	}
+
+	type testCase struct {
+		name     string
+		opts     []Option
+		expected tokenData
+	}
+
+	testCases := []testCase{
+		{
+			name: "without secret",
+			expected: tokenData{
+				CustomerID: "******",
+				Phone:      "XXXXXXXX6789",
+				Email:      "jo**@do****m",
+			},
+		},
+		{
+			name: "with secret",
+			opts: []Option{WithMaskSecret(tokenSecret)},
+			expected: tokenData{
+				CustomerID: MaskToken(tokenSecret, maskHMAC, "cust-1"),
+				Phone:      "XXXXXXXX6789",
+				Email:      "jo**@do****m",
+			},
+		},
+		{
+			name: "tokenize phone",
+			opts: []Option{WithMaskSecret(tokenSecret), WithMaskTokenize(maskPhone)},
+			expected: tokenData{
+				CustomerID: MaskToken(tokenSecret, maskHMAC, "cust-1"),
+				Phone:      MaskToken(tokenSecret, maskPhone, "628123456789"),
+				Email:      "jo**@do****m",
+			},
+		},
+		{
+			name: "tokenize listed strategies",
+			opts: []Option{WithMaskTokenize(maskPhone, maskEmail), WithMaskSecret(tokenSecret)},
+			expected: tokenData{
+				CustomerID: MaskToken(tokenSecret, maskHMAC, "cust-1"),
+				Phone:      MaskToken(tokenSecret, maskPhone, "08123456789"),
+				Email:      MaskToken(tokenSecret, maskEmail, "john@doe.com"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			writer := &testAssertionLogger{}
+			log, err := newLogger(append([]Option{WithCustomWriter(writer), MaskEnabled()}, tc.opts...)...)
+			require.NoError(t, err)
+
+			log.Info(ctx, logMessage, ToField("data", data))
+
+			var record struct {
+				Data map[string]interface{} `json:"data"`
+			}
+
+			require.NoError(t, json.Unmarshal([]byte(writer.GetActualData()), &record))
+			assert.Equal(t, tc.expected.CustomerID, record.Data["customerId"])
+			assert.Equal(t, tc.expected.Phone, record.Data["phone"])
+			assert.Equal(t, tc.expected.Email, record.Data["email"])
+
+			// account is number, so it is masked as string
+			account, ok := record.Data["account"].(string)
+			require.True(t, ok)
+			assert.Len(t, account, 10)
+			assert.NotEqual(t, "1234567890", account)
+		})
+	}
+
+	t.Run("mask rule", func(t *testing.T) {
+		writer := &testAssertionLogger{}
+		log, err := newLogger(WithCustomWriter(writer), MaskEnabled(), WithMaskSecret(tokenSecret),
+			WithMaskRules(MaskRule{Key: "customerId", Strategy: maskHMAC}, MaskRule{Key: "accountId", Strategy: maskHash}))
+		require.NoError(t, err)
+
+		log.Info(ctx, logMessage, ToField("data", `{"customerId":"cust-1","accountId":"acc-1"}`))
+		assert.Contains(t, string(writer.GetActualData()), MaskToken(tokenSecret, maskHMAC, "cust-1"))
+		assert.Contains(t, string(writer.GetActualData()), MaskToken(tokenSecret, maskHMAC, "acc-1"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := newLogger(WithMaskSecret([]byte("short")))
+		assert.Error(t, err)
+
+		_, err = newLogger(WithMaskTokenize(maskPhone))
+		assert.Error(t, err)
+
+		_, err = newLogger(WithMaskSecret(tokenSecret), WithMaskTokenize())
+		assert.EqualError(t, err, "mask tokenize needs at least one strategy")
+
+		for _, strategy := range []string{maskPIN, maskAny, maskBase64} {
+			_, err = newLogger(WithMaskSecret(tokenSecret), WithMaskTokenize(maskPhone, strategy))
+			assert.EqualError(t, err, "mask strategy "+strategy+" cannot be tokenized")
+		}
+	})
+}
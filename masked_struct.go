@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// maskedStruct is masked struct written as JSON object in field declaration order, the same as encoding/json
+// write the original struct
+type maskedStruct []maskedField
+
+type maskedField struct {
+	key   string
+	value interface{}
+}
+
+func (s maskedStruct) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	// zap write reflected value without escaping HTML, so does this
+	enc.SetEscapeHTML(false)
+
+	buf.WriteByte('{')
+	for i, f := range s {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		if err := encodeTrimmed(enc, &buf, f.key); err != nil {
+			return nil, err
+		}
+
+		buf.WriteByte(':')
+		if err := encodeTrimmed(enc, &buf, f.value); err != nil {
+			return nil, err
+		}
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encodeTrimmed encode v without new line written by json.Encoder
+func encodeTrimmed(enc *json.Encoder, buf *bytes.Buffer, v interface{}) error {
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
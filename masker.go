@@ -0,0 +1,232 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	maskKeep = "keep"
+	// maskHash is the same as hmac, plain hash of phone or PIN can be brute forced
+	maskHash = "hash"
+)
+
+// UnknownMaskTag is how logger handle `mask` tag value (or MaskRule strategy) without masker.
+type UnknownMaskTag int
+
+const (
+	// UnknownMaskTagIgnore write value as is, this is the default.
+	UnknownMaskTagIgnore UnknownMaskTag = iota
+	// UnknownMaskTagWarn write value as is and write warning log once for each unknown tag.
+	UnknownMaskTagWarn
+	// UnknownMaskTagFail fully mask the value, so typo in tag never leak sensitive data.
+	UnknownMaskTagFail
+)
+
+// maskFallback is called for value which tag has no masker
+type maskFallback func(tag, value string) string
+
+var (
+	maskersMu sync.RWMutex
+	maskers   = map[string]func(string) string{
+		maskPIN: func(string) string {
+			return pinMask
+		},
+		maskName: func(value string) string {
+			return MaskingName(SanitizeName(value))
+		},
+		maskPhone: func(value string) string {
+			return MaskingPhoneNumber(SanitizePhoneNumber(value))
+		},
+		maskAny: func(value string) string {
+			return strings.Repeat("*", len(value))
+		},
+		maskBase64: func(string) string {
+			return base64Mask
+		},
+		maskEmail: MaskingEmail,
+	}
+
+	// parameterized masker, used as `mask:"name=param"`
+	paramMaskers = map[string]func(param string) (func(string) string, error){
+		maskKeep: keepMasker,
+	}
+)
+
+// RegisterMasker add masking strategy which can be used as `mask` tag value or MaskRule strategy,
+// such as RegisterMasker("card", maskCard) for `mask:"card"`. It is safe to call concurrently,
+// but usually called in init. It panics when tag is empty, contains "=", fn is nil
+// or tag is already registered.
+func RegisterMasker(tag string, fn func(string) string) {
+	if tag == "" || strings.Contains(tag, "=") {
+		panic(fmt.Sprintf("logger: invalid masker tag %q", tag))
+	}
+
+	if fn == nil {
+		panic(fmt.Sprintf("logger: masker %q is nil", tag))
+	}
+
+	maskersMu.Lock()
+	defer maskersMu.Unlock()
+
+	if _, ok := maskers[tag]; ok {
+		panic(fmt.Sprintf("logger: masker %q already registered", tag))
+	}
+
+	if _, ok := paramMaskers[tag]; ok || isTokenStrategy(tag) {
+		panic(fmt.Sprintf("logger: masker %q already registered", tag))
+	}
+
+	maskers[tag] = fn
+}
+
+// lookupMasker returns masker of tag value, including parameterized tag such as "keep=last4"
+func lookupMasker(tag string) (func(string) string, bool) {
+	maskersMu.RLock()
+	fn, ok := maskers[tag]
+	maskersMu.RUnlock()
+
+	if ok {
+		return fn, true
+	}
+
+	name, param, ok := strings.Cut(tag, "=")
+	if !ok {
+		return nil, false
+	}
+
+	newMasker, ok := paramMaskers[name]
+	if !ok {
+		return nil, false
+	}
+
+	fn, err := newMasker(param)
+	if err != nil {
+		return nil, false
+	}
+
+	// cache it, so param is parsed once
+	maskersMu.Lock()
+	maskers[tag] = fn
+	maskersMu.Unlock()
+
+	return fn, true
+}
+
+// isTokenStrategy returns true for strategy which needs logger secret, so it is not in maskers
+func isTokenStrategy(tag string) bool {
+	return tag == maskHMAC || tag == maskHash || tag == maskToken
+}
+
+// keepMasker returns masker which keep first and/or last n character and mask the rest with "*",
+// param is "first6", "last4" or both separated by comma "first6,last4".
+func keepMasker(param string) (func(string) string, error) {
+	first, last := 0, 0
+	for _, p := range strings.Split(param, ",") {
+		var err error
+		switch {
+		case strings.HasPrefix(p, "first"):
+			first, err = strconv.Atoi(strings.TrimPrefix(p, "first"))
+		case strings.HasPrefix(p, "last"):
+			last, err = strconv.Atoi(strings.TrimPrefix(p, "last"))
+		default:
+			err = fmt.Errorf("unknown param %q", p)
+		}
+
+		if err != nil || first < 0 || last < 0 {
+			return nil, fmt.Errorf("invalid keep param %q", param)
+		}
+	}
+
+	return func(value string) string {
+		runes := []rune(value)
+		if first+last >= len(runes) {
+			return value
+		}
+
+		for i := first; i < len(runes)-last; i++ {
+			runes[i] = rune(CharacterAsterisk)
+		}
+
+		return string(runes)
+	}, nil
+}
+
+// ValidateMaskTags returns error listing every `mask` tag value in type of v (and its nested type)
+// which has no masker, or which is ignored since its struct implements json.Marshaler or encoding.TextMarshaler
+// and is written as is. Use it in test or on startup to catch typo before sensitive data is written.
+func ValidateMaskTags(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+
+	unknown := make(map[string]struct{})
+	ignored := make(map[string]struct{})
+	collectMaskTags(reflect.TypeOf(v), make(map[reflect.Type]bool), false, func(field, tag string, marshaler bool) {
+		if marshaler {
+			ignored[fmt.Sprintf("%s: %q", field, tag)] = struct{}{}
+		} else if _, ok := lookupMasker(tag); !ok && !isTokenStrategy(tag) {
+			unknown[fmt.Sprintf("%s: %q", field, tag)] = struct{}{}
+		}
+	})
+
+	var errs []string
+	if len(unknown) > 0 {
+		errs = append(errs, "unknown mask tag: "+sortedKeys(unknown))
+	}
+
+	if len(ignored) > 0 {
+		errs = append(errs, "mask tag of json.Marshaler or encoding.TextMarshaler is ignored: "+sortedKeys(ignored))
+	}
+
+	if len(errs) <= 0 {
+		return nil
+	}
+
+	return errors.New(strings.Join(errs, "; "))
+}
+
+func sortedKeys(set map[string]struct{}) string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return strings.Join(keys, "; ")
+}
+
+// collectMaskTags call fn for each tagged field, marshaler is true when the field is inside a marshaler,
+// since the marshaler is written as is by masking
+func collectMaskTags(t reflect.Type, visited map[reflect.Type]bool, marshaler bool, fn func(field, tag string, marshaler bool)) {
+	marshaler = marshaler || isMarshaler(t)
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		collectMaskTags(t.Elem(), visited, marshaler, fn)
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+
+	if visited[t] {
+		return
+	}
+
+	visited[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		// []byte is masked regardless tag value
+		if tag, ok := field.Tag.Lookup(maskTag); ok && field.Type != TypeSliceOfBytes {
+			fn(t.Name()+"."+field.Name, tag, marshaler)
+		}
+
+		collectMaskTags(field.Type, visited, marshaler, fn)
+	}
+}
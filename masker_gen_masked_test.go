@@ -0,0 +1,47 @@
+// Code generated by maskgen. DO NOT EDIT.
+
+package logger_test
+
+import "github.com/armiariyan/logger"
+
+var _ logger.OptionsMasker = genPayment{}
+
+// LogMasked implements logger.Masker.
+func (x genPayment) LogMasked() interface{} {
+	return x.LogMaskedWith(logger.DefaultFieldMasker())
+}
+
+// LogMaskedWith implements logger.OptionsMasker.
+func (x genPayment) LogMaskedWith(f logger.FieldMasker) interface{} {
+	m := make(map[string]interface{}, 11)
+	m["id"] = x.ID
+	m["card"] = f.MaskString("keep=first6,last4", x.Card)
+	m["account"] = f.MaskTagged("keep=last4", x.Account)
+	m["amount"] = x.Amount
+	if x.Note != "" {
+		m["note"] = x.Note
+	}
+	if len(x.Tags) != 0 {
+		m["tags"] = x.Tags
+	}
+	m["metadata"] = f.Mask(x.Metadata)
+	m["customer"] = f.Mask(x.Customer)
+	m["createdAt"] = x.CreatedAt
+	return m
+}
+
+var _ logger.OptionsMasker = genCustomer{}
+
+// LogMasked implements logger.Masker.
+func (x genCustomer) LogMasked() interface{} {
+	return x.LogMaskedWith(logger.DefaultFieldMasker())
+}
+
+// LogMaskedWith implements logger.OptionsMasker.
+func (x genCustomer) LogMaskedWith(f logger.FieldMasker) interface{} {
+	m := make(map[string]interface{}, 3)
+	m["name"] = f.MaskString("name", x.Name)
+	m["emails"] = f.MaskTagged("email", x.Emails)
+	m["phone"] = f.MaskString("hmac", x.Phone)
+	return m
+}
@@ -0,0 +1,150 @@
+package logger_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armiariyan/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:generate go run ./cmd/maskgen -type genPayment,genCustomer -o masker_gen_masked_test.go
+
+type genCustomer struct {
+	Name   string   `json:"name"   mask:"name" log:"safe"`
+	Emails []string `json:"emails" mask:"email"`
+	Phone  string   `json:"phone"  mask:"hmac"`
+}
+
+type genPayment struct {
+	ID        string            `json:"id"                                   log:"safe"`
+	Card      string            `json:"card"      mask:"keep=first6,last4" log:"safe"`
+	Account   int64             `json:"account"   mask:"keep=last4"`
+	Amount    float64           `json:"amount"                               log:"safe"`
+	Note      string            `json:"note,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	Metadata  map[string]string `json:"metadata"`
+	Customer  *genCustomer      `json:"customer"                             log:"safe"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Internal  string            `json:"-"`
+	secret    string
+}
+
+// genPaymentReflect has the same field as genPayment but without LogMasked, so it is masked using reflection
+type genPaymentReflect genPayment
+
+func newGenPayment() genPayment {
+	return genPayment{
+		ID:        "trx-1",
+		Card:      "4111111111111111",
+		Account:   1234567890,
+		Amount:    10000.5,
+		Metadata:  map[string]string{"channel": "mobile"},
+		Customer:  &genCustomer{Name: "Johnny Depp", Emails: []string{"nur@gmail.com"}, Phone: "081234567890"},
+		CreatedAt: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Internal:  "internal",
+		secret:    "secret",
+	}
+}
+
+func TestGeneratedMasker(t *testing.T) {
+	payment := newGenPayment()
+
+	generated, err := json.Marshal(logger.Mask(payment))
+	require.NoError(t, err)
+
+	reflected, err := json.Marshal(logger.Mask(genPaymentReflect(payment)))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, string(reflected), string(generated))
+	assert.JSONEq(t, `{
+		"id":"trx-1",
+		"card":"411111******1111",
+		"account":"******7890",
+		"amount":10000.5,
+		"metadata":{"channel":"mobile"},
+		"customer":{"name":"Jo***y De**","emails":["n**@gm******m"],"phone":"************"},
+		"createdAt":"2023-01-02T03:04:05Z"
+	}`, string(generated))
+}
+
+func TestGeneratedMasker_LoggerOptions(t *testing.T) {
+	secret := "generated-masker-secret"
+	conf := &logger.OptionsFile{FileLocation: filepath.Join(t.TempDir(), "sys"), Mask: true, OptionsOutput: logger.OptionsOutput{MaskSecret: secret}}
+	log := logger.SetupLoggerFile("test", conf)
+
+	payment := newGenPayment()
+	log.Info(context.Background(), "payment",
+		logger.ToField("generated", payment),
+		logger.ToField("reflected", genPaymentReflect(payment)))
+	require.NoError(t, log.Close())
+
+	content, err := os.ReadFile(conf.FileLocation)
+	require.NoError(t, err)
+
+	var line map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(content, &line))
+	assert.JSONEq(t, string(line["reflected"]), string(line["generated"]))
+
+	var generated struct {
+		Customer genCustomer `json:"customer"`
+	}
+
+	require.NoError(t, json.Unmarshal(line["generated"], &generated))
+	assert.Equal(t, logger.MaskToken([]byte(secret), "hmac", payment.Customer.Phone), generated.Customer.Phone)
+}
+
+func TestGeneratedMasker_AllowlistMode(t *testing.T) {
+	conf := &logger.OptionsFile{FileLocation: filepath.Join(t.TempDir(), "sys"), OptionsOutput: logger.OptionsOutput{AllowlistMode: true}}
+	log := logger.SetupLoggerFile("test", conf)
+
+	payment := newGenPayment()
+	payment.Note = "card 4111111111111111"
+	log.Info(context.Background(), "payment",
+		logger.ToField("generated", payment),
+		logger.ToField("reflected", genPaymentReflect(payment)))
+	require.NoError(t, log.Close())
+
+	content, err := os.ReadFile(conf.FileLocation)
+	require.NoError(t, err)
+
+	// the first line is safe fields report
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	var line map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &line))
+	assert.JSONEq(t, string(line["reflected"]), string(line["generated"]))
+	assert.JSONEq(t, `{
+		"id":"trx-1",
+		"card":"411111******1111",
+		"account":"[int64]",
+		"amount":10000.5,
+		"note":"[string]",
+		"metadata":"[map[string]string]",
+		"customer":{"name":"Jo***y De**","emails":"[[]string]","phone":"[string]"},
+		"createdAt":"[time.Time]"
+	}`, string(line["generated"]))
+}
+
+func BenchmarkMask(b *testing.B) {
+	payment := newGenPayment()
+
+	b.Run("reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.Mask(genPaymentReflect(payment))
+		}
+	})
+
+	b.Run("generated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.Mask(payment)
+		}
+	})
+}
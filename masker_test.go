@@ -0,0 +1,178 @@
+package logger
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	RegisterMasker("card", func(value string) string {
+		return MaskExceptLastNthCharacter(value, 4, CharacterAsterisk)
+	})
+}
+
+// linesWriter keep all lines written by logger
+type linesWriter struct {
+	lines []string
+}
+
+func (w *linesWriter) Write(p []byte) (n int, err error) {
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+func (w *linesWriter) Close() error {
+	return nil
+}
+
+type maskerData struct {
+	Card    string `json:"card" mask:"card"`
+	Account string `json:"account" mask:"keep=first2,last3"`
+	Last4   string `json:"last4" mask:"keep=last4"`
+	Secret  string `json:"secret" mask:"hash"`
+	Typo    string `json:"typo" mask:"pni"`
+}
+
+type maskerNested struct {
+	Data  *maskerData   `json:"data"`
+	Items []maskerData  `json:"items"`
+	Self  *maskerNested `json:"self"`
+	Bad   string        `json:"bad" mask:"keep=middle"`
+}
+
+func TestRegisterMasker(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		assert.Panics(t, func() { RegisterMasker("", strings.ToUpper) })
+		assert.Panics(t, func() { RegisterMasker("a=b", strings.ToUpper) })
+		assert.Panics(t, func() { RegisterMasker("upper", nil) })
+		assert.Panics(t, func() { RegisterMasker(maskPIN, strings.ToUpper) })
+		assert.Panics(t, func() { RegisterMasker("card", strings.ToUpper) })
+		assert.Panics(t, func() { RegisterMasker(maskKeep, strings.ToUpper) })
+		assert.Panics(t, func() { RegisterMasker(maskHMAC, strings.ToUpper) })
+		assert.Panics(t, func() { RegisterMasker(maskHash, strings.ToUpper) })
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+		type testCase struct {
+			tag      string
+			value    string
+			expected string
+			unknown  bool
+		}
+
+		testCases := []testCase{
+			{tag: "card", value: "4111111111111111", expected: "************1111"},
+			{tag: "keep=last4", value: "1234567890", expected: "******7890"},
+			{tag: "keep=first6,last4", value: "4111111111111111", expected: "411111******1111"},
+			{tag: "keep=last4", value: "123", expected: "123"},
+			{tag: "keep=last", unknown: true},
+			{tag: "keep=middle", unknown: true},
+			{tag: "unknown", unknown: true},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.tag, func(t *testing.T) {
+				fn, ok := lookupMasker(tc.tag)
+				if tc.unknown {
+					assert.False(t, ok)
+					return
+				}
+
+				require.True(t, ok)
+				assert.Equal(t, tc.expected, fn(tc.value))
+			})
+		}
+	})
+}
+
+func TestValidateMaskTags(t *testing.T) {
+	assert.NoError(t, ValidateMaskTags(nil))
+	assert.NoError(t, ValidateMaskTags(LogTdrModel{}))
+
+	err := ValidateMaskTags(&maskerNested{})
+	require.Error(t, err)
+	assert.Equal(t, `unknown mask tag: maskerData.Typo: "pni"; maskerNested.Bad: "keep=middle"`, err.Error())
+
+	t.Run("marshaler", func(t *testing.T) {
+		err := ValidateMaskTags(struct {
+			Data  maskerMarshaler   `json:"data"`
+			Items []maskerMarshaler `json:"items"`
+		}{})
+		require.Error(t, err)
+		assert.Equal(t, `mask tag of json.Marshaler or encoding.TextMarshaler is ignored: maskerMarshaler.PIN: "pin"`, err.Error())
+	})
+}
+
+// maskerMarshaler is written as is, so its mask tag is ignored
+type maskerMarshaler struct {
+	PIN string `json:"pin" mask:"pin"`
+}
+
+func (m maskerMarshaler) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"pin": m.PIN})
+}
+
+func TestDefaultLogger_UnknownMaskTag(t *testing.T) {
+	data := maskerData{
+		Card:    "4111111111111111",
+		Account: "1234567890",
+		Last4:   "1234567890",
+		Secret:  "secret",
+		Typo:    "123456",
+	}
+
+	type testCase struct {
+		name         string
+		policy       UnknownMaskTag
+		expectedTypo string
+		expectedWarn bool
+	}
+
+	testCases := []testCase{
+		{name: "ignore", policy: UnknownMaskTagIgnore, expectedTypo: "123456"},
+		{name: "warn", policy: UnknownMaskTagWarn, expectedTypo: "123456", expectedWarn: true},
+		{name: "fail", policy: UnknownMaskTagFail, expectedTypo: "******"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			writer := &linesWriter{}
+			log, err := newLogger(WithCustomWriter(writer), MaskEnabled(), WithUnknownMaskTag(tc.policy))
+			require.NoError(t, err)
+
+			log.Info(ctx, logMessage, ToField("data", data))
+			log.Info(ctx, logMessage, ToField("data", data))
+
+			var record struct {
+				Data maskerData `json:"data"`
+			}
+
+			require.NoError(t, json.Unmarshal([]byte(writer.lines[len(writer.lines)-1]), &record))
+			assert.Equal(t, maskerData{
+				Card:    "************1111",
+				Account: "12*****890",
+				Last4:   "******7890",
+				Secret:  "******", // no mask secret, so it is never written as plain hash
+				Typo:    tc.expectedTypo,
+			}, record.Data)
+
+			// warning is written once for each tag
+			warnings := 0
+			for _, line := range writer.lines {
+				if strings.Contains(line, "unknown mask tag") {
+					warnings++
+				}
+			}
+
+			if tc.expectedWarn {
+				assert.Equal(t, 1, warnings)
+			} else {
+				assert.Zero(t, warnings)
+			}
+		})
+	}
+}
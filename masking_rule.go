@@ -0,0 +1,273 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaskRule mask value of JSON object, such as raw JSON request string or proto message,
+// where `mask` tag cannot be used.
+//
+// Key is either key name matched anywhere in the object, compared case-insensitively and ignoring
+// "_" and "-" (so "phoneNumber" also match "phone_number"), or JSONPath when started with "$".
+// Supported JSONPath syntax: "$.data.pin", "$..pin" (any depth), "$.items[*].card", "$.items[0].card" and "$.data.*".
+// When matched value is an object or array, every value inside it is masked using the strategy.
+//
+// Strategy is the same as `mask` tag value: pin, name, phone, any, base64, email, hash, keep=last4
+// or masker added by RegisterMasker.
+type MaskRule struct {
+	Key      string `json:"key"`
+	Strategy string `json:"strategy"`
+}
+
+// DefaultMaskRules is applied to JSON object when masking is enabled, before rules added by WithMaskRules.
+var DefaultMaskRules = []MaskRule{
+	{Key: "pin", Strategy: maskPIN},
+	{Key: "password", Strategy: maskPIN},
+	{Key: "otp", Strategy: maskPIN},
+	{Key: "phone", Strategy: maskPhone},
+	{Key: "phoneNumber", Strategy: maskPhone},
+	{Key: "mobile", Strategy: maskPhone},
+	{Key: "msisdn", Strategy: maskPhone},
+	{Key: "email", Strategy: maskEmail},
+	{Key: "fullName", Strategy: maskName},
+	{Key: "customerName", Strategy: maskName},
+}
+
+type compiledMaskRule struct {
+	key      string      // normalized key name, empty when using path
+	path     []pathToken // JSONPath without "$"
+	strategy string
+}
+
+type pathToken struct {
+	recursive bool   // ".." descendant
+	name      string // key name or "*" wildcard
+	index     int    // array index, -1 for any index
+	isIndex   bool
+}
+
+func compileMaskRules(rules []MaskRule) ([]compiledMaskRule, error) {
+	compiled := make([]compiledMaskRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Key == "" {
+			return nil, fmt.Errorf("mask rule key is empty")
+		}
+
+		if !strings.HasPrefix(rule.Key, "$") {
+			compiled = append(compiled, compiledMaskRule{key: normalizeKey(rule.Key), strategy: rule.Strategy})
+			continue
+		}
+
+		path, err := parseJSONPath(rule.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		compiled = append(compiled, compiledMaskRule{path: path, strategy: rule.Strategy})
+	}
+
+	return compiled, nil
+}
+
+func normalizeKey(key string) string {
+	key = strings.ToLower(key)
+	key = strings.ReplaceAll(key, "_", "")
+	return strings.ReplaceAll(key, "-", "")
+}
+
+func parseJSONPath(path string) ([]pathToken, error) {
+	rest := strings.TrimPrefix(path, "$")
+	tokens := make([]pathToken, 0)
+
+	for len(rest) > 0 {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			rest = rest[2:]
+			name, remaining := readName(rest)
+			if name == "" {
+				return nil, fmt.Errorf("invalid mask rule path %q: empty key after ..", path)
+			}
+
+			tokens = append(tokens, pathToken{recursive: true, name: name})
+			rest = remaining
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			name, remaining := readName(rest)
+			if name == "" {
+				return nil, fmt.Errorf("invalid mask rule path %q: empty key", path)
+			}
+
+			tokens = append(tokens, pathToken{name: name})
+			rest = remaining
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid mask rule path %q: missing ]", path)
+			}
+
+			inside := strings.Trim(rest[1:end], `'"`)
+			rest = rest[end+1:]
+
+			if inside == "*" {
+				tokens = append(tokens, pathToken{isIndex: true, index: -1})
+				continue
+			}
+
+			if index, err := strconv.Atoi(inside); err == nil {
+				tokens = append(tokens, pathToken{isIndex: true, index: index})
+				continue
+			}
+
+			// bracket notation of key, such as $['data']
+			tokens = append(tokens, pathToken{name: inside})
+		default:
+			return nil, fmt.Errorf("invalid mask rule path %q at %q", path, rest)
+		}
+	}
+
+	if len(tokens) <= 0 {
+		return nil, fmt.Errorf("invalid mask rule path %q: path is empty", path)
+	}
+
+	return tokens, nil
+}
+
+func readName(path string) (name, rest string) {
+	end := strings.IndexAny(path, ".[")
+	if end < 0 {
+		return path, ""
+	}
+
+	return path[:end], path[end:]
+}
+
+// pathSegment is one step from root to the value, either key of object or index of array
+type pathSegment struct {
+	key   string
+	index int
+}
+
+func (r compiledMaskRule) match(path []pathSegment) bool {
+	if r.key != "" {
+		return len(path) > 0 && path[len(path)-1].key != "" && normalizeKey(path[len(path)-1].key) == r.key
+	}
+
+	return matchPath(r.path, path)
+}
+
+func matchPath(tokens []pathToken, path []pathSegment) bool {
+	if len(tokens) <= 0 {
+		return len(path) <= 0
+	}
+
+	token := tokens[0]
+	if token.recursive {
+		// ".." can skip any number of segments before the key
+		for i := 0; i < len(path); i++ {
+			if matchSegment(token, path[i]) && matchPath(tokens[1:], path[i+1:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(path) <= 0 || !matchSegment(token, path[0]) {
+		return false
+	}
+
+	return matchPath(tokens[1:], path[1:])
+}
+
+func matchSegment(token pathToken, segment pathSegment) bool {
+	if token.isIndex {
+		return segment.key == "" && (token.index < 0 || token.index == segment.index)
+	}
+
+	return segment.key != "" && (token.name == "*" || token.name == segment.key)
+}
+
+// maskJSON returns copy of JSON value (result of json.Unmarshal into interface{}) where value
+// matched by rules is masked. Original value is never modified.
+func maskJSON(data interface{}, rules []compiledMaskRule, opts *maskOptions) interface{} {
+	if len(rules) <= 0 {
+		return data
+	}
+
+	return maskJSONValue(data, make([]pathSegment, 0, 8), rules, opts)
+}
+
+func maskJSONValue(data interface{}, path []pathSegment, rules []compiledMaskRule, opts *maskOptions) interface{} {
+	switch data.(type) {
+	case map[string]interface{}, []interface{}:
+		// object or array under sensitive key, such as list of phone, is masked entirely
+		for _, rule := range rules {
+			if rule.match(path) {
+				return maskJSONTree(data, rule.strategy, opts)
+			}
+		}
+	}
+
+	switch val := data.(type) {
+	case map[string]interface{}:
+		altered := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			altered[k] = maskJSONValue(v, append(path, pathSegment{key: k}), rules, opts)
+		}
+
+		return altered
+	case []interface{}:
+		altered := make([]interface{}, len(val))
+		for i, v := range val {
+			altered[i] = maskJSONValue(v, append(path, pathSegment{index: i}), rules, opts)
+		}
+
+		return altered
+	case nil:
+		return nil
+	}
+
+	for _, rule := range rules {
+		if rule.match(path) {
+			return maskJSONScalar(data, rule.strategy, opts)
+		}
+	}
+
+	return data
+}
+
+// maskJSONTree mask every scalar of object or array using strategy
+func maskJSONTree(data interface{}, strategy string, opts *maskOptions) interface{} {
+	switch val := data.(type) {
+	case map[string]interface{}:
+		altered := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			altered[k] = maskJSONTree(v, strategy, opts)
+		}
+
+		return altered
+	case []interface{}:
+		altered := make([]interface{}, len(val))
+		for i, v := range val {
+			altered[i] = maskJSONTree(v, strategy, opts)
+		}
+
+		return altered
+	case nil:
+		return nil
+	}
+
+	return maskJSONScalar(data, strategy, opts)
+}
+
+func maskJSONScalar(data interface{}, strategy string, opts *maskOptions) interface{} {
+	// number and boolean is masked as string, since masked value is no longer a number
+	str, ok := data.(string)
+	if !ok {
+		str = fmt.Sprint(data)
+	}
+
+	return maskString(strategy, str, opts)
+}
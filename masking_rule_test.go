@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskJSON(t *testing.T) {
+	type testCase struct {
+		name     string
+		rules    []MaskRule
+		input    string
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "no rules",
+			input:    `{"pin":"123456"}`,
+			expected: `{"pin":"123456"}`,
+		},
+		{
+			name:     "key rule at root",
+			rules:    []MaskRule{{Key: "pin", Strategy: maskPIN}},
+			input:    `{"pin":"123456","amount":1000}`,
+			expected: `{"pin":"******","amount":1000}`,
+		},
+		{
+			name:     "key rule is case insensitive and ignore separator",
+			rules:    []MaskRule{{Key: "phoneNumber", Strategy: maskPhone}},
+			input:    `{"PhoneNumber":"08000000111","phone_number":"08000000111","phone-number":"08000000111"}`,
+			expected: `{"PhoneNumber":"XXXXXXXX0111","phone_number":"XXXXXXXX0111","phone-number":"XXXXXXXX0111"}`,
+		},
+		{
+			name:     "key rule at any depth and inside array",
+			rules:    []MaskRule{{Key: "email", Strategy: maskEmail}},
+			input:    `{"data":{"users":[{"email":"nur@gmail.com"},{"email":"matt@gmail.com"}]}}`,
+			expected: `{"data":{"users":[{"email":"n**@gm******m"},{"email":"ma**@gm******m"}]}}`,
+		},
+		{
+			name:     "number and boolean masked as string",
+			rules:    []MaskRule{{Key: "pin", Strategy: maskAny}, {Key: "verified", Strategy: maskAny}},
+			input:    `{"pin":123456,"verified":true}`,
+			expected: `{"pin":"******","verified":"****"}`,
+		},
+		{
+			name:     "null and empty value is kept",
+			rules:    []MaskRule{{Key: "pin", Strategy: maskPIN}, {Key: "password", Strategy: maskPIN}},
+			input:    `{"pin":null,"password":""}`,
+			expected: `{"pin":null,"password":""}`,
+		},
+		{
+			name:     "object under key is masked entirely",
+			rules:    []MaskRule{{Key: "password", Strategy: maskPIN}},
+			input:    `{"password":{"old":"x","new":"y"}}`,
+			expected: `{"password":{"old":"******","new":"******"}}`,
+		},
+		{
+			name:     "array under key is masked entirely",
+			rules:    DefaultMaskRules,
+			input:    `{"phone":["081234567890"],"pin":{"old":"1234","new":"5678"}}`,
+			expected: `{"phone":["XXXXXXXXX7890"],"pin":{"old":"******","new":"******"}}`,
+		},
+		{
+			name:     "nested array and object under json path",
+			rules:    []MaskRule{{Key: "$.data.cards", Strategy: maskAny}},
+			input:    `{"data":{"cards":[{"number":"4111","cvv":123},["5500"]],"amount":1000}}`,
+			expected: `{"data":{"cards":[{"number":"****","cvv":"***"},["****"]],"amount":1000}}`,
+		},
+		{
+			name:     "array of string under key",
+			rules:    []MaskRule{{Key: "$.phones[*]", Strategy: maskPhone}},
+			input:    `{"phones":["08000000111","08000000222"]}`,
+			expected: `{"phones":["XXXXXXXX0111","XXXXXXXX0222"]}`,
+		},
+		{
+			name:     "json path from root",
+			rules:    []MaskRule{{Key: "$.data.name", Strategy: maskName}},
+			input:    `{"name":"Johnny Depp","data":{"name":"Johnny Depp","child":{"name":"Johnny Depp"}}}`,
+			expected: `{"name":"Johnny Depp","data":{"name":"Jo***y De**","child":{"name":"Johnny Depp"}}}`,
+		},
+		{
+			name:     "json path recursive descent",
+			rules:    []MaskRule{{Key: "$..card", Strategy: maskAny}},
+			input:    `{"card":"1234","data":{"items":[{"card":"5678"}]}}`,
+			expected: `{"card":"****","data":{"items":[{"card":"****"}]}}`,
+		},
+		{
+			name:     "json path array index and wildcard key",
+			rules:    []MaskRule{{Key: "$.items[1].*", Strategy: maskAny}},
+			input:    `{"items":[{"a":"xx","b":"yy"},{"a":"xx","b":"yy"}]}`,
+			expected: `{"items":[{"a":"xx","b":"yy"},{"a":"**","b":"**"}]}`,
+		},
+		{
+			name:     "json path bracket notation",
+			rules:    []MaskRule{{Key: "$['data']['pin']", Strategy: maskPIN}},
+			input:    `{"data":{"pin":"123456"}}`,
+			expected: `{"data":{"pin":"******"}}`,
+		},
+		{
+			name:     "root array",
+			rules:    []MaskRule{{Key: "$[*].pin", Strategy: maskPIN}},
+			input:    `[{"pin":"123456"},{"pin":"654321"}]`,
+			expected: `[{"pin":"******"},{"pin":"******"}]`,
+		},
+		{
+			name:     "unknown strategy keep value",
+			rules:    []MaskRule{{Key: "pin", Strategy: "unknown"}},
+			input:    `{"pin":"123456"}`,
+			expected: `{"pin":"123456"}`,
+		},
+		{
+			name:     "first matched rule win",
+			rules:    []MaskRule{{Key: "$.data.pin", Strategy: maskAny}, {Key: "pin", Strategy: maskPIN}},
+			input:    `{"pin":"1234","data":{"pin":"1234"}}`,
+			expected: `{"pin":"******","data":{"pin":"****"}}`,
+		},
+		{
+			name:     "default rules",
+			rules:    DefaultMaskRules,
+			input:    `{"pin":"123456","password":"secret","mobile":"6281297191466","email":"nur@gmail.com","title":"INFO"}`,
+			expected: `{"pin":"******","password":"******","mobile":"XXXXXXXXX1466","email":"n**@gm******m","title":"INFO"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := compileMaskRules(tc.rules)
+			require.NoError(t, err)
+
+			var input interface{}
+			require.NoError(t, json.Unmarshal([]byte(tc.input), &input))
+
+			inputCopy, err := json.Marshal(input)
+			require.NoError(t, err)
+
+			actual, err := json.Marshal(maskJSON(input, rules, nil))
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expected, string(actual))
+
+			// original value must not be modified
+			after, err := json.Marshal(input)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(inputCopy), string(after))
+		})
+	}
+}
+
+func TestCompileMaskRules(t *testing.T) {
+	invalid := []string{"", "$", "$.", "$..", "$.data[0", "$data"}
+	for _, key := range invalid {
+		t.Run(key, func(t *testing.T) {
+			_, err := compileMaskRules([]MaskRule{{Key: key, Strategy: maskAny}})
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("option return error", func(t *testing.T) {
+		log, err := newLogger(WithMaskRules(MaskRule{Key: "$data"}))
+		assert.Nil(t, log)
+		assert.Error(t, err)
+	})
+}
+
+func TestDefaultLogger_MaskRules(t *testing.T) {
+	writer := &testAssertionLogger{}
+	log, err := newLogger(
+		MaskEnabled(),
+		WithCustomWriter(writer),
+		WithMaskRules(
+			MaskRule{Key: "$.account.number", Strategy: maskAny},
+			MaskRule{Key: "text", Strategy: maskAny},
+		),
+	)
+	require.NoError(t, err)
+
+	log.Info(ctx, logMessage,
+		ToField("json_string", `{"pin":"123456","account":{"number":"1234"}}`),
+		ToField("json_map", map[string]interface{}{"password": "secret", "account": map[string]interface{}{"number": "1234"}}),
+		ToField("proto", &protoMessage{Text: "lorem"}),
+	)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(writer.GetActualData(), &record))
+	assert.EqualValues(t, map[string]interface{}{"pin": "******", "account": map[string]interface{}{"number": "****"}}, record["json_string"])
+	assert.EqualValues(t, map[string]interface{}{"password": "******", "account": map[string]interface{}{"number": "****"}}, record["json_map"])
+	assert.EqualValues(t, map[string]interface{}{"Text": "*****"}, record["proto"])
+}
@@ -1,20 +1,26 @@
 package logger
 
+//go:generate go run ./cmd/tdrschema -o schema/tdr.schema.json
+
+// TdrSchemaVersion is version of TDR record written as "schemaVersion",
+// increase it whenever field of LogTdrModel is added, removed or changed.
+const TdrSchemaVersion = "2"
+
 // LogTdrModel or Transaction Data Record
 type LogTdrModel struct {
-	AppName    string `json:"app"`
+	AppName    string `json:"app" validate:"required"`
 	AppVersion string `json:"ver"`
-	ThreadID   string `json:"xid"`
+	ThreadID   string `json:"xid" validate:"required"`
 	JourneyID  string `json:"jid"`
 	ChainID    string `json:"cid"`
 
-	Path         string `json:"path"`
+	Path         string `json:"path" validate:"required"`
 	Method       string `json:"method"`
 	IP           string `json:"ip"`
 	Port         int    `json:"port"`
 	SrcIP        string `json:"srcIP"`
 	RespTime     int64  `json:"rt"`
-	ResponseCode string `json:"rc"`
+	ResponseCode string `json:"rc" validate:"required"`
 
 	Header   interface{} `json:"header"` // better to pass data here as is, don't cast it to string. use map or array
 	Request  interface{} `json:"req"`
@@ -22,4 +28,33 @@ type LogTdrModel struct {
 	Error    string      `json:"error"`
 
 	AdditionalData interface{} `json:"addData"`
+
+	// Direction is TdrInbound for request received by the service, or TdrOutbound for call to other service
+	Direction string `json:"direction,omitempty"`
+}
+
+// LogAuditModel is audit trail record of "audit" stream, who did what to which resource.
+// Before, After and AdditionalData is masked the same as TDR request.
+type LogAuditModel struct {
+	ActorID    string `json:"actorId"`
+	ActorType  string `json:"actorType"` // such as user, admin or service
+	Action     string `json:"action"`    // such as account.update
+	TargetType string `json:"targetType"`
+	TargetID   string `json:"targetId"`
+	Outcome    string `json:"outcome"` // such as success or failure
+	Reason     string `json:"reason"`
+	SrcIP      string `json:"srcIP"`
+	ThreadID   string `json:"xid"` // filled from context when it is empty
+
+	Before         interface{} `json:"before"`
+	After          interface{} `json:"after"`
+	AdditionalData interface{} `json:"addData"`
+}
+
+// LogEventModel is business event record of "event" stream, such as order.paid
+type LogEventModel struct {
+	Name       string      `json:"event"`
+	EntityType string      `json:"entityType"`
+	EntityID   string      `json:"entityId"`
+	Properties interface{} `json:"props"`
 }
@@ -1,13 +1,13 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"strings"
+	"reflect"
 	"time"
 
-	"github.com/Shopify/sarama"
 	"github.com/go-playground/validator/v10"
 	rotateLogs "github.com/lestrrat-go/file-rotatelogs"
 )
@@ -28,6 +28,163 @@ func MaskEnabled() Option {
 	}
 }
 
+// WithMaskRules add rules to mask JSON object by key name or JSONPath, applied after DefaultMaskRules
+// when masking is enabled. See MaskRule.
+func WithMaskRules(rules ...MaskRule) Option {
+	return func(logger *defaultLogger) error {
+		compiled, err := compileMaskRules(rules)
+		if err != nil {
+			return fmt.Errorf("mask rules error: %w", err)
+		}
+
+		logger.maskRules = append(logger.maskRules, compiled...)
+		return nil
+	}
+}
+
+// WithUnknownMaskTag set how to handle `mask` tag value or MaskRule strategy which has no masker,
+// default is UnknownMaskTagIgnore. See also ValidateMaskTags to check it on startup.
+func WithUnknownMaskTag(policy UnknownMaskTag) Option {
+	return func(logger *defaultLogger) error {
+		logger.unknownMaskTag = policy
+		return nil
+	}
+}
+
+// WithUnexportedFields set whether unexported struct field is written when masking is enabled,
+// default is UnexportedOmit which is the same as encoding/json.
+func WithUnexportedFields(policy UnexportedFields) Option {
+	return func(logger *defaultLogger) error {
+		logger.maskOpts.unexported = policy
+		return nil
+	}
+}
+
+// WithMaskLimits limit nested depth and number of element of each slice, array and map walked by masking,
+// so large or deeply nested value does not slow down logging. The rest is replaced by marker such as
+// "[max depth]" or "[10 more]". Zero is unlimited, default max depth is 32 and elements is unlimited.
+func WithMaskLimits(maxDepth, maxElems int) Option {
+	return func(logger *defaultLogger) error {
+		if maxDepth < 0 || maxElems < 0 {
+			return fmt.Errorf("mask limits must not be negative")
+		}
+
+		logger.maskOpts.maxDepth = maxDepth
+		logger.maskOpts.maxElems = maxElems
+		return nil
+	}
+}
+
+// WithMaskSecret set secret used by `mask:"hmac"` and `mask:"token"` strategy (see MaskToken),
+// so the same value always has the same token and log of a customer can be correlated.
+// Without it, value of such strategy is fully masked. Secret must be at least 16 bytes.
+func WithMaskSecret(secret []byte) Option {
+	return func(logger *defaultLogger) error {
+		if len(secret) < minMaskSecret {
+			return fmt.Errorf("mask secret must be at least %d bytes", minMaskSecret)
+		}
+
+		if logger.maskOpts.tokenizer == nil {
+			logger.maskOpts.tokenizer = &tokenizer{}
+		}
+
+		logger.maskOpts.tokenizer.secret = append([]byte(nil), secret...)
+		return nil
+	}
+}
+
+// WithMaskTokenize replace value of given masking strategy, such as "phone" and "email", with
+// format-preserving token instead of partially masked value. Strategy must be listed explicitly,
+// and pin, any and base64 cannot be tokenized since their token can be brute forced by anyone holding the secret.
+// It needs WithMaskSecret.
+func WithMaskTokenize(strategies ...string) Option {
+	return func(logger *defaultLogger) error {
+		if len(strategies) <= 0 {
+			return errors.New("mask tokenize needs at least one strategy")
+		}
+
+		for _, strategy := range strategies {
+			if !isTokenizable(strategy) {
+				return fmt.Errorf("mask strategy %s cannot be tokenized", strategy)
+			}
+		}
+
+		if logger.maskOpts.tokenizer == nil {
+			logger.maskOpts.tokenizer = &tokenizer{}
+		}
+
+		t := logger.maskOpts.tokenizer
+		if t.tokens == nil {
+			t.tokens = make(map[string]struct{}, len(strategies))
+		}
+
+		for _, strategy := range strategies {
+			t.tokens[strategy] = struct{}{}
+		}
+
+		return nil
+	}
+}
+
+// WithAllowlistMode write only struct field declared safe, using `log:"safe"` tag or RegisterSafeFields,
+// and replace everything else with its type such as "[string]", for Field value and TDR request, response
+// and additional data. Value which is not a struct, including JSON string, must be wrapped using Safe.
+// It also enables masking, so safe field is still masked by its `mask` tag, and value returned by Masker or
+// ObjectMasker is walked the same way. Safe fields of given types
+// and registered types are written in startup log for review.
+func WithAllowlistMode(types ...interface{}) Option {
+	return func(logger *defaultLogger) error {
+		logger.maskEnabled = true
+		logger.maskOpts.allowlist = true
+		for _, t := range types {
+			if t == nil {
+				return fmt.Errorf("allowlist type must not be nil")
+			}
+
+			logger.allowlistTypes = append(logger.allowlistTypes, reflect.TypeOf(t))
+		}
+
+		return nil
+	}
+}
+
+// WithHeaderDenylist add header which value is redacted in TDR, in addition to DefaultRedactedHeaders.
+// Header name is compared case-insensitively.
+func WithHeaderDenylist(headers ...string) Option {
+	return func(logger *defaultLogger) error {
+		logger.headers.deny(headers...)
+		return nil
+	}
+}
+
+// WithHeaderAllowlist set header which value is written as is in TDR, value of other header is redacted.
+// Header in denylist is still redacted even if it is listed here.
+func WithHeaderAllowlist(headers ...string) Option {
+	return func(logger *defaultLogger) error {
+		logger.headers.allow(headers...)
+		return nil
+	}
+}
+
+// WithScrubber find and replace sensitive data in log message, TDR error and string field
+// using detectors, or DefaultDetectors when none is given. It works regardless MaskEnabled.
+func WithScrubber(detectors ...Detector) Option {
+	return func(logger *defaultLogger) error {
+		if len(detectors) <= 0 {
+			detectors = DefaultDetectors
+		}
+
+		for _, d := range detectors {
+			if d.Pattern == nil || d.Replace == nil {
+				return fmt.Errorf("scrubber detector %q must have pattern and replace function", d.Name)
+			}
+		}
+
+		logger.scrubber = newScrubber(detectors)
+		return nil
+	}
+}
+
 func WithStdout() Option {
 	return func(logger *defaultLogger) error {
 		// Wire STD output for both type
@@ -43,6 +200,17 @@ func WithFileOutput(conf *OptionsFile) Option {
 			return fmt.Errorf("config for file output error: %w", err)
 		}
 
+		if conf.HashChain || conf.KeyProvider != nil || conf.EncryptKeyEnv != "" || conf.EncryptKeyFile != "" {
+			output, err := newFileOutput(conf, time.Now)
+			if err != nil {
+				return fmt.Errorf("sys file error: %w", err)
+			}
+
+			logger.writers = append(logger.writers, output)
+			logger.closer = append(logger.closer, output)
+			return nil
+		}
+
 		outputSys, err := rotateLogs.New(
 			conf.FileLocation+".%Y%m%d",
 			rotateLogs.WithLinkName(conf.FileLocation),
@@ -61,57 +229,118 @@ func WithFileOutput(conf *OptionsFile) Option {
 	}
 }
 
-type wrapKafkaWriter struct {
-	topic    string
-	producer sarama.SyncProducer
+// WithKafkaOutput can be called multiple times to add functionality
+// where we want to broadcast log into different kafka cluster
+// or topic. Log is sent in batch, see WithQueueOutput.
+func WithKafkaOutput(conf *OptionsQueue) Option {
+	return func(logger *defaultLogger) error {
+		kafkaConf := *conf
+		kafkaConf.Type = QueueTypeKafka
+		return WithQueueOutput(&kafkaConf)(logger)
+	}
 }
 
-func (w *wrapKafkaWriter) Write(p []byte) (n int, err error) {
-	_, _, err = w.producer.SendMessage(&sarama.ProducerMessage{
-		Topic: w.topic,
-		Key:   sarama.StringEncoder(fmt.Sprint(time.Now().UTC())),
-		Value: sarama.ByteEncoder(p),
+// WithQueueOutput send log into message broker based on conf.Type, which is kafka, NATS JetStream,
+// RabbitMQ or Redis Streams. Every broker send log in batch on background, failed record is retried with
+// backoff and log is dropped when buffer is full so application is never blocked. Close send the remaining log.
+func WithQueueOutput(conf *OptionsQueue) Option {
+	return func(logger *defaultLogger) error {
+		err := validator.New().Struct(conf)
+		if err != nil {
+			return fmt.Errorf("config for queue output error: %w", err)
+		}
 
-		// Below this point are filled in by the producer as the message is processed
-		Offset:    0,
-		Partition: 0,
-		Timestamp: time.Time{},
-	})
+		queueWriter, err := newQueueWriter(conf)
+		if err != nil {
+			return fmt.Errorf("%s writer error: %w", conf.Type, err)
+		}
 
-	return
+		logger.writers = append(logger.writers, queueWriter)
+		logger.closer = append(logger.closer, queueWriter)
+		return nil
+	}
 }
 
-var _ io.Writer = (*wrapKafkaWriter)(nil)
+// WithSyslogOutput write each log as RFC 5424 message into syslog server over udp, tcp, tls or unix socket,
+// global log field such as thread ID is also written as structured data (see DefaultSyslogStructuredData).
+// Message is sent on background, failed message is retried with backoff and log is dropped when buffer is full
+// so application is never blocked. Close send the remaining log. It can be called multiple times to write into different server.
+func WithSyslogOutput(conf *OptionsSyslog) Option {
+	return func(logger *defaultLogger) error {
+		err := validator.New().Struct(conf)
+		if err != nil {
+			return fmt.Errorf("config for syslog output error: %w", err)
+		}
 
-// WithKafkaOutput can be called multiple times to add functionality
-// where we want to broadcast log into different kafka cluster
-// or topic.
-func WithKafkaOutput(conf *OptionsQueue) Option {
+		syslogWriter, err := newSyslogWriter(conf)
+		if err != nil {
+			return fmt.Errorf("syslog writer error: %w", err)
+		}
+
+		logger.writers = append(logger.writers, syslogWriter)
+		logger.closer = append(logger.closer, syslogWriter)
+		return nil
+	}
+}
+
+// WithHTTPBulkOutput send log in batch as NDJSON into Elasticsearch or OpenSearch bulk API, using index
+// sys-YYYY.MM.DD and tdr-YYYY.MM.DD based on logType, or into generic endpoint using ndjson format.
+// Failed batch is retried with backoff, log is dropped when buffer is full so application is never blocked.
+// Close send the remaining log. It can be called multiple times to send into different endpoint.
+func WithHTTPBulkOutput(conf *OptionsHTTPBulk) Option {
+	return func(logger *defaultLogger) error {
+		err := validator.New().Struct(conf)
+		if err != nil {
+			return fmt.Errorf("config for http bulk output error: %w", err)
+		}
+
+		bulkWriter := newHTTPBulkWriter(conf)
+		logger.writers = append(logger.writers, bulkWriter)
+		logger.closer = append(logger.closer, bulkWriter)
+		return nil
+	}
+}
+
+// WithLokiOutput push log in batch into Grafana Loki, low cardinality field such as level and logType
+// is sent as stream label (see DefaultLokiLabels) while the rest is kept in the line.
+// Batch rejected by rate limit is retried with backoff, log is dropped when buffer is full so application
+// is never blocked. Close send the remaining log.
+func WithLokiOutput(conf *OptionsLoki) Option {
 	return func(logger *defaultLogger) error {
 		err := validator.New().Struct(conf)
 		if err != nil {
-			return fmt.Errorf("config for kafka output error: %w", err)
+			return fmt.Errorf("config for loki output error: %w", err)
+		}
+
+		lokiWriter, err := newLokiWriter(conf)
+		if err != nil {
+			return fmt.Errorf("loki writer error: %w", err)
 		}
 
-		saramaConf := sarama.NewConfig()
-		saramaConf.Producer.Partitioner = sarama.NewRandomPartitioner
-		saramaConf.Producer.RequiredAcks = sarama.WaitForAll
-		saramaConf.Producer.Retry.Max = conf.Producer.RetryMax
-		saramaConf.Producer.Return.Successes = conf.Producer.ReturnSuccesses
+		logger.writers = append(logger.writers, lokiWriter)
+		logger.closer = append(logger.closer, lokiWriter)
+		return nil
+	}
+}
 
-		kafkaProducer, err := sarama.NewSyncProducer(strings.Split(conf.Producer.Address, ","), saramaConf)
+// WithOTLPOutput export log in batch into OpenTelemetry Collector using OTLP/HTTP or OTLP/gRPC, each log is
+// converted into LogRecord where severity is based on level, service name and version is the resource
+// and trace id from context is attached. Failed batch is retried with backoff, log is dropped when
+// buffer is full so application is never blocked. Close send the remaining log.
+func WithOTLPOutput(conf *OptionsOTLP) Option {
+	return func(logger *defaultLogger) error {
+		err := validator.New().Struct(conf)
 		if err != nil {
-			return fmt.Errorf("kafka sync producer error: %w", err)
+			return fmt.Errorf("config for otlp output error: %w", err)
 		}
 
-		kafkaWriter := &wrapKafkaWriter{
-			topic:    conf.Topic,
-			producer: kafkaProducer,
+		otlpWriter, err := newOTLPWriter(conf)
+		if err != nil {
+			return fmt.Errorf("otlp writer error: %w", err)
 		}
 
-		// wire Kafka writer to log
-		logger.writers = append(logger.writers, kafkaWriter)
-		logger.closer = append(logger.closer, kafkaProducer)
+		logger.writers = append(logger.writers, otlpWriter)
+		logger.closer = append(logger.closer, otlpWriter)
 		return nil
 	}
 }
@@ -138,3 +367,13 @@ func WithLevel(level Level) Option {
 		return nil
 	}
 }
+
+// WithTdrValidation validate required field of TDR and then each custom validator.
+// Invalid TDR is still written with additional "schemaError" field describing the problem.
+func WithTdrValidation(validators ...TdrValidator) Option {
+	return func(logger *defaultLogger) error {
+		logger.tdrValidation = true
+		logger.tdrValidators = append(logger.tdrValidators, validators...)
+		return nil
+	}
+}
@@ -0,0 +1,374 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	OTLPHTTP = "http"
+	OTLPGRPC = "grpc"
+
+	otlpScopeName = "github.com/armiariyan/logger"
+)
+
+// otlpSeverities map "level" field of log record into OTLP severity number
+var otlpSeverities = map[string]logspb.SeverityNumber{
+	"debug":  logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+	"info":   logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+	"warn":   logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	"error":  logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+	"dpanic": logspb.SeverityNumber_SEVERITY_NUMBER_ERROR2,
+	"panic":  logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+	"fatal":  logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4,
+}
+
+// otlpRetryable is gRPC status which should be retried according to OTLP specification
+var otlpRetryable = map[codes.Code]bool{
+	codes.Canceled:          true,
+	codes.DeadlineExceeded:  true,
+	codes.ResourceExhausted: true,
+	codes.Aborted:           true,
+	codes.OutOfRange:        true,
+	codes.Unavailable:       true,
+	codes.DataLoss:          true,
+}
+
+// otlpSender export batch of records into OpenTelemetry Collector as ExportLogsServiceRequest
+type otlpSender struct {
+	conf     *OptionsOTLP
+	timeout  time.Duration
+	client   *http.Client                  // used by http protocol
+	conn     *grpc.ClientConn              // used by grpc protocol
+	logs     collogspb.LogsServiceClient   // used by grpc protocol
+	resource map[string]*commonpb.AnyValue // ResourceAttributes of config
+	now      func() time.Time
+}
+
+// otlpWriter close grpc connection after the remaining log is sent
+type otlpWriter struct {
+	*batchWriter
+	sender *otlpSender
+}
+
+func (w *otlpWriter) Close() error {
+	err := w.batchWriter.Close()
+	if w.sender.conn != nil {
+		if connErr := w.sender.conn.Close(); err == nil {
+			err = connErr
+		}
+	}
+
+	return err
+}
+
+func newOTLPWriter(conf *OptionsOTLP) (*otlpWriter, error) {
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPBulkTimeout
+	}
+
+	sender := &otlpSender{conf: conf, timeout: timeout, now: time.Now}
+	sender.resource = make(map[string]*commonpb.AnyValue, len(conf.ResourceAttributes))
+	for k, v := range conf.ResourceAttributes {
+		sender.resource[k] = otlpString(v)
+	}
+
+	if conf.Protocol == OTLPGRPC {
+		creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+		if conf.Insecure {
+			creds = insecure.NewCredentials()
+		}
+
+		dialOptions := append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, conf.DialOptions...)
+		conn, err := grpc.Dial(conf.Endpoint, dialOptions...)
+		if err != nil {
+			return nil, err
+		}
+
+		sender.conn = conn
+		sender.logs = collogspb.NewLogsServiceClient(conn)
+	} else {
+		u, err := url.Parse(conf.Endpoint)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid otlp http endpoint %q", conf.Endpoint)
+		}
+
+		sender.client = conf.Client
+		if sender.client == nil {
+			sender.client = &http.Client{Timeout: timeout}
+		}
+	}
+
+	return &otlpWriter{
+		batchWriter: newBatchWriter(batchConfig{
+			size:          conf.BatchSize,
+			flushInterval: conf.FlushInterval,
+			bufferSize:    conf.BufferSize,
+			maxRetries:    conf.MaxRetries,
+			retryBackoff:  conf.RetryBackoff,
+			onError:       conf.OnError,
+		}, sender.send),
+		sender: sender,
+	}, nil
+}
+
+func (s *otlpSender) send(records [][]byte) ([][]byte, error) {
+	req := s.request(records)
+	if s.conf.Protocol == OTLPGRPC {
+		return s.sendGRPC(records, req)
+	}
+
+	return s.sendHTTP(records, req)
+}
+
+func (s *otlpSender) sendGRPC(records [][]byte, req *collogspb.ExportLogsServiceRequest) ([][]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	if len(s.conf.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(s.conf.Headers))
+	}
+
+	resp, err := s.logs.Export(ctx, req)
+	if err != nil {
+		if otlpRetryable[status.Code(err)] {
+			return records, fmt.Errorf("otlp grpc error: %w", err)
+		}
+
+		return nil, fmt.Errorf("otlp grpc error: %w", err)
+	}
+
+	return nil, otlpPartialSuccess(resp)
+}
+
+func (s *otlpSender) sendHTTP(records [][]byte, req *collogspb.ExportLogsServiceRequest) ([][]byte, error) {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("otlp marshal error: %w", err)
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, s.conf.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range s.conf.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return records, err
+	}
+
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return records, fmt.Errorf("otlp http status %d: %s", resp.StatusCode, truncateBody(respBody))
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("otlp http status %d: %s", resp.StatusCode, truncateBody(respBody))
+	}
+
+	exportResp := &collogspb.ExportLogsServiceResponse{}
+	if err := proto.Unmarshal(respBody, exportResp); err != nil {
+		return nil, nil
+	}
+
+	return nil, otlpPartialSuccess(exportResp)
+}
+
+// otlpPartialSuccess returns error when collector rejects some of the records, it must not be retried
+func otlpPartialSuccess(resp *collogspb.ExportLogsServiceResponse) error {
+	partial := resp.GetPartialSuccess()
+	if partial.GetRejectedLogRecords() <= 0 {
+		return nil
+	}
+
+	return fmt.Errorf("otlp %d log records rejected: %s", partial.GetRejectedLogRecords(), partial.GetErrorMessage())
+}
+
+// request group records by service name and version into ResourceLogs, in the same order as it is written
+func (s *otlpSender) request(records [][]byte) *collogspb.ExportLogsServiceRequest {
+	req := &collogspb.ExportLogsServiceRequest{}
+	byResource := make(map[string]*logspb.ScopeLogs)
+	observed := uint64(s.now().UnixNano())
+
+	for _, record := range records {
+		service, version, logRecord := s.logRecord(record)
+		logRecord.ObservedTimeUnixNano = observed
+
+		key := service + "\x00" + version
+		scope, ok := byResource[key]
+		if !ok {
+			scope = &logspb.ScopeLogs{Scope: &commonpb.InstrumentationScope{Name: otlpScopeName}}
+			byResource[key] = scope
+			req.ResourceLogs = append(req.ResourceLogs, &logspb.ResourceLogs{
+				Resource:  &resourcepb.Resource{Attributes: s.resourceAttributes(service, version)},
+				ScopeLogs: []*logspb.ScopeLogs{scope},
+			})
+		}
+
+		scope.LogRecords = append(scope.LogRecords, logRecord)
+	}
+
+	return req
+}
+
+func (s *otlpSender) resourceAttributes(service, version string) []*commonpb.KeyValue {
+	attrs := make(map[string]*commonpb.AnyValue, len(s.resource)+2)
+	for k, v := range s.resource {
+		attrs[k] = v
+	}
+
+	if service != "" {
+		attrs["service.name"] = otlpString(service)
+	}
+
+	if version != "" {
+		attrs["service.version"] = otlpString(version)
+	}
+
+	return otlpKeyValues(attrs)
+}
+
+// logRecord convert JSON log record into LogRecord, "message" is the body and
+// field which isn't mapped into LogRecord or Resource is kept as attribute
+func (s *otlpSender) logRecord(record []byte) (service, version string, logRecord *logspb.LogRecord) {
+	record = bytes.TrimRight(record, "\n")
+	logRecord = &logspb.LogRecord{}
+
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(record))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		logRecord.Body = otlpString(string(record))
+		logRecord.TimeUnixNano = uint64(s.now().UnixNano())
+		return "", "", logRecord
+	}
+
+	service = recordString(fields, "_app_name")
+	version = recordString(fields, "_app_version")
+	delete(fields, "_app_name")
+	delete(fields, "_app_version")
+
+	level := recordString(fields, "level")
+	logRecord.SeverityNumber = otlpSeverities[level]
+	logRecord.SeverityText = strings.ToUpper(level)
+	delete(fields, "level")
+
+	logRecord.Body = otlpString(recordString(fields, "message"))
+	delete(fields, "message")
+
+	logRecord.TimeUnixNano = uint64(s.now().UnixNano())
+	if t, err := time.ParseInLocation(lokiTimeLayout, recordString(fields, "xtime"), time.Local); err == nil {
+		logRecord.TimeUnixNano = uint64(t.UnixNano())
+		delete(fields, "xtime")
+	}
+
+	// invalid trace id is kept as attribute, so it is not lost
+	if traceID, err := hex.DecodeString(recordString(fields, "_app_trace_id")); err == nil && len(traceID) == 16 {
+		logRecord.TraceId = traceID
+		delete(fields, "_app_trace_id")
+	}
+
+	if spanID, err := hex.DecodeString(recordString(fields, "_app_span_id")); err == nil && len(spanID) == 8 {
+		logRecord.SpanId = spanID
+		delete(fields, "_app_span_id")
+	}
+
+	attrs := make(map[string]*commonpb.AnyValue, len(fields))
+	for k, v := range fields {
+		if value := otlpValue(v); value != nil {
+			attrs[k] = value
+		}
+	}
+
+	logRecord.Attributes = otlpKeyValues(attrs)
+	return service, version, logRecord
+}
+
+// otlpValue returns AnyValue of decoded JSON value, or nil when it is null
+func otlpValue(v interface{}) *commonpb.AnyValue {
+	switch v := v.(type) {
+	case string:
+		return otlpString(v)
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: i}}
+		}
+
+		f, _ := v.Float64()
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: f}}
+	case []interface{}:
+		values := make([]*commonpb.AnyValue, 0, len(v))
+		for _, elem := range v {
+			if value := otlpValue(elem); value != nil {
+				values = append(values, value)
+			}
+		}
+
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case map[string]interface{}:
+		attrs := make(map[string]*commonpb.AnyValue, len(v))
+		for k, elem := range v {
+			if value := otlpValue(elem); value != nil {
+				attrs[k] = value
+			}
+		}
+
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: otlpKeyValues(attrs)}}}
+	}
+
+	return nil
+}
+
+func otlpString(v string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+}
+
+// otlpKeyValues returns attributes sorted by key, so the output is stable
+func otlpKeyValues(attrs map[string]*commonpb.AnyValue) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	kvs := make([]*commonpb.KeyValue, len(keys))
+	for i, k := range keys {
+		kvs[i] = &commonpb.KeyValue{Key: k, Value: attrs[k]}
+	}
+
+	return kvs
+}
@@ -0,0 +1,319 @@
+package logger
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeCollector is in-process OpenTelemetry Collector receiving log using OTLP/HTTP and OTLP/gRPC
+type fakeCollector struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu       sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+	headers  []string       // value of "x-tenant" header or metadata of each request
+	fail     []codes.Code   // grpc error of each request, http status is based on it
+	partial  map[int]string // request number to partial success error message
+}
+
+// export record the request, returns non-nil status when the attempt should fail
+func (c *fakeCollector) export(req *collogspb.ExportLogsServiceRequest, tenant string) (*collogspb.ExportLogsServiceResponse, *status.Status) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	attempt := len(c.requests)
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, tenant)
+	if attempt < len(c.fail) && c.fail[attempt] != codes.OK {
+		return nil, status.New(c.fail[attempt], "collector error")
+	}
+
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if msg, ok := c.partial[attempt]; ok {
+		resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{RejectedLogRecords: 1, ErrorMessage: msg}
+	}
+
+	return resp, nil
+}
+
+func (c *fakeCollector) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	tenant := ""
+	if values := md.Get("x-tenant"); len(values) > 0 {
+		tenant = values[0]
+	}
+
+	resp, st := c.export(req, tenant)
+	if st != nil {
+		return nil, st.Err()
+	}
+
+	return resp, nil
+}
+
+func (c *fakeCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	req := &collogspb.ExportLogsServiceRequest{}
+	if err := proto.Unmarshal(body, req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	resp, st := c.export(req, r.Header.Get("X-Tenant"))
+	if st != nil {
+		code := http.StatusBadRequest
+		if st.Code() == codes.Unavailable {
+			code = http.StatusServiceUnavailable
+		}
+
+		w.WriteHeader(code)
+		return
+	}
+
+	b, _ := proto.Marshal(resp)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(b)
+}
+
+// logRecords returns all LogRecord received, with resource attributes of each record
+func (c *fakeCollector) logRecords() ([]*logspb.LogRecord, []map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var records []*logspb.LogRecord
+	var resources []map[string]string
+	for _, req := range c.requests {
+		for _, rl := range req.GetResourceLogs() {
+			resource := make(map[string]string)
+			for _, kv := range rl.GetResource().GetAttributes() {
+				resource[kv.GetKey()] = kv.GetValue().GetStringValue()
+			}
+
+			for _, sl := range rl.GetScopeLogs() {
+				for _, lr := range sl.GetLogRecords() {
+					records = append(records, lr)
+					resources = append(resources, resource)
+				}
+			}
+		}
+	}
+
+	return records, resources
+}
+
+func otlpAttribute(lr *logspb.LogRecord, key string) *commonpb.AnyValue {
+	for _, kv := range lr.GetAttributes() {
+		if kv.GetKey() == key {
+			return kv.GetValue()
+		}
+	}
+
+	return nil
+}
+
+func startGRPCCollector(t *testing.T, c *fakeCollector) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(server, c)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	return lis.Addr().String()
+}
+
+func TestWithOTLPOutput(t *testing.T) {
+	traceCtx := InjectCtx(context.Background(), Context{
+		ServiceName:    "my service name",
+		ServiceVersion: "v1.0.0",
+		Tag:            "my-tag",
+		TraceID:        "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:         "00f067aa0ba902b7",
+	})
+
+	for _, protocol := range []string{OTLPHTTP, OTLPGRPC} {
+		t.Run(protocol, func(t *testing.T) {
+			c := &fakeCollector{}
+			conf := &OptionsOTLP{
+				Protocol:           protocol,
+				Insecure:           true,
+				Headers:            map[string]string{"x-tenant": "tenant"},
+				ResourceAttributes: map[string]string{"deployment.environment": "test"},
+			}
+
+			if protocol == OTLPGRPC {
+				conf.Endpoint = startGRPCCollector(t, c)
+			} else {
+				server := httptest.NewServer(c)
+				defer server.Close()
+				conf.Endpoint = server.URL + "/v1/logs"
+			}
+
+			log, err := newLogger(WithOTLPOutput(conf))
+			require.NoError(t, err)
+
+			log.Warn(traceCtx, logMessage, Field{Key: "count", Val: 3})
+			log.TDR(traceCtx, LogTdrModel{ResponseCode: "00", RespTime: 12})
+			require.NoError(t, log.Close())
+
+			require.Len(t, c.requests, 1)
+			assert.Equal(t, "tenant", c.headers[0])
+
+			records, resources := c.logRecords()
+			require.Len(t, records, 2)
+
+			sys := records[0]
+			assert.Equal(t, logMessage, sys.GetBody().GetStringValue())
+			assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, sys.GetSeverityNumber())
+			assert.Equal(t, "WARN", sys.GetSeverityText())
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(sys.GetTraceId()))
+			assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(sys.GetSpanId()))
+			assert.NotZero(t, sys.GetTimeUnixNano())
+			assert.NotZero(t, sys.GetObservedTimeUnixNano())
+			assert.Equal(t, "SYS", otlpAttribute(sys, "logType").GetStringValue())
+			assert.Equal(t, "my-tag", otlpAttribute(sys, "_app_tag").GetStringValue())
+			assert.Equal(t, int64(3), otlpAttribute(sys, "count").GetIntValue())
+			assert.Nil(t, otlpAttribute(sys, "level"))
+			assert.Nil(t, otlpAttribute(sys, "_app_name"))
+			assert.Nil(t, otlpAttribute(sys, "_app_trace_id"))
+
+			assert.Equal(t, map[string]string{
+				"service.name":           "my service name",
+				"service.version":        "v1.0.0",
+				"deployment.environment": "test",
+			}, resources[0])
+
+			tdr := records[1]
+			assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_INFO, tdr.GetSeverityNumber())
+			assert.Equal(t, "TDR", otlpAttribute(tdr, "logType").GetStringValue())
+			assert.Equal(t, "00", otlpAttribute(tdr, "rc").GetStringValue())
+			assert.Equal(t, int64(12), otlpAttribute(tdr, "rt").GetIntValue())
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(tdr.GetTraceId()))
+		})
+	}
+
+	t.Run("group by resource", func(t *testing.T) {
+		c := &fakeCollector{}
+		log, err := newLogger(WithOTLPOutput(&OptionsOTLP{Protocol: OTLPGRPC, Endpoint: startGRPCCollector(t, c), Insecure: true}))
+		require.NoError(t, err)
+
+		log.Info(ctx, "first")
+		log.Info(traceCtx, "second")
+		log.Info(ctx, "third")
+		require.NoError(t, log.Close())
+
+		require.Len(t, c.requests, 1)
+		resourceLogs := c.requests[0].GetResourceLogs()
+		require.Len(t, resourceLogs, 2)
+		assert.Len(t, resourceLogs[0].GetScopeLogs()[0].GetLogRecords(), 2)
+		assert.Equal(t, otlpScopeName, resourceLogs[0].GetScopeLogs()[0].GetScope().GetName())
+		assert.Len(t, resourceLogs[1].GetScopeLogs()[0].GetLogRecords(), 1)
+
+		// log without trace id has empty trace context
+		assert.Empty(t, resourceLogs[0].GetScopeLogs()[0].GetLogRecords()[0].GetTraceId())
+	})
+
+	for _, protocol := range []string{OTLPHTTP, OTLPGRPC} {
+		t.Run(protocol+" retry", func(t *testing.T) {
+			c := &fakeCollector{fail: []codes.Code{codes.Unavailable}}
+			var errs []error
+			conf := &OptionsOTLP{
+				Protocol:     protocol,
+				Insecure:     true,
+				RetryBackoff: time.Millisecond,
+				OnError:      func(err error) { errs = append(errs, err) },
+			}
+
+			if protocol == OTLPGRPC {
+				conf.Endpoint = startGRPCCollector(t, c)
+			} else {
+				server := httptest.NewServer(c)
+				defer server.Close()
+				conf.Endpoint = server.URL + "/v1/logs"
+			}
+
+			log, err := newLogger(WithOTLPOutput(conf))
+			require.NoError(t, err)
+
+			log.Info(ctx, logMessage)
+			require.NoError(t, log.Close())
+
+			assert.Len(t, c.requests, 2)
+			assert.Empty(t, errs)
+		})
+	}
+
+	t.Run("permanent error", func(t *testing.T) {
+		c := &fakeCollector{fail: []codes.Code{codes.InvalidArgument}}
+		var errs []error
+		log, err := newLogger(WithOTLPOutput(&OptionsOTLP{
+			Protocol: OTLPGRPC,
+			Endpoint: startGRPCCollector(t, c),
+			Insecure: true,
+			OnError:  func(err error) { errs = append(errs, err) },
+		}))
+		require.NoError(t, err)
+
+		log.Info(ctx, logMessage)
+		require.NoError(t, log.Close())
+
+		assert.Len(t, c.requests, 1)
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0].Error(), "InvalidArgument")
+	})
+
+	t.Run("partial success", func(t *testing.T) {
+		c := &fakeCollector{partial: map[int]string{0: "timestamp too old"}}
+		server := httptest.NewServer(c)
+		defer server.Close()
+
+		var errs []error
+		log, err := newLogger(WithOTLPOutput(&OptionsOTLP{
+			Endpoint: server.URL + "/v1/logs",
+			OnError:  func(err error) { errs = append(errs, err) },
+		}))
+		require.NoError(t, err)
+
+		log.Info(ctx, logMessage)
+		require.NoError(t, log.Close())
+
+		assert.Len(t, c.requests, 1)
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0].Error(), "timestamp too old")
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := newLogger(WithOTLPOutput(&OptionsOTLP{Endpoint: "collector:4318"}))
+		assert.Error(t, err)
+
+		_, err = newLogger(WithOTLPOutput(&OptionsOTLP{Endpoint: "http://localhost", Protocol: "thrift"}))
+		assert.Error(t, err)
+
+		_, err = newLogger(WithOTLPOutput(&OptionsOTLP{}))
+		assert.Error(t, err)
+	})
+}
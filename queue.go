@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// queuePublisher publish batch of records into message broker, it returns records which should be retried
+// the same as sendBatch. Close is called after the remaining records is published.
+type queuePublisher interface {
+	publish(records [][]byte) (retry [][]byte, err error)
+	io.Closer
+}
+
+// queueWriter send records into message broker in batch, so every broker share the same batching,
+// retry and close behaviour
+type queueWriter struct {
+	*batchWriter
+	publisher queuePublisher
+}
+
+func (w *queueWriter) Close() error {
+	err := w.batchWriter.Close()
+	if closeErr := w.publisher.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func newQueueWriter(conf *OptionsQueue) (*queueWriter, error) {
+	var publisher queuePublisher
+	var err error
+	switch conf.Type {
+	case QueueTypeKafka:
+		publisher, err = newKafkaPublisher(conf)
+	case QueueTypeNATS:
+		publisher, err = newNATSPublisher(conf)
+	case QueueTypeRabbitMQ:
+		publisher, err = newRabbitMQPublisher(conf)
+	case QueueTypeRedis:
+		publisher, err = newRedisPublisher(conf)
+	default:
+		return nil, fmt.Errorf("unsupported queue type %s", conf.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newQueueWriterWith(conf, publisher), nil
+}
+
+func newQueueWriterWith(conf *OptionsQueue, publisher queuePublisher) *queueWriter {
+	return &queueWriter{
+		batchWriter: newBatchWriter(batchConfig{
+			size:          conf.Producer.BatchSize,
+			flushInterval: conf.Producer.FlushInterval,
+			bufferSize:    conf.Producer.BufferSize,
+			maxRetries:    conf.Producer.RetryMax,
+			retryBackoff:  conf.Producer.RetryBackoff,
+			onError:       conf.OnError,
+		}, publisher.publish),
+		publisher: publisher,
+	}
+}
+
+// queueTimeout returns timeout of each publish
+func queueTimeout(conf *OptionsQueue) time.Duration {
+	if conf.Producer.Timeout <= 0 {
+		return defaultHTTPBulkTimeout
+	}
+
+	return conf.Producer.Timeout
+}
+
+// kafkaPublisher send batch of records into kafka topic using sync producer
+type kafkaPublisher struct {
+	topic    string
+	producer sarama.SyncProducer
+}
+
+func newKafkaPublisher(conf *OptionsQueue) (*kafkaPublisher, error) {
+	producer, err := sarama.NewSyncProducer(strings.Split(conf.Producer.Address, ","), newKafkaConfig(conf))
+	if err != nil {
+		return nil, fmt.Errorf("kafka sync producer error: %w", err)
+	}
+
+	return &kafkaPublisher{topic: conf.Topic, producer: producer}, nil
+}
+
+func newKafkaConfig(conf *OptionsQueue) *sarama.Config {
+	saramaConf := sarama.NewConfig()
+	saramaConf.Producer.Partitioner = sarama.NewRandomPartitioner
+	saramaConf.Producer.RequiredAcks = sarama.WaitForAll
+	// failed records is retried by batchWriter using RetryMax, so it is not retried twice
+	saramaConf.Producer.Retry.Max = 0
+	saramaConf.Producer.Return.Successes = conf.Producer.ReturnSuccesses
+	saramaConf.Producer.Timeout = queueTimeout(conf)
+	return saramaConf
+}
+
+func (p *kafkaPublisher) publish(records [][]byte) ([][]byte, error) {
+	msgs := make([]*sarama.ProducerMessage, len(records))
+	for i, record := range records {
+		msgs[i] = &sarama.ProducerMessage{
+			Topic:    p.topic,
+			Key:      sarama.StringEncoder(fmt.Sprint(time.Now().UTC())),
+			Value:    sarama.ByteEncoder(record),
+			Metadata: i, // index of record, to find which one is failed
+		}
+	}
+
+	err := p.producer.SendMessages(msgs)
+	if err == nil {
+		return nil, nil
+	}
+
+	var producerErrs sarama.ProducerErrors
+	if !errors.As(err, &producerErrs) {
+		return records, err
+	}
+
+	retry := make([][]byte, 0, len(producerErrs))
+	for _, producerErr := range producerErrs {
+		if i, ok := producerErr.Msg.Metadata.(int); ok {
+			retry = append(retry, records[i])
+		}
+	}
+
+	return retry, fmt.Errorf("kafka %d messages failed: %w", len(producerErrs), producerErrs[0].Err)
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.producer.Close()
+}
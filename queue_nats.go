@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsPublisher publish each record into JetStream subject, the stream of subject must already exist.
+// Records are published asynchronously and record without ack is retried.
+type natsPublisher struct {
+	subject string
+	timeout time.Duration
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+}
+
+func newNATSPublisher(conf *OptionsQueue) (*natsPublisher, error) {
+	conn, err := nats.Connect(conf.Producer.Address, nats.Name("logger"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("nats connect error: %w", err)
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats jetstream error: %w", err)
+	}
+
+	return &natsPublisher{subject: conf.Topic, timeout: queueTimeout(conf), conn: conn, js: js}, nil
+}
+
+func (p *natsPublisher) publish(records [][]byte) ([][]byte, error) {
+	var retry [][]byte
+	var lastErr error
+
+	futures := make([]nats.PubAckFuture, len(records))
+	for i, record := range records {
+		future, err := p.js.PublishAsync(p.subject, record)
+		if err != nil {
+			retry = append(retry, record)
+			lastErr = err
+			continue
+		}
+
+		futures[i] = future
+	}
+
+	timeout := time.NewTimer(p.timeout)
+	defer timeout.Stop()
+
+	expired := false
+	for i, future := range futures {
+		if future == nil {
+			continue
+		}
+
+		if expired {
+			retry = append(retry, records[i])
+			continue
+		}
+
+		select {
+		case <-future.Ok():
+		case err := <-future.Err():
+			retry = append(retry, records[i])
+			lastErr = err
+		case <-timeout.C:
+			expired = true
+			retry = append(retry, records[i])
+			lastErr = errors.New("timeout waiting for ack")
+		}
+	}
+
+	if len(retry) <= 0 {
+		return nil, nil
+	}
+
+	return retry, fmt.Errorf("nats %d messages failed: %w", len(retry), lastErr)
+}
+
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// rabbitMQPublisher publish each record as persistent message into exchange using publisher confirm,
+// record which is nacked or not confirmed is retried. Connection is opened again when it is closed by broker.
+type rabbitMQPublisher struct {
+	url        string
+	exchange   string
+	routingKey string
+	timeout    time.Duration
+	conn       *amqp.Connection
+	ch         *amqp.Channel
+}
+
+func newRabbitMQPublisher(conf *OptionsQueue) (*rabbitMQPublisher, error) {
+	p := &rabbitMQPublisher{
+		url:        conf.Producer.Address,
+		exchange:   conf.Exchange,
+		routingKey: conf.Topic,
+		timeout:    queueTimeout(conf),
+	}
+
+	if _, err := p.channel(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// channel returns channel in confirm mode, connecting again when previous connection is closed
+func (p *rabbitMQPublisher) channel() (*amqp.Channel, error) {
+	if p.ch != nil && !p.ch.IsClosed() {
+		return p.ch, nil
+	}
+
+	_ = p.Close()
+
+	conn, err := amqp.DialConfig(p.url, amqp.Config{Dial: amqp.DefaultDial(p.timeout)})
+	if err != nil {
+		return nil, fmt.Errorf("rabbitmq dial error: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("rabbitmq channel error: %w", err)
+	}
+
+	if err = ch.Confirm(false); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("rabbitmq confirm mode error: %w", err)
+	}
+
+	p.conn, p.ch = conn, ch
+	return ch, nil
+}
+
+func (p *rabbitMQPublisher) publish(records [][]byte) ([][]byte, error) {
+	ch, err := p.channel()
+	if err != nil {
+		return records, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	var retry [][]byte
+	var lastErr error
+
+	confirms := make([]*amqp.DeferredConfirmation, 0, len(records))
+	for _, record := range records {
+		confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, p.exchange, p.routingKey, false, false, amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Timestamp:    time.Now(),
+			Body:         record,
+		})
+
+		if err != nil {
+			lastErr = err
+			break
+		}
+
+		confirms = append(confirms, confirm)
+	}
+
+	for i, confirm := range confirms {
+		ack, err := confirm.WaitContext(ctx)
+		if err != nil || !ack {
+			retry = append(retry, records[i])
+			lastErr = err
+			if lastErr == nil {
+				lastErr = errors.New("message is nacked")
+			}
+		}
+	}
+
+	// channel is closed, the rest of records is published using new channel on retry
+	retry = append(retry, records[len(confirms):]...)
+	if len(retry) <= 0 {
+		return nil, nil
+	}
+
+	return retry, fmt.Errorf("rabbitmq %d messages failed: %w", len(retry), lastErr)
+}
+
+func (p *rabbitMQPublisher) Close() error {
+	if p.conn == nil {
+		return nil
+	}
+
+	err := p.conn.Close()
+	p.conn, p.ch = nil, nil
+	if errors.Is(err, amqp.ErrClosed) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPublisher append each record into Redis Stream using pipelined XADD, record is written as "log" field
+type redisPublisher struct {
+	stream  string
+	maxLen  int64
+	timeout time.Duration
+	client  *redis.Client
+}
+
+func newRedisPublisher(conf *OptionsQueue) (*redisPublisher, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     conf.Producer.Address,
+		Password: conf.Producer.Password,
+	})
+
+	return &redisPublisher{
+		stream:  conf.Topic,
+		maxLen:  conf.Producer.MaxLen,
+		timeout: queueTimeout(conf),
+		client:  client,
+	}, nil
+}
+
+func (p *redisPublisher) publish(records [][]byte) ([][]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	cmds := make([]*redis.StringCmd, len(records))
+	pipe := p.client.Pipeline()
+	for i, record := range records {
+		// trimming is approximate, so Redis only remove whole macro node which is much cheaper
+		cmds[i] = pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: p.stream,
+			MaxLen: p.maxLen,
+			Approx: p.maxLen > 0,
+			Values: []interface{}{"log", record},
+		})
+	}
+
+	_, execErr := pipe.Exec(ctx)
+
+	var retry [][]byte
+	var lastErr error
+	for i, cmd := range cmds {
+		// command error is not set when connection is failed, so command without stream ID is not written
+		err := cmd.Err()
+		if err == nil && cmd.Val() == "" {
+			err = execErr
+		}
+
+		if err != nil {
+			retry = append(retry, records[i])
+			lastErr = err
+		}
+	}
+
+	if len(retry) <= 0 {
+		return nil, nil
+	}
+
+	return retry, fmt.Errorf("redis %d messages failed: %w", len(retry), lastErr)
+}
+
+func (p *redisPublisher) Close() error {
+	return p.client.Close()
+}
@@ -0,0 +1,409 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAMQPServer is minimal AMQP 0.9.1 broker which only support publisher confirm,
+// each published message is acked unless its delivery tag is in nack.
+type fakeAMQPServer struct {
+	lis  net.Listener
+	nack map[uint64]bool
+
+	mu        sync.Mutex
+	published []string
+	conns     int
+}
+
+func startFakeAMQPServer(t *testing.T, nack ...uint64) *fakeAMQPServer {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+
+	s := &fakeAMQPServer{lis: lis, nack: make(map[uint64]bool)}
+	for _, tag := range nack {
+		s.nack[tag] = true
+	}
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeAMQPServer) url() string {
+	return "amqp://guest:guest@" + s.lis.Addr().String() + "/"
+}
+
+func (s *fakeAMQPServer) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.published...)
+}
+
+func (s *fakeAMQPServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	s.mu.Lock()
+	s.conns++
+	s.mu.Unlock()
+
+	r := bufio.NewReader(conn)
+	if _, err := io.ReadFull(r, make([]byte, 8)); err != nil { // protocol header
+		return
+	}
+
+	method := func(channel uint16, class, id uint16, args ...byte) {
+		payload := binary.BigEndian.AppendUint16(nil, class)
+		payload = binary.BigEndian.AppendUint16(payload, id)
+		payload = append(payload, args...)
+
+		frame := []byte{1}
+		frame = binary.BigEndian.AppendUint16(frame, channel)
+		frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+		frame = append(frame, payload...)
+		_, _ = conn.Write(append(frame, 0xCE))
+	}
+
+	// connection.start with version 0-9, empty server properties, PLAIN mechanism and en_US locale
+	method(0, 10, 10, 0, 9, 0, 0, 0, 0, 0, 0, 0, 5, 'P', 'L', 'A', 'I', 'N', 0, 0, 0, 5, 'e', 'n', '_', 'U', 'S')
+
+	var tag, size uint64
+	var body []byte
+	delivered := func(channel uint16) {
+		tag++
+		args := append(binary.BigEndian.AppendUint64(nil, tag), 0)
+		if s.nack[tag] {
+			method(channel, 60, 120, args...) // basic.nack
+			return
+		}
+
+		s.mu.Lock()
+		s.published = append(s.published, string(body))
+		s.mu.Unlock()
+		method(channel, 60, 80, args...) // basic.ack
+	}
+
+	for {
+		header := make([]byte, 7)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return
+		}
+
+		channel := binary.BigEndian.Uint16(header[1:])
+		payload := make([]byte, binary.BigEndian.Uint32(header[3:])+1) // with frame end
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return
+		}
+
+		payload = payload[:len(payload)-1]
+		switch header[0] {
+		case 1:
+			switch [2]uint16{binary.BigEndian.Uint16(payload), binary.BigEndian.Uint16(payload[2:])} {
+			case [2]uint16{10, 11}: // connection.start-ok, reply connection.tune
+				method(0, 10, 30, 0x07, 0xFF, 0, 2, 0, 0, 0, 0)
+			case [2]uint16{10, 40}: // connection.open
+				method(0, 10, 41, 0)
+			case [2]uint16{20, 10}: // channel.open
+				method(channel, 20, 11, 0, 0, 0, 0)
+			case [2]uint16{85, 10}: // confirm.select
+				method(channel, 85, 11)
+			case [2]uint16{60, 40}: // basic.publish, content header and body follow
+				body = nil
+			case [2]uint16{20, 40}: // channel.close
+				method(channel, 20, 41)
+			case [2]uint16{10, 50}: // connection.close
+				method(0, 10, 51)
+				return
+			}
+		case 2:
+			size = binary.BigEndian.Uint64(payload[4:])
+			if size == 0 {
+				delivered(channel)
+			}
+		case 3:
+			body = append(body, payload...)
+			if uint64(len(body)) >= size {
+				delivered(channel)
+			}
+		}
+	}
+}
+
+func startNATSServer(t *testing.T) string {
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+
+	go ns.Start()
+	t.Cleanup(ns.Shutdown)
+	require.True(t, ns.ReadyForConnections(5*time.Second))
+
+	return ns.ClientURL()
+}
+
+// blockingPublisher publish nothing until block is closed
+type blockingPublisher struct {
+	block chan struct{}
+}
+
+func (p *blockingPublisher) publish([][]byte) ([][]byte, error) {
+	<-p.block
+	return nil, nil
+}
+
+func (p *blockingPublisher) Close() error { return nil }
+
+func TestWithQueueOutput(t *testing.T) {
+	t.Run("kafka", func(t *testing.T) {
+		var values []string
+		check := func(val []byte) error {
+			values = append(values, string(val))
+			return nil
+		}
+
+		producer := mocks.NewSyncProducer(t, nil)
+		producer.ExpectSendMessageAndFail(sarama.ErrNotLeaderForPartition)
+		producer.ExpectSendMessageAndSucceed()
+		producer.ExpectSendMessageWithCheckerFunctionAndSucceed(check)
+		producer.ExpectSendMessageWithCheckerFunctionAndSucceed(check)
+
+		var errs []error
+		conf := &OptionsQueue{
+			Type:     QueueTypeKafka,
+			Topic:    "log",
+			Producer: ProducerOptions{Address: "localhost:9092", RetryBackoff: time.Millisecond},
+			OnError:  func(err error) { errs = append(errs, err) },
+		}
+
+		w := newQueueWriterWith(conf, &kafkaPublisher{topic: conf.Topic, producer: producer})
+		log, err := newLogger(WithCustomWriter(w))
+		require.NoError(t, err)
+
+		log.Info(ctx, "first")
+		log.Info(ctx, "second")
+		require.NoError(t, log.Close())
+
+		// the whole batch is retried since mock producer doesn't return which message is failed
+		require.Len(t, values, 2)
+		assert.Contains(t, values[0], `"message":"first"`)
+		assert.Contains(t, values[1], `"message":"second"`)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("kafka config", func(t *testing.T) {
+		conf := newKafkaConfig(&OptionsQueue{Producer: ProducerOptions{RetryMax: -1}})
+		assert.NoError(t, conf.Validate())
+		assert.Equal(t, 0, conf.Producer.Retry.Max)
+
+		conf = newKafkaConfig(&OptionsQueue{Producer: ProducerOptions{RetryMax: 5}})
+		assert.Equal(t, 0, conf.Producer.Retry.Max)
+	})
+
+	t.Run("drop when buffer is full", func(t *testing.T) {
+		var mu sync.Mutex
+		var errs []error
+		conf := &OptionsQueue{
+			Type:     QueueTypeKafka,
+			Topic:    "log",
+			Producer: ProducerOptions{BatchSize: 1, BufferSize: 1},
+			OnError: func(err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, err)
+			},
+		}
+
+		block := make(chan struct{})
+		w := newQueueWriterWith(conf, &blockingPublisher{block: block})
+		log, err := newLogger(WithCustomWriter(w))
+		require.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			log.Info(ctx, logMessage)
+		}
+
+		close(block)
+		require.NoError(t, log.Close())
+
+		require.NotEmpty(t, errs)
+		assert.Contains(t, errs[0].Error(), "records dropped")
+	})
+
+	t.Run("nats", func(t *testing.T) {
+		url := startNATSServer(t)
+		nc, err := nats.Connect(url)
+		require.NoError(t, err)
+		defer nc.Close()
+
+		js, err := nc.JetStream()
+		require.NoError(t, err)
+		_, err = js.AddStream(&nats.StreamConfig{Name: "LOGS", Subjects: []string{"logs.>"}})
+		require.NoError(t, err)
+
+		log, err := newLogger(WithQueueOutput(&OptionsQueue{
+			Type:     QueueTypeNATS,
+			Topic:    "logs.app",
+			Producer: ProducerOptions{Address: url},
+		}))
+		require.NoError(t, err)
+
+		log.Info(ctx, "first")
+		log.TDR(ctx, LogTdrModel{})
+		require.NoError(t, log.Close())
+
+		first, err := js.GetMsg("LOGS", 1)
+		require.NoError(t, err)
+		assert.Equal(t, "logs.app", first.Subject)
+		assert.Contains(t, string(first.Data), `"message":"first"`)
+
+		tdr, err := js.GetMsg("LOGS", 2)
+		require.NoError(t, err)
+		assert.Contains(t, string(tdr.Data), `"logType":"TDR"`)
+	})
+
+	t.Run("nats without stream", func(t *testing.T) {
+		var errs []error
+		log, err := newLogger(WithQueueOutput(&OptionsQueue{
+			Type:     QueueTypeNATS,
+			Topic:    "unknown",
+			Producer: ProducerOptions{Address: startNATSServer(t), RetryMax: -1},
+			OnError:  func(err error) { errs = append(errs, err) },
+		}))
+		require.NoError(t, err)
+
+		log.Info(ctx, logMessage)
+		require.NoError(t, log.Close())
+
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0].Error(), "nats 1 messages failed")
+	})
+
+	t.Run("rabbitmq", func(t *testing.T) {
+		// the second message is nacked, so it is published again
+		s := startFakeAMQPServer(t, 2)
+
+		var errs []error
+		log, err := newLogger(WithQueueOutput(&OptionsQueue{
+			Type:     QueueTypeRabbitMQ,
+			Topic:    "logs",
+			Producer: ProducerOptions{Address: s.url(), RetryBackoff: time.Millisecond},
+			OnError:  func(err error) { errs = append(errs, err) },
+		}))
+		require.NoError(t, err)
+
+		log.Info(ctx, "first")
+		log.Info(ctx, "second")
+		require.NoError(t, log.Close())
+
+		messages := s.messages()
+		require.Len(t, messages, 2)
+		assert.Contains(t, messages[0], `"message":"first"`)
+		assert.Contains(t, messages[1], `"message":"second"`)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("rabbitmq reconnect", func(t *testing.T) {
+		s := startFakeAMQPServer(t)
+		p, err := newRabbitMQPublisher(&OptionsQueue{Topic: "logs", Producer: ProducerOptions{Address: s.url()}})
+		require.NoError(t, err)
+
+		// connection is closed by broker
+		require.NoError(t, p.conn.Close())
+
+		retry, err := p.publish([][]byte{[]byte("a")})
+		require.NoError(t, err)
+		assert.Empty(t, retry)
+		assert.Equal(t, []string{"a"}, s.messages())
+		assert.Equal(t, 2, s.conns)
+		require.NoError(t, p.Close())
+	})
+
+	t.Run("redis", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+
+		log, err := newLogger(WithQueueOutput(&OptionsQueue{
+			Type:     QueueTypeRedis,
+			Topic:    "logs",
+			Producer: ProducerOptions{Address: mr.Addr()},
+		}))
+		require.NoError(t, err)
+
+		log.Info(ctx, "first")
+		log.Error(ctx, "second")
+		require.NoError(t, log.Close())
+
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		defer client.Close()
+
+		entries, err := client.XRange(context.Background(), "logs", "-", "+").Result()
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Contains(t, entries[0].Values["log"], `"message":"first"`)
+		assert.Contains(t, entries[1].Values["log"], `"level":"error"`)
+	})
+
+	t.Run("redis unavailable", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		addr := mr.Addr()
+		mr.Close()
+
+		var errs []error
+		log, err := newLogger(WithQueueOutput(&OptionsQueue{
+			Type:     QueueTypeRedis,
+			Topic:    "logs",
+			Producer: ProducerOptions{Address: addr, RetryMax: 1, RetryBackoff: time.Millisecond},
+			OnError:  func(err error) { errs = append(errs, err) },
+		}))
+		require.NoError(t, err)
+
+		log.Info(ctx, logMessage)
+		require.NoError(t, log.Close())
+
+		require.Len(t, errs, 1)
+		assert.True(t, strings.Contains(errs[0].Error(), "redis 1 messages failed"), errs[0].Error())
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := newLogger(WithQueueOutput(&OptionsQueue{Type: "sqs", Topic: "logs", Producer: ProducerOptions{Address: "localhost"}}))
+		assert.Error(t, err)
+
+		_, err = newLogger(WithQueueOutput(&OptionsQueue{Type: QueueTypeRedis, Producer: ProducerOptions{Address: "localhost"}}))
+		assert.Error(t, err)
+
+		_, err = newQueueWriter(&OptionsQueue{Topic: "logs", Producer: ProducerOptions{Address: "localhost"}})
+		assert.EqualError(t, err, "unsupported queue type ")
+	})
+}
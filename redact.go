@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/segmentio/encoding/json"
+)
+
+// headerRedacted replace value of header which is not allowed to be written
+const headerRedacted = "[REDACTED]"
+
+// DefaultRedactedHeaders is header which value is always redacted in TDR, compared case-insensitively.
+// Add more header using WithHeaderDenylist.
+var DefaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"Api-Key",
+	"X-Auth-Token",
+	"X-Access-Token",
+	"X-Client-Secret",
+	"X-Signature",
+}
+
+type headerRedactor struct {
+	denylist  map[string]struct{}
+	allowlist map[string]struct{} // when not empty, only header listed here is written as is
+}
+
+func newHeaderRedactor(denylist []string) *headerRedactor {
+	r := &headerRedactor{
+		denylist:  make(map[string]struct{}),
+		allowlist: make(map[string]struct{}),
+	}
+
+	r.deny(denylist...)
+	return r
+}
+
+func (r *headerRedactor) deny(headers ...string) {
+	for _, h := range headers {
+		r.denylist[strings.ToLower(h)] = struct{}{}
+	}
+}
+
+func (r *headerRedactor) allow(headers ...string) {
+	for _, h := range headers {
+		r.allowlist[strings.ToLower(h)] = struct{}{}
+	}
+}
+
+func (r *headerRedactor) redacted(key string) bool {
+	key = strings.ToLower(key)
+	if _, ok := r.denylist[key]; ok {
+		return true
+	}
+
+	if len(r.allowlist) <= 0 {
+		return false
+	}
+
+	_, ok := r.allowlist[key]
+	return !ok
+}
+
+// redact returns copy of header with redacted value. It accept any map with string key,
+// such as http.Header, metadata.MD, map[string]string or JSON object string,
+// other type is returned as is.
+func (r *headerRedactor) redact(header interface{}) interface{} {
+	if header == nil {
+		return nil
+	}
+
+	// header passed as JSON string is parsed, so redacted value can be replaced
+	if str, ok := header.(string); ok {
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(str), &data); err != nil {
+			return header
+		}
+
+		header = data
+	}
+
+	val := reflect.ValueOf(header)
+	if val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+
+	if val.Kind() != reflect.Map || val.Type().Key().Kind() != reflect.String {
+		return header
+	}
+
+	altered := make(map[string]interface{}, val.Len())
+	iter := val.MapRange()
+	for iter.Next() {
+		key := iter.Key().String()
+		if r.redacted(key) {
+			altered[key] = headerRedacted
+			continue
+		}
+
+		altered[key] = iter.Value().Interface()
+	}
+
+	return altered
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHeaderRedactor(t *testing.T) {
+	type testCase struct {
+		name      string
+		allowlist []string
+		input     interface{}
+		expected  interface{}
+	}
+
+	testCases := []testCase{
+		{
+			name:     "nil",
+			input:    nil,
+			expected: nil,
+		},
+		{
+			name: "http header",
+			input: http.Header{
+				"Authorization": []string{"Bearer token"},
+				"Content-Type":  []string{"application/json"},
+			},
+			expected: map[string]interface{}{
+				"Authorization": headerRedacted,
+				"Content-Type":  []string{"application/json"},
+			},
+		},
+		{
+			name: "grpc metadata",
+			input: metadata.Pairs(
+				"authorization", "Bearer token",
+				"x-api-key", "secret",
+				"correlation-id", "123",
+			),
+			expected: map[string]interface{}{
+				"authorization":  headerRedacted,
+				"x-api-key":      headerRedacted,
+				"correlation-id": []string{"123"},
+			},
+		},
+		{
+			name: "plain map and case insensitive",
+			input: map[string]string{
+				"COOKIE": "session=1",
+				"accept": "*/*",
+			},
+			expected: map[string]interface{}{
+				"COOKIE": headerRedacted,
+				"accept": "*/*",
+			},
+		},
+		{
+			name:  "pointer to map",
+			input: &map[string]interface{}{"Set-Cookie": "session=1"},
+			expected: map[string]interface{}{
+				"Set-Cookie": headerRedacted,
+			},
+		},
+		{
+			name:  "json string",
+			input: `{"Authorization":"Bearer token","Accept":"*/*"}`,
+			expected: map[string]interface{}{
+				"Authorization": headerRedacted,
+				"Accept":        "*/*",
+			},
+		},
+		{
+			name:     "non json string kept as is",
+			input:    "Authorization: Bearer token",
+			expected: "Authorization: Bearer token",
+		},
+		{
+			name:     "map with non string key kept as is",
+			input:    map[int]string{1: "a"},
+			expected: map[int]string{1: "a"},
+		},
+		{
+			name:      "allowlist",
+			allowlist: []string{"content-type", "Authorization"},
+			input: http.Header{
+				"Authorization":   []string{"Bearer token"},
+				"Content-Type":    []string{"application/json"},
+				"X-Forwarded-For": []string{"10.0.0.1"},
+			},
+			expected: map[string]interface{}{
+				"Authorization":   headerRedacted,
+				"Content-Type":    []string{"application/json"},
+				"X-Forwarded-For": headerRedacted,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newHeaderRedactor(DefaultRedactedHeaders)
+			r.allow(tc.allowlist...)
+			assert.EqualValues(t, tc.expected, r.redact(tc.input))
+		})
+	}
+
+	t.Run("original header is not modified", func(t *testing.T) {
+		header := http.Header{"Authorization": []string{"Bearer token"}}
+		newHeaderRedactor(DefaultRedactedHeaders).redact(header)
+		assert.EqualValues(t, "Bearer token", header.Get("Authorization"))
+	})
+}
+
+func TestDefaultLoggerTDR_HeaderRedaction(t *testing.T) {
+	writer := &testAssertionLogger{}
+	log, err := newLogger(
+		WithCustomWriter(writer),
+		WithHeaderDenylist("X-Partner-Secret"),
+	)
+	require.NoError(t, err)
+
+	log.TDR(ctx, LogTdrModel{
+		Header: http.Header{
+			"Authorization":    []string{"Bearer token"},
+			"X-Partner-Secret": []string{"secret"},
+			"Accept":           []string{"*/*"},
+		},
+	})
+
+	var logTdrData LogTdrModel
+	require.NoError(t, json.Unmarshal(writer.GetActualData(), &logTdrData))
+	assert.EqualValues(t, map[string]interface{}{
+		"Authorization":    headerRedacted,
+		"X-Partner-Secret": headerRedacted,
+		"Accept":           []interface{}{"*/*"},
+	}, logTdrData.Header)
+}
@@ -0,0 +1,233 @@
+package logger
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Detector find sensitive data in free-form text, such as log message or error string, and replace it.
+// Valid is optional, used to drop false positive match (for example Luhn check of card number).
+type Detector struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Valid   func(match string) bool
+	Replace func(match string) string
+
+	// mayMatch is cheap check to skip the regex, only set for default detectors
+	mayMatch func(text string) bool
+}
+
+var (
+	// DetectorBearer replace token of "Bearer <token>".
+	DetectorBearer = Detector{
+		Name:    "bearer",
+		Pattern: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`),
+		Replace: func(match string) string {
+			return match[:len("bearer")] + " " + headerRedacted
+		},
+		mayMatch: func(text string) bool {
+			return strings.Contains(text, "earer") || strings.Contains(text, "EARER")
+		},
+	}
+
+	// DetectorJWT replace JSON Web Token, which always started with base64 of `{"` in header and payload.
+	DetectorJWT = Detector{
+		Name:    "jwt",
+		Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+		Replace: func(string) string {
+			return headerRedacted
+		},
+		mayMatch: func(text string) bool {
+			return strings.Contains(text, "eyJ")
+		},
+	}
+
+	// DetectorPAN mask card number (13-19 digits, may be separated by space or dash) which pass Luhn check,
+	// except the last 4 digits.
+	DetectorPAN = Detector{
+		Name:    "pan",
+		Pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Valid: func(match string) bool {
+			return luhn(digitsOnly(match))
+		},
+		Replace: func(match string) string {
+			return MaskExceptLastNthCharacter(digitsOnly(match), 4, CharacterAsterisk)
+		},
+		mayMatch: func(text string) bool {
+			return longestDigits(text, true) >= 13
+		},
+	}
+
+	// DetectorNIK mask Indonesian identity number (16 digits: region code, birth date and sequence),
+	// except the last 4 digits.
+	DetectorNIK = Detector{
+		Name:    "nik",
+		Pattern: regexp.MustCompile(`\b\d{16}\b`),
+		Valid:   validNIK,
+		Replace: func(match string) string {
+			return MaskExceptLastNthCharacter(match, 4, CharacterAsterisk)
+		},
+		mayMatch: func(text string) bool {
+			return longestDigits(text, false) >= 16
+		},
+	}
+
+	// DetectorEmail mask email address using MaskingEmail.
+	DetectorEmail = Detector{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		Replace: MaskingEmail,
+		mayMatch: func(text string) bool {
+			return strings.IndexByte(text, '@') >= 0
+		},
+	}
+
+	// DetectorPhone mask Indonesian mobile number started with 628, +628 or 08 (see SanitizePhoneNumber)
+	// using MaskingPhoneNumber.
+	DetectorPhone = Detector{
+		Name:    "phone",
+		Pattern: regexp.MustCompile(`(?:\+?\b628|\b08)\d{7,11}\b`),
+		Replace: MaskingPhoneNumber,
+		mayMatch: func(text string) bool {
+			return longestDigits(text, false) >= 9
+		},
+	}
+)
+
+// DefaultDetectors is used by WithScrubber when no detector is given.
+// Order matters: token is replaced before its content can be matched as other data,
+// and card number is checked before NIK since both can be 16 digits.
+var DefaultDetectors = []Detector{
+	DetectorBearer,
+	DetectorJWT,
+	DetectorPAN,
+	DetectorNIK,
+	DetectorEmail,
+	DetectorPhone,
+}
+
+type scrubber struct {
+	detectors []Detector
+}
+
+func newScrubber(detectors []Detector) *scrubber {
+	return &scrubber{detectors: detectors}
+}
+
+// scrub returns text with every match of detectors replaced.
+func (s *scrubber) scrub(text string) string {
+	if s == nil || text == "" {
+		return text
+	}
+
+	for _, d := range s.detectors {
+		if d.Pattern == nil || d.Replace == nil {
+			continue
+		}
+
+		// most log message has no sensitive data, so skip the regex whenever possible
+		if d.mayMatch != nil && !d.mayMatch(text) {
+			continue
+		}
+
+		text = d.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if d.Valid != nil && !d.Valid(match) {
+				return match
+			}
+
+			return d.Replace(match)
+		})
+	}
+
+	return text
+}
+
+// longestDigits returns length of the longest digit sequence in text,
+// space and dash is not counted but do not break the sequence when separator is allowed.
+func longestDigits(text string, separator bool) int {
+	longest, current := 0, 0
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c >= '0' && c <= '9':
+			current++
+			if current > longest {
+				longest = current
+			}
+		case separator && (c == ' ' || c == '-'):
+		default:
+			current = 0
+		}
+	}
+
+	return longest
+}
+
+func digitsOnly(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+func luhn(number string) bool {
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		n := int(number[i] - '0')
+		if double {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+
+		sum += n
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+// validNIK check structure of NIK: 2 digits province code (11-94), 4 digits city and district,
+// birth date (day + 40 for female), month, 2 digits year and 4 digits sequence which is not 0000.
+func validNIK(nik string) bool {
+	if len(nik) != 16 {
+		return false
+	}
+
+	num := func(s string) int {
+		n := 0
+		for i := 0; i < len(s); i++ {
+			n = n*10 + int(s[i]-'0')
+		}
+
+		return n
+	}
+
+	province := num(nik[0:2])
+	day := num(nik[6:8])
+	month := num(nik[8:10])
+
+	if province < 11 || province > 94 {
+		return false
+	}
+
+	if day > 40 {
+		day -= 40
+	}
+
+	if day < 1 || day > 31 || month < 1 || month > 12 {
+		return false
+	}
+
+	return nik[12:] != "0000"
+}
@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/segmentio/encoding/json"
+)
+
+// SinkFactory returns writer of registered sink type, config is the raw OptionsLogger.OptionsSink.
+type SinkFactory func(config json.RawMessage) (io.WriteCloser, error)
+
+// OptionsSink is common option of registered sink type, it is decoded from the same raw config
+// passed into SinkFactory, so the sink can define its own field next to these.
+type OptionsSink struct {
+	Mask bool `json:"mask"`
+	OptionsOutput
+	Level Level `json:"level"`
+}
+
+// builtinSinks is OptionsLogger.Type handled by this package, it cannot be registered
+var builtinSinks = []string{File, Queue, Syslog, Loki, OTLP, HTTPBulk}
+
+var (
+	sinksMu sync.RWMutex
+	sinks   = map[string]SinkFactory{}
+)
+
+// RegisterSink add sink type which can be selected as OptionsLogger.Type, such as
+// RegisterSink("s3", newS3Writer) for {"type": "s3", "optionsSink": {...}}. It is safe to call concurrently,
+// but usually called in init. It panics when typeName is empty, factory is nil or typeName is already
+// registered, including built-in type such as "file".
+func RegisterSink(typeName string, factory SinkFactory) {
+	if typeName == "" {
+		panic("logger: sink type is empty")
+	}
+
+	if factory == nil {
+		panic(fmt.Sprintf("logger: sink %q factory is nil", typeName))
+	}
+
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+
+	if _, ok := sinks[typeName]; ok || isBuiltinSink(typeName) {
+		panic(fmt.Sprintf("logger: sink %q already registered", typeName))
+	}
+
+	sinks[typeName] = factory
+}
+
+// Sinks returns every type which can be used as OptionsLogger.Type, both built-in and registered, sorted by name.
+func Sinks() []string {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+
+	types := append(make([]string, 0, len(builtinSinks)+len(sinks)), builtinSinks...)
+	for typeName := range sinks {
+		types = append(types, typeName)
+	}
+
+	sort.Strings(types)
+	return types
+}
+
+func isBuiltinSink(typeName string) bool {
+	for _, builtin := range builtinSinks {
+		if builtin == typeName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func lookupSink(typeName string) (SinkFactory, bool) {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+
+	factory, ok := sinks[typeName]
+	return factory, ok
+}
+
+// WithSinkOutput add writer of registered sink type, config is passed into its factory as is.
+func WithSinkOutput(typeName string, config json.RawMessage) Option {
+	return func(logger *defaultLogger) error {
+		factory, ok := lookupSink(typeName)
+		if !ok {
+			return fmt.Errorf("sink %q is not registered, available: %v", typeName, Sinks())
+		}
+
+		writer, err := factory(config)
+		if err != nil {
+			return fmt.Errorf("sink %q error: %w", typeName, err)
+		}
+
+		if writer == nil {
+			return fmt.Errorf("sink %q writer is nil", typeName)
+		}
+
+		logger.writers = append(logger.writers, writer)
+		logger.closer = append(logger.closer, writer)
+		return nil
+	}
+}
+
+// SetupLoggerSink will return Logger writing into registered sink type
+func SetupLoggerSink(serviceName, typeName string, config json.RawMessage) Logger {
+	fmt.Println("Try newLogger " + typeName + "...")
+
+	var options OptionsSink
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &options); err != nil {
+			panic(fmt.Errorf("logger sink %s config error: %w", typeName, err))
+		}
+	}
+
+	opt := options.options(options.Mask, options.Level)
+	opt = append(opt, WithSinkOutput(typeName, config))
+
+	log, err := newLogger(opt...)
+	if err != nil {
+		panic(fmt.Errorf("init logger with mode %s error: %w", typeName, err))
+	}
+
+	return log
+}
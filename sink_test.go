@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/segmentio/encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySinks keep writer created by "test-memory" sink, by its name config
+var memorySinks = map[string]*linesWriter{}
+
+func init() {
+	RegisterSink("test-memory", func(config json.RawMessage) (io.WriteCloser, error) {
+		var conf struct {
+			Name string `json:"name"`
+		}
+
+		if err := json.Unmarshal(config, &conf); err != nil {
+			return nil, err
+		}
+
+		if conf.Name == "" {
+			return nil, errors.New("name is required")
+		}
+
+		w := &linesWriter{}
+		memorySinks[conf.Name] = w
+		return w, nil
+	})
+}
+
+func TestRegisterSink(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		factory := func(json.RawMessage) (io.WriteCloser, error) { return &linesWriter{}, nil }
+
+		assert.Panics(t, func() { RegisterSink("", factory) })
+		assert.Panics(t, func() { RegisterSink("test-nil", nil) })
+		assert.PanicsWithValue(t, `logger: sink "test-memory" already registered`, func() { RegisterSink("test-memory", factory) })
+		assert.PanicsWithValue(t, `logger: sink "file" already registered`, func() { RegisterSink(File, factory) })
+	})
+
+	t.Run("list", func(t *testing.T) {
+		types := Sinks()
+		assert.Subset(t, types, []string{File, Queue, Syslog, Loki, OTLP, HTTPBulk, "test-memory"})
+		assert.IsNonDecreasing(t, types)
+	})
+
+	t.Run("combine", func(t *testing.T) {
+		log := SetupLoggerCombine(Options{
+			Name: "my service name",
+			SysOptions: OptionsLogger{
+				Type:        "test-memory",
+				OptionsSink: json.RawMessage(`{"name": "sys", "mask": true, "level": 1}`),
+			},
+			TdrOptions: OptionsLogger{
+				Type:        "test-memory",
+				OptionsSink: json.RawMessage(`{"name": "tdr"}`),
+			},
+		})
+
+		log.Info(ctx, "below level")
+		log.Warn(ctx, logMessage, Field{Key: "card", Val: maskerData{Card: "4111111111111111"}})
+		log.TDR(ctx, LogTdrModel{})
+		require.NoError(t, log.Close())
+
+		require.Len(t, memorySinks["sys"].lines, 1)
+		assert.Contains(t, memorySinks["sys"].lines[0], `"message":"log message"`)
+		assert.NotContains(t, memorySinks["sys"].lines[0], "4111111111111111")
+
+		require.Len(t, memorySinks["tdr"].lines, 1)
+		assert.Contains(t, memorySinks["tdr"].lines[0], `"logType":"TDR"`)
+	})
+
+	t.Run("not registered", func(t *testing.T) {
+		assert.PanicsWithError(t, `init logger with mode s3 error: sink "s3" is not registered, available: `+
+			`[file httpbulk loki otlp queue syslog test-memory]`, func() {
+			SetupLoggerSink("my service name", "s3", nil)
+		})
+	})
+
+	t.Run("factory error", func(t *testing.T) {
+		_, err := newLogger(WithSinkOutput("test-memory", json.RawMessage(`{}`)))
+		assert.EqualError(t, err, `sink "test-memory" error: name is required`)
+	})
+}
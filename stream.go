@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// StreamLogger write record into named stream such as audit, see StreamOf.
+type StreamLogger interface {
+	// Log write record with logType of the stream, LogAuditModel and LogEventModel is written
+	// at top level of the log while other record is written as "data".
+	Log(ctx context.Context, record interface{})
+}
+
+// StreamProvider is implemented by Logger of this package which support named stream beyond SYS and TDR,
+// it is kept out of Logger so its other implementation and mock still satisfy Logger.
+type StreamProvider interface {
+	Stream(name string) StreamLogger
+}
+
+// StreamOf returns named stream of l, such as StreamOf(l, StreamAudit).Log(ctx, LogAuditModel{}),
+// record is discarded when l doesn't implement StreamProvider.
+func StreamOf(l Logger, name string) StreamLogger {
+	if p, ok := l.(StreamProvider); ok {
+		return p.Stream(name)
+	}
+
+	return noopStream{}
+}
+
+// defaultStream write record into the same writers of defaultLogger
+type defaultStream struct {
+	logger  *defaultLogger
+	logType string
+}
+
+// Stream returns named stream using the same output, its logType is the upper case name such as AUDIT
+func (d *defaultLogger) Stream(name string) StreamLogger {
+	return &defaultStream{logger: d, logType: strings.ToUpper(name)}
+}
+
+// Log is written regardless of the SYS level, so audit trail is never dropped by level
+func (s *defaultStream) Log(ctx context.Context, record interface{}) {
+	d := s.logger
+
+	fields := make([]zap.Field, 0)
+	fields = append(fields, zap.String("logType", s.logType))
+	fields = append(fields, zap.String("level", "info"))
+
+	// add this first, so global context value still logged
+	fields = append(fields, d.formatLogs(ctx, separator, d.maskEnabled)...)
+
+	switch r := record.(type) {
+	case LogAuditModel:
+		fields = append(fields, d.auditFields(ctx, r)...)
+	case *LogAuditModel:
+		if r != nil {
+			fields = append(fields, d.auditFields(ctx, *r)...)
+		}
+	case LogEventModel:
+		fields = append(fields, d.eventFields(r)...)
+	case *LogEventModel:
+		if r != nil {
+			fields = append(fields, d.eventFields(*r)...)
+		}
+	default:
+		fields = append(fields, d.formatLog("data", record, d.maskEnabled))
+	}
+
+	_ = d.zapLogger.Core().Write(zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: separator}, fields)
+}
+
+func (d *defaultLogger) auditFields(ctx context.Context, audit LogAuditModel) []zap.Field {
+	if audit.ThreadID == "" {
+		audit.ThreadID = ExtractCtx(ctx).ThreadID
+	}
+
+	fields := make([]zap.Field, 0)
+	fields = append(fields, zap.String("actorId", audit.ActorID))
+	fields = append(fields, zap.String("actorType", audit.ActorType))
+	fields = append(fields, zap.String("action", audit.Action))
+	fields = append(fields, zap.String("targetType", audit.TargetType))
+	fields = append(fields, zap.String("targetId", audit.TargetID))
+	fields = append(fields, zap.String("outcome", audit.Outcome))
+	fields = append(fields, zap.String("reason", d.scrubber.scrub(audit.Reason)))
+	fields = append(fields, zap.String("srcIP", audit.SrcIP))
+	fields = append(fields, zap.String("xid", audit.ThreadID))
+
+	fields = append(fields, d.formatLog("before", audit.Before, d.maskEnabled))
+	fields = append(fields, d.formatLog("after", audit.After, d.maskEnabled))
+	fields = append(fields, d.formatLog("addData", audit.AdditionalData, d.maskEnabled))
+	return fields
+}
+
+func (d *defaultLogger) eventFields(event LogEventModel) []zap.Field {
+	fields := make([]zap.Field, 0)
+	fields = append(fields, zap.String("event", event.Name))
+	fields = append(fields, zap.String("entityType", event.EntityType))
+	fields = append(fields, zap.String("entityId", event.EntityID))
+	fields = append(fields, d.formatLog("props", event.Properties, d.maskEnabled))
+	return fields
+}
+
+// multiStream write record into stream of every logger
+type multiStream struct {
+	streams []StreamLogger
+}
+
+func (m *multiLogger) Stream(name string) StreamLogger {
+	streams := make([]StreamLogger, len(m.loggers))
+	for i, l := range m.loggers {
+		streams[i] = StreamOf(l, name)
+	}
+
+	return &multiStream{streams: streams}
+}
+
+func (m *multiStream) Log(ctx context.Context, record interface{}) {
+	for _, s := range m.streams {
+		s.Log(ctx, record)
+	}
+}
+
+// Stream returns stream using its output in Options.Streams, or SYS output when it is not configured
+func (c *combineLogger) Stream(name string) StreamLogger {
+	if l, ok := c.streams[name]; ok {
+		return StreamOf(l, name)
+	}
+
+	return StreamOf(c.sysLog, name)
+}
+
+type noopStream struct{}
+
+func (noopStream) Log(context.Context, interface{}) {}
+
+func (n *NoopContextLogger) Stream(string) StreamLogger { return noopStream{} }
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/segmentio/encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStream(t *testing.T) {
+	t.Run("audit", func(t *testing.T) {
+		w := &linesWriter{}
+		log, err := newLogger(WithCustomWriter(w), MaskEnabled())
+		require.NoError(t, err)
+
+		StreamOf(log, StreamAudit).Log(ctx, &LogAuditModel{
+			ActorID:    "user-1",
+			ActorType:  "admin",
+			Action:     "account.update",
+			TargetType: "account",
+			TargetID:   "acc-9",
+			Outcome:    "success",
+			Before:     maskerData{Card: "4111111111111111"},
+			After:      map[string]interface{}{"status": "blocked"},
+		})
+		require.NoError(t, log.Close())
+
+		require.Len(t, w.lines, 1)
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(w.lines[0]), &record))
+		assert.Equal(t, LogTypeAudit, record["logType"])
+		assert.Equal(t, "info", record["level"])
+		assert.Equal(t, "my service name", record["_app_name"])
+		assert.Equal(t, "user-1", record["actorId"])
+		assert.Equal(t, "account.update", record["action"])
+		assert.Equal(t, "acc-9", record["targetId"])
+		assert.Equal(t, map[string]interface{}{"status": "blocked"}, record["after"])
+		assert.NotContains(t, w.lines[0], "4111111111111111")
+	})
+
+	t.Run("event and generic record", func(t *testing.T) {
+		w := &linesWriter{}
+		log, err := newLogger(WithCustomWriter(w))
+		require.NoError(t, err)
+
+		StreamOf(log, StreamEvent).Log(ctx, LogEventModel{Name: "order.paid", EntityType: "order", EntityID: "o-1", Properties: map[string]interface{}{"amount": 10}})
+		StreamOf(log, "security").Log(ctx, map[string]interface{}{"rule": "brute-force"})
+		require.NoError(t, log.Close())
+
+		require.Len(t, w.lines, 2)
+		assert.Contains(t, w.lines[0], `"logType":"EVENT"`)
+		assert.Contains(t, w.lines[0], `"event":"order.paid"`)
+		assert.Contains(t, w.lines[0], `"props":{"amount":10}`)
+		assert.Contains(t, w.lines[1], `"logType":"SECURITY"`)
+		assert.Contains(t, w.lines[1], `"data":{"rule":"brute-force"}`)
+	})
+
+	t.Run("combine", func(t *testing.T) {
+		log := SetupLoggerCombine(Options{
+			Name: "my service name",
+			SysOptions: OptionsLogger{
+				Type:        "test-memory",
+				OptionsSink: json.RawMessage(`{"name": "stream-sys"}`),
+			},
+			TdrOptions: OptionsLogger{
+				Type:        "test-memory",
+				OptionsSink: json.RawMessage(`{"name": "stream-tdr"}`),
+			},
+			Streams: map[string]OptionsLogger{
+				StreamAudit: {
+					Type:        "test-memory",
+					OptionsSink: json.RawMessage(`{"name": "stream-audit"}`),
+				},
+			},
+		})
+
+		StreamOf(log, StreamAudit).Log(ctx, LogAuditModel{Action: "login"})
+		StreamOf(log, StreamEvent).Log(ctx, LogEventModel{Name: "order.paid"})
+		require.NoError(t, log.Close())
+
+		require.Len(t, memorySinks["stream-audit"].lines, 1)
+		assert.Contains(t, memorySinks["stream-audit"].lines[0], `"action":"login"`)
+
+		// stream without output is written into SYS output
+		require.Len(t, memorySinks["stream-sys"].lines, 1)
+		assert.Contains(t, memorySinks["stream-sys"].lines[0], `"logType":"EVENT"`)
+		assert.Empty(t, memorySinks["stream-tdr"].lines)
+	})
+
+	t.Run("multi", func(t *testing.T) {
+		first, second := &linesWriter{}, &linesWriter{}
+		firstLog, err := newLogger(WithCustomWriter(first))
+		require.NoError(t, err)
+		secondLog, err := newLogger(WithCustomWriter(second))
+		require.NoError(t, err)
+
+		StreamOf(NewMultiLogger(firstLog, secondLog), StreamAudit).Log(ctx, LogAuditModel{Action: "login"})
+		assert.Len(t, first.lines, 1)
+		assert.Len(t, second.lines, 1)
+	})
+
+	t.Run("sys level", func(t *testing.T) {
+		w := &linesWriter{}
+		log, err := newLogger(WithCustomWriter(w), WithLevel(ErrorLevel))
+		require.NoError(t, err)
+
+		log.Info(ctx, logMessage)
+		StreamOf(log, StreamAudit).Log(ctx, LogAuditModel{Action: "login"})
+		require.Len(t, w.lines, 1)
+		assert.Contains(t, w.lines[0], `"logType":"AUDIT"`)
+	})
+
+	t.Run("logger without stream", func(t *testing.T) {
+		assert.Equal(t, noopStream{}, StreamOf(plainLogger{Logger: &NoopContextLogger{}}, StreamAudit))
+	})
+}
+
+// plainLogger implement only Logger, such as mock of the application
+type plainLogger struct {
+	Logger
+}
@@ -0,0 +1,423 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/encoding/json"
+)
+
+const (
+	syslogDialTimeout  = 5 * time.Second
+	syslogWriteTimeout = 5 * time.Second
+	syslogNilValue     = "-"
+	syslogBOM          = "\xEF\xBB\xBF"
+
+	// DefaultSyslogSDID is SD-ID of structured data written by syslog output, using enterprise number
+	// reserved for documentation. Set OptionsSyslog.SDID to use your own enterprise number.
+	DefaultSyslogSDID = "logger@32473"
+)
+
+// DefaultSyslogStructuredData map global log field into SD-PARAM name of syslog structured data.
+var DefaultSyslogStructuredData = map[string]string{
+	"_app_name":       "name",
+	"_app_version":    "version",
+	"_app_port":       "port",
+	"_app_thread_id":  "threadId",
+	"_app_journey_id": "journeyId",
+	"_app_chain_id":   "chainId",
+	"_app_tag":        "tag",
+	"_app_method":     "method",
+	"_app_uri":        "uri",
+}
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSeverities map "level" field of log record into syslog severity
+// syslogIANASDIDs is SD-ID registered by IANA, which is the only SD-ID without "@" (RFC 5424 section 7)
+var syslogIANASDIDs = map[string]bool{
+	"timeQuality": true,
+	"origin":      true,
+	"meta":        true,
+}
+
+var syslogSeverities = map[string]int{
+	"fatal": 1, // alert
+	"panic": 2, // critical
+	"error": 3,
+	"warn":  4,
+	"info":  6,
+	"debug": 7,
+}
+
+type syslogParam struct {
+	key  string // log record key
+	name string // SD-PARAM name
+}
+
+// syslogWriter format each log record as RFC 5424 message and send it on background using batchWriter,
+// so unreachable or stalled server never blocks logging. Stream transport (tcp, tls and unix)
+// use octet counting framing (RFC 6587), while datagram transport (udp and unixgram) send one message
+// per datagram. Connection is dialed on first send and redialed once when write fails,
+// write which is not done within timeout fails and the remaining messages is retried with backoff.
+type syslogWriter struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	facility  int
+	hostname  string
+	appName   string
+	procID    string
+	sdID      string
+	params    []syslogParam
+	batch     *batchWriter
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+var _ io.WriteCloser = (*syslogWriter)(nil)
+
+func newSyslogWriter(conf *OptionsSyslog) (*syslogWriter, error) {
+	facility := syslogFacilities["user"]
+	if conf.Facility != "" {
+		f, ok := syslogFacilities[conf.Facility]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog facility %q", conf.Facility)
+		}
+
+		facility = f
+	}
+
+	hostname := conf.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+
+	sdID := conf.SDID
+	if sdID == "" {
+		sdID = DefaultSyslogSDID
+	}
+
+	if !isSyslogSDID(sdID) {
+		return nil, fmt.Errorf("invalid syslog SD-ID %q, it must be name@enterprise number", sdID)
+	}
+
+	structuredData := conf.StructuredData
+	if structuredData == nil {
+		structuredData = DefaultSyslogStructuredData
+	}
+
+	params := make([]syslogParam, 0, len(structuredData))
+	for key, name := range structuredData {
+		if !isSyslogName(name) {
+			return nil, fmt.Errorf("invalid syslog structured data param name %q", name)
+		}
+
+		params = append(params, syslogParam{key: key, name: name})
+	}
+
+	// map has random order, so param is sorted to keep the message stable
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].name < params[j].name
+	})
+
+	timeout := conf.WriteTimeout
+	if timeout <= 0 {
+		timeout = syslogWriteTimeout
+	}
+
+	w := &syslogWriter{
+		network:  conf.Network,
+		address:  conf.Address,
+		timeout:  timeout,
+		facility: facility,
+		hostname: syslogHeader(hostname, 255),
+		appName:  syslogHeader(conf.AppName, 48),
+		procID:   strconv.Itoa(os.Getpid()),
+		sdID:     sdID,
+		params:   params,
+	}
+
+	if conf.Network == "tls" {
+		tlsConfig, err := syslogTLSConfig(conf)
+		if err != nil {
+			return nil, err
+		}
+
+		w.tlsConfig = tlsConfig
+	}
+
+	w.batch = newBatchWriter(batchConfig{
+		size:          conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+		bufferSize:    conf.BufferSize,
+		maxRetries:    conf.MaxRetries,
+		retryBackoff:  conf.RetryBackoff,
+		onError:       conf.OnError,
+	}, w.send)
+
+	return w, nil
+}
+
+func syslogTLSConfig(conf *OptionsSyslog) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: conf.InsecureSkipVerify,
+	}
+
+	if conf.CAFile == "" {
+		return tlsConfig, nil
+	}
+
+	ca, err := os.ReadFile(conf.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("syslog ca file error: %w", err)
+	}
+
+	tlsConfig.RootCAs = x509.NewCertPool()
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("syslog ca file %s has no certificate", conf.CAFile)
+	}
+
+	return tlsConfig, nil
+}
+
+func (w *syslogWriter) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogDialTimeout}
+	if w.network == "tls" {
+		return tls.DialWithDialer(dialer, "tcp", w.address, w.tlsConfig)
+	}
+
+	return dialer.Dial(w.network, w.address)
+}
+
+func (w *syslogWriter) isStream() bool {
+	return w.network != "udp" && w.network != "unixgram"
+}
+
+// Write format p when it is logged, so the message timestamp is not delayed by the batch
+func (w *syslogWriter) Write(p []byte) (n int, err error) {
+	msg := w.format(p, time.Now())
+	if w.isStream() {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	if _, err = w.batch.Write(msg); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (w *syslogWriter) Sync() error {
+	return w.batch.Sync()
+}
+
+// Close send the remaining messages and close the connection
+func (w *syslogWriter) Close() error {
+	_ = w.batch.Close()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// send write formatted messages in order, the unsent messages is returned to be retried
+func (w *syslogWriter) send(msgs [][]byte) ([][]byte, error) {
+	for i, msg := range msgs {
+		if err := w.write(msg); err != nil {
+			return msgs[i:], err
+		}
+	}
+
+	return nil, nil
+}
+
+func (w *syslogWriter) write(msg []byte) (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// retry once using new connection, since server may close idle connection
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if w.conn, err = w.dial(); err != nil {
+				return fmt.Errorf("syslog dial error: %w", err)
+			}
+		}
+
+		if err = w.conn.SetWriteDeadline(time.Now().Add(w.timeout)); err == nil {
+			if _, err = w.conn.Write(msg); err == nil {
+				return nil
+			}
+		}
+
+		// partially written message breaks the stream framing, so connection is never reused
+		_ = w.conn.Close()
+		w.conn = nil
+
+		// server is stalled, retry is done by batchWriter with backoff
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			break
+		}
+	}
+
+	return fmt.Errorf("syslog write error: %w", err)
+}
+
+// format returns RFC 5424 message of JSON log record p, the record itself is written as MSG
+func (w *syslogWriter) format(p []byte, now time.Time) []byte {
+	var record map[string]interface{}
+	_ = json.Unmarshal(p, &record)
+
+	severity, ok := syslogSeverities[recordString(record, "level")]
+	if !ok {
+		severity = syslogSeverities["info"]
+	}
+
+	appName := w.appName
+	if appName == syslogNilValue {
+		appName = syslogHeader(recordString(record, "_app_name"), 48)
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "<%d>1 %s %s %s %s %s ",
+		w.facility*8+severity,
+		now.Format("2006-01-02T15:04:05.000000Z07:00"),
+		w.hostname,
+		appName,
+		w.procID,
+		syslogHeader(recordString(record, "logType"), 32),
+	)
+
+	w.writeStructuredData(buf, record)
+
+	buf.WriteString(" " + syslogBOM)
+	buf.Write(bytes.TrimRight(p, "\n"))
+	return buf.Bytes()
+}
+
+func (w *syslogWriter) writeStructuredData(buf *bytes.Buffer, record map[string]interface{}) {
+	written := false
+	for _, param := range w.params {
+		value := recordString(record, param.key)
+		if value == "" {
+			continue
+		}
+
+		if !written {
+			buf.WriteString("[" + w.sdID)
+			written = true
+		}
+
+		buf.WriteString(" " + param.name + `="`)
+		_, _ = syslogEscaper.WriteString(buf, value)
+		buf.WriteString(`"`)
+	}
+
+	if !written {
+		buf.WriteString(syslogNilValue)
+		return
+	}
+
+	buf.WriteString("]")
+}
+
+// syslogEscaper escape PARAM-VALUE as required by RFC 5424 section 6.3.3
+var syslogEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func recordString(record map[string]interface{}, key string) string {
+	switch v := record[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// syslogHeader returns header field which only contains printable US-ASCII, or "-" when it is empty
+func syslogHeader(value string, maxLen int) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+
+		return r
+	}, value)
+
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+
+	if value == "" {
+		return syslogNilValue
+	}
+
+	return value
+}
+
+// isSyslogSDID returns true for valid SD-ID, which is either registered by IANA
+// or name@number using private enterprise number (RFC 5424 section 6.3.2)
+func isSyslogSDID(id string) bool {
+	if !isSyslogName(id) {
+		return false
+	}
+
+	if syslogIANASDIDs[id] {
+		return true
+	}
+
+	name, number, ok := strings.Cut(id, "@")
+	if !ok || name == "" || number == "" || strings.Contains(number, "@") {
+		return false
+	}
+
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isSyslogName returns true for valid SD-NAME
+func isSyslogName(name string) bool {
+	if name == "" || len(name) > 32 {
+		return false
+	}
+
+	for _, r := range name {
+		if r < 33 || r > 126 || r == '=' || r == ' ' || r == ']' || r == '"' {
+			return false
+		}
+	}
+
+	return true
+}
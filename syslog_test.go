@@ -0,0 +1,329 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslogWriter_Format(t *testing.T) {
+	w, err := newSyslogWriter(&OptionsSyslog{
+		Network:  "udp",
+		Address:  "127.0.0.1:514",
+		Facility: "local0",
+		Hostname: "my host",
+	})
+	require.NoError(t, err)
+	defer w.Close()
+	w.procID = "1"
+
+	now := time.Date(2023, 1, 2, 3, 4, 5, 6000, time.UTC)
+
+	t.Run("record", func(t *testing.T) {
+		record := `{"logType":"SYS","level":"error","_app_name":"svc","_app_thread_id":"t\"1]","_app_port":8000}` + "\n"
+		expected := `<131>1 2023-01-02T03:04:05.000006Z my_host svc 1 SYS [logger@32473 name="svc" port="8000" threadId="t\"1\]"] ` +
+			syslogBOM + strings.TrimSpace(record)
+		assert.Equal(t, expected, string(w.format([]byte(record), now)))
+	})
+
+	t.Run("no structured data", func(t *testing.T) {
+		expected := "<134>1 2023-01-02T03:04:05.000006Z my_host - 1 - - " + syslogBOM + "not json"
+		assert.Equal(t, expected, string(w.format([]byte("not json"), now)))
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := newSyslogWriter(&OptionsSyslog{Network: "udp", Facility: "unknown"})
+		assert.Error(t, err)
+
+		_, err = newSyslogWriter(&OptionsSyslog{Network: "udp", StructuredData: map[string]string{"_app_tag": "a b"}})
+		assert.Error(t, err)
+
+		_, err = newLogger(WithSyslogOutput(&OptionsSyslog{Network: "http", Address: "127.0.0.1:514"}))
+		assert.Error(t, err)
+
+		for _, sdID := range []string{"logger", "logger@", "@32473", "logger@abc", "logger@1@2", "logger id@32473", "logger]@32473"} {
+			_, err = newSyslogWriter(&OptionsSyslog{Network: "udp", SDID: sdID})
+			assert.Error(t, err, sdID)
+		}
+	})
+
+	t.Run("sd id", func(t *testing.T) {
+		for _, sdID := range []string{"app@32473", "origin"} {
+			w, err := newSyslogWriter(&OptionsSyslog{Network: "udp", SDID: sdID})
+			require.NoError(t, err, sdID)
+			assert.Equal(t, sdID, w.sdID)
+			assert.NoError(t, w.Close())
+		}
+	})
+}
+
+func TestWithSyslogOutput(t *testing.T) {
+	t.Run("tcp", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer ln.Close()
+
+		assertSyslogStream(t, ln, &OptionsSyslog{Network: "tcp", Address: ln.Addr().String()})
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		cert, caFile := syslogTestCert(t)
+		ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+		require.NoError(t, err)
+		defer ln.Close()
+
+		assertSyslogStream(t, ln, &OptionsSyslog{Network: "tls", Address: ln.Addr().String(), CAFile: caFile})
+	})
+
+	t.Run("unix", func(t *testing.T) {
+		// unix socket path is limited to around 100 characters, so t.TempDir may be too long
+		dir, err := os.MkdirTemp("", "syslog")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		ln, err := net.Listen("unix", filepath.Join(dir, "log.sock"))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		assertSyslogStream(t, ln, &OptionsSyslog{Network: "unix", Address: ln.Addr().String()})
+	})
+
+	t.Run("udp", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer conn.Close()
+
+		log, err := newLogger(WithSyslogOutput(&OptionsSyslog{
+			Network:       "udp",
+			Address:       conn.LocalAddr().String(),
+			FlushInterval: 10 * time.Millisecond,
+		}))
+		require.NoError(t, err)
+		defer log.Close()
+
+		log.Info(ctx, logMessage)
+
+		buf := make([]byte, 64*1024)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+
+		// datagram has no octet counting
+		assert.True(t, strings.HasPrefix(string(buf[:n]), "<14>1 "), string(buf[:n]))
+		assert.Contains(t, string(buf[:n]), `"message":"log message"`)
+	})
+}
+
+func TestSyslogWriter_WriteTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	// server accept connection but never read it
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	w, err := newSyslogWriter(&OptionsSyslog{Network: "tcp", Address: ln.Addr().String(), WriteTimeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	defer w.Close()
+
+	record := []byte(`{"message":"` + strings.Repeat("x", 1<<20) + `"}`)
+	start := time.Now()
+
+	for err == nil && time.Since(start) < 10*time.Second {
+		err = w.write(record)
+	}
+
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	select {
+	case conn := <-accepted:
+		conn.Close()
+	default:
+	}
+}
+
+func TestSyslogWriter_NonBlocking(t *testing.T) {
+	t.Run("stalled server", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer ln.Close()
+
+		// server accept connection but never read it
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err == nil {
+				accepted <- conn
+			}
+		}()
+
+		var errs []error
+		var mu sync.Mutex
+		w, err := newSyslogWriter(&OptionsSyslog{
+			Network:      "tcp",
+			Address:      ln.Addr().String(),
+			WriteTimeout: 100 * time.Millisecond,
+			BufferSize:   10,
+			MaxRetries:   -1,
+			OnError: func(err error) {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			},
+		})
+		require.NoError(t, err)
+
+		record := []byte(`{"message":"` + strings.Repeat("x", 1<<20) + `"}`)
+		start := time.Now()
+		for i := 0; i < 20; i++ {
+			n, err := w.Write(record)
+			require.NoError(t, err)
+			assert.Equal(t, len(record), n)
+		}
+
+		// writing is never blocked by the server, even longer than write timeout
+		assert.Less(t, time.Since(start), time.Second)
+
+		require.NoError(t, w.Close())
+		mu.Lock()
+		assert.NotEmpty(t, errs)
+		mu.Unlock()
+
+		select {
+		case conn := <-accepted:
+			conn.Close()
+		default:
+		}
+	})
+
+	t.Run("unreachable server", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		address := ln.Addr().String()
+		require.NoError(t, ln.Close())
+
+		errs := make(chan error, 10)
+		log, err := newLogger(WithSyslogOutput(&OptionsSyslog{
+			Network:    "tcp",
+			Address:    address,
+			MaxRetries: -1,
+			OnError:    func(err error) { errs <- err },
+		}))
+		require.NoError(t, err)
+
+		start := time.Now()
+		for i := 0; i < 10; i++ {
+			log.Info(ctx, logMessage)
+		}
+
+		assert.Less(t, time.Since(start), time.Second)
+		require.NoError(t, log.Close())
+
+		select {
+		case err := <-errs:
+			assert.Contains(t, err.Error(), "syslog dial error")
+		default:
+			t.Fatal("dial error is not reported")
+		}
+	})
+}
+
+// assertSyslogStream write two log and read octet counted message from the first accepted connection
+func assertSyslogStream(t *testing.T, ln net.Listener, conf *OptionsSyslog) {
+	t.Helper()
+
+	messages := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		for i := 0; i < 2; i++ {
+			size, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+
+			n, _ := strconv.Atoi(strings.TrimSpace(size))
+			msg := make([]byte, n)
+			if _, err = io.ReadFull(r, msg); err != nil {
+				return
+			}
+
+			messages <- string(msg)
+		}
+	}()
+
+	conf.FlushInterval = 10 * time.Millisecond
+	log, err := newLogger(WithSyslogOutput(conf))
+	require.NoError(t, err)
+	defer log.Close()
+
+	log.Error(ctx, logMessage)
+	log.TDR(ctx, LogTdrModel{})
+
+	for _, prefix := range []string{"<11>1 ", "<14>1 "} {
+		select {
+		case msg := <-messages:
+			assert.True(t, strings.HasPrefix(msg, prefix), msg)
+			assert.Contains(t, msg, `[logger@32473 chainId="`)
+			assert.True(t, strings.HasSuffix(msg, "}"), msg)
+		case <-time.After(5 * time.Second):
+			t.Fatal("syslog message is not received")
+		}
+	}
+}
+
+// syslogTestCert returns self-signed certificate for 127.0.0.1 and its PEM file
+func syslogTestCert(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, caFile
+}
@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// TdrValidator is custom rule applied to TDR when validation enabled, see WithTdrValidation.
+type TdrValidator func(tdr LogTdrModel) error
+
+var tdrValidate = validator.New()
+
+// fillTdr set empty TDR identifier using value from logging context,
+// so TDR written by middleware or manually always has the same identifier as SYS log.
+func fillTdr(ctx context.Context, tdr LogTdrModel) LogTdrModel {
+	ctxVal := ExtractCtx(ctx)
+
+	if tdr.AppName == "" {
+		tdr.AppName = ctxVal.ServiceName
+	}
+
+	if tdr.AppVersion == "" {
+		tdr.AppVersion = ctxVal.ServiceVersion
+	}
+
+	if tdr.ThreadID == "" {
+		tdr.ThreadID = ctxVal.ThreadID
+	}
+
+	if tdr.JourneyID == "" {
+		tdr.JourneyID = ctxVal.JourneyID
+	}
+
+	if tdr.ChainID == "" {
+		tdr.ChainID = ctxVal.ChainID
+	}
+
+	if tdr.Port == 0 {
+		tdr.Port = ctxVal.ServicePort
+	}
+
+	return tdr
+}
+
+// validateTdr check required field using `validate` tag in LogTdrModel and then all custom validators.
+// All errors are joined, so one log line shows every problem of the record.
+func validateTdr(tdr LogTdrModel, validators ...TdrValidator) error {
+	errs := make([]string, 0)
+
+	if err := tdrValidate.Struct(tdr); err != nil {
+		if fieldErrs, ok := err.(validator.ValidationErrors); ok {
+			for _, fieldErr := range fieldErrs {
+				errs = append(errs, fmt.Sprintf("%s is %s", jsonName(reflect.TypeOf(tdr), fieldErr.StructField()), fieldErr.Tag()))
+			}
+		} else {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	for _, v := range validators {
+		if v == nil {
+			continue
+		}
+
+		if err := v(tdr); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) <= 0 {
+		return nil
+	}
+
+	return fmt.Errorf("invalid tdr: %s", strings.Join(errs, "; "))
+}
+
+func jsonName(t reflect.Type, fieldName string) string {
+	field, ok := t.FieldByName(fieldName)
+	if !ok {
+		return fieldName
+	}
+
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return fieldName
+	}
+
+	return name
+}
+
+// TdrJSONSchema returns JSON Schema (draft-07) of record written by Logger.TDR,
+// generated from Context, LogTdrModel and the fields added by this logger.
+// Downstream consumer can use it to parse TDR, see schema/tdr.schema.json.
+func TdrJSONSchema() ([]byte, error) {
+	properties := map[string]interface{}{
+		"xtime":         map[string]interface{}{"type": "string", "description": "log time, format 2006-01-02 15:04:05.999"},
+		"x":             map[string]interface{}{"type": "string", "const": separator},
+		"logType":       map[string]interface{}{"type": "string", "enum": []string{LogTypeTDR}},
+		"level":         map[string]interface{}{"type": "string"},
+		"message":       map[string]interface{}{"type": "string"},
+		"schemaVersion": map[string]interface{}{"type": "string", "const": TdrSchemaVersion},
+		"schemaError":   map[string]interface{}{"type": "string", "description": "validation error, only exist when validation enabled and failed"},
+	}
+
+	required := []string{"xtime", "logType", "level", "schemaVersion"}
+
+	for _, t := range []reflect.Type{reflect.TypeOf(Context{}), reflect.TypeOf(LogTdrModel{})} {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+
+			properties[name] = jsonSchemaType(field.Type)
+
+			if strings.Contains(field.Tag.Get("validate"), "required") {
+				required = append(required, name)
+			}
+		}
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"$id":         "https://github.com/armiariyan/logger/schema/tdr.schema.json",
+		"title":       "TDR",
+		"description": fmt.Sprintf("Transaction Data Record written by Logger.TDR, schema version %s", TdrSchemaVersion),
+		"type":        "object",
+		"properties":  properties,
+		"required":    required,
+	}, "", "  ")
+}
+
+func jsonSchemaType(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Map, reflect.Struct:
+		return map[string]interface{}{"type": "object"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array"}
+	}
+
+	// interface{} field is written as json object, array or string
+	return map[string]interface{}{}
+}
@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tdrRecord struct {
+	LogTdrModel
+	SchemaVersion string `json:"schemaVersion"`
+	SchemaError   string `json:"schemaError"`
+}
+
+func TestDefaultLoggerTDR_Schema(t *testing.T) {
+	t.Run("fill empty identifier from context", func(t *testing.T) {
+		writer := &testAssertionLogger{}
+		log, err := newLogger(WithCustomWriter(writer))
+		require.NoError(t, err)
+
+		log.TDR(ctx, LogTdrModel{
+			AppName: "from tdr",
+			Path:    "/path",
+		})
+
+		var record tdrRecord
+		require.NoError(t, json.Unmarshal(writer.GetActualData(), &record))
+		assert.EqualValues(t, TdrSchemaVersion, record.SchemaVersion)
+		assert.EqualValues(t, "from tdr", record.AppName)
+		assert.EqualValues(t, ctxValue.ServiceVersion, record.AppVersion)
+		assert.EqualValues(t, ctxValue.ThreadID, record.ThreadID)
+		assert.EqualValues(t, ctxValue.JourneyID, record.JourneyID)
+		assert.EqualValues(t, ctxValue.ChainID, record.ChainID)
+		assert.EqualValues(t, ctxValue.ServicePort, record.Port)
+		assert.Empty(t, record.SchemaError)
+	})
+
+	t.Run("validation disabled by default", func(t *testing.T) {
+		writer := &testAssertionLogger{}
+		log, err := newLogger(WithCustomWriter(writer))
+		require.NoError(t, err)
+
+		log.TDR(context.Background(), LogTdrModel{})
+
+		var record tdrRecord
+		require.NoError(t, json.Unmarshal(writer.GetActualData(), &record))
+		assert.Empty(t, record.SchemaError)
+	})
+
+	t.Run("invalid record still written with schema error", func(t *testing.T) {
+		writer := &testAssertionLogger{}
+		log, err := newLogger(
+			WithCustomWriter(writer),
+			WithTdrValidation(func(tdr LogTdrModel) error {
+				if tdr.RespTime < 0 {
+					return fmt.Errorf("rt must not negative")
+				}
+
+				return nil
+			}),
+		)
+		require.NoError(t, err)
+
+		log.TDR(context.Background(), LogTdrModel{AppName: "app", Path: "/path", RespTime: -1})
+
+		var record tdrRecord
+		require.NoError(t, json.Unmarshal(writer.GetActualData(), &record))
+		assert.EqualValues(t, "/path", record.Path)
+		assert.EqualValues(t, "invalid tdr: xid is required; rc is required; rt must not negative", record.SchemaError)
+	})
+
+	t.Run("valid record", func(t *testing.T) {
+		writer := &testAssertionLogger{}
+		log, err := newLogger(WithCustomWriter(writer), WithTdrValidation())
+		require.NoError(t, err)
+
+		log.TDR(ctx, LogTdrModel{Path: "/path", ResponseCode: "00"})
+
+		var record tdrRecord
+		require.NoError(t, json.Unmarshal(writer.GetActualData(), &record))
+		assert.Empty(t, record.SchemaError)
+	})
+}
+
+func TestTdrJSONSchema(t *testing.T) {
+	schema, err := TdrJSONSchema()
+	require.NoError(t, err)
+
+	t.Run("documented schema is up to date", func(t *testing.T) {
+		documented, err := os.ReadFile("schema/tdr.schema.json")
+		require.NoError(t, err)
+		assert.JSONEq(t, string(documented), string(schema), "run go generate to update schema/tdr.schema.json")
+	})
+
+	t.Run("all written field is described", func(t *testing.T) {
+		var parsed struct {
+			Properties map[string]interface{} `json:"properties"`
+			Required   []string               `json:"required"`
+		}
+		require.NoError(t, json.Unmarshal(schema, &parsed))
+
+		writer := &testAssertionLogger{}
+		log, err := newLogger(WithCustomWriter(writer))
+		require.NoError(t, err)
+		log.TDR(ctx, GenerateLogTDR(nil))
+
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(writer.GetActualData(), &record))
+
+		for key := range record {
+			assert.Contains(t, parsed.Properties, key)
+		}
+
+		for _, key := range parsed.Required {
+			assert.Contains(t, parsed.Properties, key)
+		}
+	})
+
+	t.Run("version is increased when field is changed", func(t *testing.T) {
+		// fields of each schema version, add the new version here when TdrSchemaVersion is increased
+		versions := map[string][]string{
+			"2": {
+				"_app_name string", "_app_version string", "_app_port int", "_app_thread_id string",
+				"_app_journey_id string", "_app_chain_id string", "_app_tag string", "_app_trace_id string",
+				"_app_span_id string", "_app_method string", "_app_uri string", "_app_data map[string]interface {}",
+				"app string", "ver string", "xid string", "jid string", "cid string", "path string",
+				"method string", "ip string", "port int", "srcIP string", "rt int64", "rc string",
+				"header interface {}", "req interface {}", "resp interface {}", "error string",
+				"addData interface {}", "direction string",
+			},
+		}
+
+		var fields []string
+		for _, typ := range []reflect.Type{reflect.TypeOf(Context{}), reflect.TypeOf(LogTdrModel{})} {
+			for i := 0; i < typ.NumField(); i++ {
+				name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+				if name != "" && name != "-" {
+					fields = append(fields, name+" "+typ.Field(i).Type.String())
+				}
+			}
+		}
+
+		assert.Equal(t, versions[TdrSchemaVersion], fields, "increase TdrSchemaVersion when field of Context or LogTdrModel is changed")
+	})
+}
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"strings"
+)
+
+const (
+	traceparentLen    = 55
+	defaultTraceFlags = "00"
+)
+
+// ParseTraceparent returns trace id, span id and trace flags of W3C traceparent header,
+// such as "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01". ok is false when it is malformed.
+func ParseTraceparent(header string) (traceID, spanID, flags string, ok bool) {
+	header = strings.TrimSpace(header)
+	if len(header) < traceparentLen || (len(header) > traceparentLen && header[traceparentLen] != '-') {
+		return "", "", "", false
+	}
+
+	version := header[0:2]
+	if !isLowerHex(version) || version == "ff" || (version == "00" && len(header) != traceparentLen) {
+		return "", "", "", false
+	}
+
+	if header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return "", "", "", false
+	}
+
+	traceID, spanID, flags = header[3:35], header[36:52], header[53:55]
+	if !isTraceID(traceID) || !isTraceID(spanID) || !isLowerHex(flags) {
+		return "", "", "", false
+	}
+
+	return traceID, spanID, flags, true
+}
+
+// FormatTraceparent returns W3C traceparent header of trace id and span id, flags is "00" when it is empty.
+// It returns empty string when trace id or span id is invalid, so nothing is propagated.
+func FormatTraceparent(traceID, spanID, flags string) string {
+	if flags == "" {
+		flags = defaultTraceFlags
+	}
+
+	if len(traceID) != 32 || len(spanID) != 16 || len(flags) != 2 ||
+		!isTraceID(traceID) || !isTraceID(spanID) || !isLowerHex(flags) {
+		return ""
+	}
+
+	return "00-" + traceID + "-" + spanID + "-" + flags
+}
+
+// isTraceID returns true when s is lower case hex and not all zero
+func isTraceID(s string) bool {
+	return isLowerHex(s) && strings.Trim(s, "0") != ""
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !(s[i] >= '0' && s[i] <= '9' || s[i] >= 'a' && s[i] <= 'f') {
+			return false
+		}
+	}
+
+	return s != ""
+}
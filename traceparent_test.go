@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceparent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	t.Run("valid", func(t *testing.T) {
+		gotTrace, gotSpan, flags, ok := ParseTraceparent("00-" + traceID + "-" + spanID + "-01")
+		assert.True(t, ok)
+		assert.Equal(t, traceID, gotTrace)
+		assert.Equal(t, spanID, gotSpan)
+		assert.Equal(t, "01", flags)
+
+		// future version may append field
+		_, _, _, ok = ParseTraceparent("01-" + traceID + "-" + spanID + "-01-extra")
+		assert.True(t, ok)
+
+		assert.Equal(t, "00-"+traceID+"-"+spanID+"-01", FormatTraceparent(traceID, spanID, "01"))
+		assert.Equal(t, "00-"+traceID+"-"+spanID+"-00", FormatTraceparent(traceID, spanID, ""))
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, header := range []string{
+			"",
+			"00-" + traceID + "-" + spanID,
+			"00-" + traceID + "-" + spanID + "-01-extra",
+			"ff-" + traceID + "-" + spanID + "-01",
+			"00-4BF92F3577B34DA6A3CE929D0E0E4736-" + spanID + "-01",
+			"00-00000000000000000000000000000000-" + spanID + "-01",
+			"00-" + traceID + "-0000000000000000-01",
+			"00_" + traceID + "_" + spanID + "_01",
+			"00-" + traceID + "-" + spanID + "-zz",
+		} {
+			_, _, _, ok := ParseTraceparent(header)
+			assert.False(t, ok, header)
+		}
+
+		assert.Empty(t, FormatTraceparent("", spanID, "01"))
+		assert.Empty(t, FormatTraceparent(traceID, "span", "01"))
+	})
+}
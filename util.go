@@ -1,8 +1,13 @@
 package logger
 
 import (
+	"encoding"
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"unsafe"
 )
 
 const (
@@ -16,6 +21,16 @@ const (
 	pinMask       = "******"
 	base64Mask    = "base64file exist"
 	sliceByteMask = "X@BQ1"
+
+	maskCycle      = "[cycle]"
+	maskMaxDepth   = "[max depth]"
+	maskMoreFormat = "[%d more]"
+	maskMoreKey    = "[more]"
+)
+
+var (
+	typeJSONMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	typeTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
 )
 
 func ToField(key string, val interface{}) (field Field) {
@@ -26,141 +41,565 @@ func ToField(key string, val interface{}) (field Field) {
 	return
 }
 
-func masking(data interface{}) interface{} {
-	original := reflect.ValueOf(data)
-	altered := reflect.New(original.Type()).Elem()
+// masking returns copy of data where value of field with `mask` tag is masked.
+// Struct is converted into maskedStruct using the same key and order as encoding/json, since masked value of
+// non-string field (such as int64 account number) is no longer the same type, so the written JSON is the same
+// as original except for masked value. Type implementing json.Marshaler or encoding.TextMarshaler is written
+// as is, its `mask` tag is ignored (see ValidateMaskTags). Original data is never modified.
+// Nil opts use the default options.
+func masking(data interface{}, opts *maskOptions) interface{} {
+	if opts == nil {
+		opts = &defaultMaskOptions
+	}
 
-	switch original.Kind() {
+	if opts.allowlist {
+		return (&maskWalker{opts: opts}).allowed(reflect.ValueOf(data))
+	}
+
+	return (&maskWalker{opts: opts}).value(reflect.ValueOf(data))
+}
+
+// maskWalker hold state of one masking call
+type maskWalker struct {
+	opts     *maskOptions
+	depth    int
+	visiting map[visit]struct{} // pointer, map and slice in current path, to detect cycle
+}
+
+type visit struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+// MaskString implements FieldMasker using options of the walker
+func (w *maskWalker) MaskString(strategy, value string) string {
+	return maskString(strategy, value, w.opts)
+}
+
+// MaskTagged implements FieldMasker using options of the walker
+func (w *maskWalker) MaskTagged(strategy string, value interface{}) interface{} {
+	return w.tagged(reflect.ValueOf(value), strategy)
+}
+
+// Mask implements FieldMasker using options of the walker
+func (w *maskWalker) Mask(value interface{}) interface{} {
+	return w.value(reflect.ValueOf(value))
+}
+
+// enter is called before walking container, it returns marker value when the container must not be walked
+func (w *maskWalker) enter() (marker interface{}, ok bool) {
+	if w.opts.maxDepth > 0 && w.depth >= w.opts.maxDepth {
+		return maskMaxDepth, false
+	}
+
+	w.depth++
+	return nil, true
+}
+
+func (w *maskWalker) leave() {
+	w.depth--
+}
+
+// visit mark reference v as being walked, false when it is already in current path
+func (w *maskWalker) visit(v reflect.Value) (visit, bool) {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+
+	if w.visiting == nil {
+		w.visiting = make(map[visit]struct{})
+	}
+
+	if _, ok := w.visiting[key]; ok {
+		return key, false
+	}
+
+	w.visiting[key] = struct{}{}
+	return key, true
+}
+
+// value mask value without `mask` tag, only its nested struct field may be masked
+func (w *maskWalker) value(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	if v.Type() == typeSafeValue {
+		return w.value(reflect.ValueOf(v.Interface().(SafeValue).value))
+	}
+
+	plan := maskPlans.plan(v.Type())
+	if plan.masker || plan.objectMasker {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return nil
+		}
+
+		// ObjectMasker wins over Masker, the same as formatLog
+		if plan.objectMasker {
+			return maskObject(v.Interface().(ObjectMasker))
+		}
+
+		if m, ok := v.Interface().(OptionsMasker); ok {
+			return m.LogMaskedWith(w)
+		}
+
+		return v.Interface().(Masker).LogMasked()
+	}
+
+	// type which encode itself is written as is, just like encoding/json does,
+	// and so does type which has nothing to be masked
+	if plan.marshaler || !plan.needsMask(w.opts.unexported) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v.Interface()
+		}
+
+		return w.value(v.Elem())
 	case reflect.Ptr:
-		// check if value is nil
-		if !isNil(original) {
-			elem := original.Elem()
-			switch elem.Kind() {
-			case reflect.Struct, reflect.Interface, reflect.Ptr:
-				altered.Set(masking(elem.Interface()).(reflect.Value).Addr())
-			case reflect.Slice:
-				altered = maskSlice(elem)
-			case reflect.Map:
-				altered = maskMap(elem)
-			default:
-				altered.Set(elem.Addr())
-			}
+		if v.IsNil() {
+			return v.Interface()
 		}
+
+		return w.reference(v, func() interface{} {
+			return w.value(v.Elem())
+		})
+	case reflect.Struct:
+		return w.container(v, func() interface{} {
+			return w.structFields(v, plan.fields)
+		})
+	case reflect.Map:
+		if v.IsNil() {
+			return v.Interface()
+		}
+
+		return w.reference(v, func() interface{} {
+			return w.mapValues(v, w.value)
+		})
+	case reflect.Slice:
+		// []byte is written as base64 string by encoding/json
+		if v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+
+		return w.reference(v, func() interface{} {
+			return w.elems(v, w.value)
+		})
+	case reflect.Array:
+		return w.container(v, func() interface{} {
+			return w.elems(v, w.value)
+		})
+	}
+
+	return v.Interface()
+}
+
+// tagged mask value of field with `mask` tag. Pointer, interface, slice, array and map value
+// are masked element by element, and number is masked as string.
+func (w *maskWalker) tagged(v reflect.Value, tag string) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return v.Interface()
+		}
+
+		return w.tagged(v.Elem(), tag)
+	case reflect.String:
+		return maskString(tag, v.String(), w.opts)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return maskString(tag, strconv.FormatInt(v.Int(), 10), w.opts)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return maskString(tag, strconv.FormatUint(v.Uint(), 10), w.opts)
+	case reflect.Float32, reflect.Float64:
+		return maskString(tag, strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), w.opts)
 	case reflect.Slice:
-		altered = maskSlice(original)
+		if v.IsNil() {
+			return v.Interface()
+		}
+
+		// []byte mostly used for byte file, always replaced regardless the tag value
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return []byte(sliceByteMask)
+		}
+
+		return w.reference(v, func() interface{} {
+			return w.elems(v, func(elem reflect.Value) interface{} {
+				return w.tagged(elem, tag)
+			})
+		})
+	case reflect.Array:
+		return w.container(v, func() interface{} {
+			return w.elems(v, func(elem reflect.Value) interface{} {
+				return w.tagged(elem, tag)
+			})
+		})
 	case reflect.Map:
-		altered = maskMap(original)
-	case reflect.Struct:
-		for i := 0; i < original.NumField(); i++ {
-			field := original.Field(i)
-			switch field.Kind() {
-			case reflect.Struct, reflect.Map, reflect.Interface, reflect.Slice, reflect.Ptr:
-				if altered.Field(i).CanSet() && !isNil(field) {
-
-					// []byte mostly used for byte file
-					if field.Type() == TypeSliceOfBytes {
-						if _, ok := original.Type().Field(i).Tag.Lookup(maskTag); ok {
-							if !original.Field(i).IsNil() {
-								altered.Field(i).SetBytes([]byte(sliceByteMask))
-							}
-						} else {
-							altered.Field(i).Set(original.Field(i))
-						}
-					} else {
-						altered.Field(i).Set(masking(field.Interface()).(reflect.Value))
-					}
-				}
-			default:
-				if val, ok := original.Type().Field(i).Tag.Lookup(maskTag); ok {
-					var value string
-					switch original.Field(i).Interface().(type) {
-					case string:
-						value = original.Field(i).Interface().(string)
-					}
-					if len(value) > 0 {
-						switch val {
-						case maskPIN:
-							value = pinMask
-						case maskName:
-							value = MaskingName(
-								SanitizeName(value),
-							)
-						case maskPhone:
-							value = MaskingPhoneNumber(
-								SanitizePhoneNumber(value),
-							)
-						case maskAny:
-							value = strings.Repeat("*", len(value))
-						case maskBase64:
-							value = base64Mask
-						case maskEmail:
-							value = MaskingEmail(value)
-						}
-
-						altered.Field(i).SetString(value)
-					} else {
-						altered.Field(i).Set(original.Field(i))
-					}
-				} else {
-					if altered.Field(i).CanSet() {
-						altered.Field(i).Set(original.Field(i))
-					} else {
-						switch original.Type() {
-						case TypeTime:
-							altered.Set(original)
-							i += 2
-						}
-					}
-				}
-			}
+		if v.IsNil() {
+			return v.Interface()
+		}
+
+		return w.reference(v, func() interface{} {
+			return w.mapValues(v, func(elem reflect.Value) interface{} {
+				return w.tagged(elem, tag)
+			})
+		})
+	}
+
+	// tag on struct or bool has no meaning, but its nested field still can be masked
+	return w.value(v)
+}
+
+// container walk struct, array or other container within depth limit
+func (w *maskWalker) container(v reflect.Value, walk func() interface{}) interface{} {
+	marker, ok := w.enter()
+	if !ok {
+		return marker
+	}
+
+	defer w.leave()
+	return walk()
+}
+
+// reference walk pointer, map or slice, which may refer to value being walked
+func (w *maskWalker) reference(v reflect.Value, walk func() interface{}) interface{} {
+	key, ok := w.visit(v)
+	if !ok {
+		return maskCycle
+	}
+
+	defer delete(w.visiting, key)
+
+	// pointer is not a container, so it does not count as depth
+	if v.Kind() == reflect.Ptr {
+		return walk()
+	}
+
+	return w.container(v, walk)
+}
+
+func (w *maskWalker) structFields(v reflect.Value, fields []jsonField) interface{} {
+	altered := make(maskedStruct, 0, len(fields))
+	include := w.opts.unexported == UnexportedInclude
+
+	for _, f := range fields {
+		if f.unexported && !include {
+			continue
+		}
+
+		// field which cannot be read using Interface can only be read through addressable value
+		if f.readOnly && !v.CanAddr() {
+			v = addressable(v)
+		}
+
+		field, ok := readField(v, f)
+		if !ok || (f.omitEmpty && isEmptyValue(field)) {
+			continue
+		}
+
+		switch {
+		case f.tagged:
+			altered = append(altered, maskedField{key: f.name, value: w.tagged(field, f.tag)})
+		case f.quoted:
+			altered = append(altered, maskedField{key: f.name, value: quoteValue(field)})
+		default:
+			altered = append(altered, maskedField{key: f.name, value: w.value(field)})
 		}
-	default:
-		altered.Set(original)
 	}
 
 	return altered
 }
 
-func maskSlice(elem reflect.Value) (altered reflect.Value) {
-	altered = reflect.MakeSlice(elem.Type(), elem.Len(), elem.Len())
-	for i := 0; i < elem.Len(); i++ {
-		value := elem.Index(i)
-		switch value.Kind() {
-		case reflect.Struct, reflect.Map, reflect.Interface, reflect.Slice, reflect.Ptr:
-			// check if value is nil
-			if !isNil(value) {
-				altered.Index(i).Set(masking(value.Interface()).(reflect.Value))
+func (w *maskWalker) elems(v reflect.Value, mask func(elem reflect.Value) interface{}) interface{} {
+	n := v.Len()
+	if w.opts.maxElems > 0 && n > w.opts.maxElems {
+		n = w.opts.maxElems
+	}
+
+	altered := make([]interface{}, n, n+1)
+	for i := 0; i < n; i++ {
+		altered[i] = mask(v.Index(i))
+	}
+
+	if n < v.Len() {
+		altered = append(altered, fmt.Sprintf(maskMoreFormat, v.Len()-n))
+	}
+
+	return altered
+}
+
+func (w *maskWalker) mapValues(v reflect.Value, mask func(elem reflect.Value) interface{}) interface{} {
+	altered := make(map[string]interface{}, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		if w.opts.maxElems > 0 && len(altered) >= w.opts.maxElems {
+			altered[maskMoreKey] = fmt.Sprintf(maskMoreFormat, v.Len()-len(altered))
+			break
+		}
+
+		key, ok := mapKey(iter.Key())
+		if !ok {
+			// key cannot be written as JSON object key, let encoding/json handle it
+			return v.Interface()
+		}
+
+		altered[key] = mask(iter.Value())
+	}
+
+	return altered
+}
+
+// mapKey convert map key the same way as encoding/json
+func mapKey(k reflect.Value) (string, bool) {
+	if k.Kind() == reflect.String {
+		return k.String(), true
+	}
+
+	if k.Type().Implements(typeTextMarshaler) {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", true
+		}
+
+		b, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err == nil
+	}
+
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), true
+	}
+
+	return "", false
+}
+
+func isMarshaler(t reflect.Type) bool {
+	return t.Implements(typeJSONMarshaler) || t.Implements(typeTextMarshaler)
+}
+
+// jsonField is struct field written by encoding/json
+type jsonField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	quoted    bool // `json:",string"` option
+	tag       string
+	tagged    bool
+	safe      bool // `log:"safe"` tag, see WithAllowlistMode
+
+	// unexported field is never written by encoding/json, only when UnexportedInclude
+	unexported bool
+	// unexported field or field promoted from unexported embedded struct, it cannot be read using Interface
+	readOnly bool
+}
+
+// jsonFields returns fields of struct type written by encoding/json, including promoted field of
+// embedded struct. When the same name exist in multiple depth, the shallowest one wins.
+func jsonFields(t reflect.Type) []jsonField {
+	fields := make([]jsonField, 0, t.NumField())
+	depths := make(map[string]int)
+	collectJSONFields(t, nil, false, depths, &fields, make(map[reflect.Type]bool))
+
+	visible := make([]jsonField, 0, len(fields))
+	for _, f := range fields {
+		if !f.unexported && depths[f.name] == len(f.index) {
+			visible = append(visible, f)
+			depths[f.name] = -1 // only the first field in the shallowest depth
+		}
+	}
+
+	// unexported field never hide exported one
+	for _, f := range fields {
+		if _, ok := depths[f.name]; f.unexported && !ok {
+			visible = append(visible, f)
+			depths[f.name] = -1
+		}
+	}
+
+	return visible
+}
+
+func collectJSONFields(t reflect.Type, index []int, readOnly bool, depths map[string]int, fields *[]jsonField, visited map[reflect.Type]bool) {
+	if visited[t] {
+		return
+	}
+
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		jsonTag := sf.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(jsonTag, ",")
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		// embedded struct without json name is promoted, even if the struct type is unexported
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			collectJSONFields(ft, fieldIndex, readOnly || !sf.IsExported(), depths, fields, visited)
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		if sf.IsExported() {
+			if d, ok := depths[name]; !ok || len(fieldIndex) < d {
+				depths[name] = len(fieldIndex)
 			}
-		default:
-			altered.Index(i).Set(value)
 		}
+
+		tag, tagged := sf.Tag.Lookup(maskTag)
+		*fields = append(*fields, jsonField{
+			name:       name,
+			index:      fieldIndex,
+			omitEmpty:  hasOption(opts, "omitempty"),
+			quoted:     hasOption(opts, "string") && isQuotable(sf.Type.Kind()),
+			tag:        tag,
+			tagged:     tagged,
+			safe:       sf.Tag.Get(logTag) == logSafe,
+			unexported: !sf.IsExported(),
+			readOnly:   readOnly || !sf.IsExported(),
+		})
 	}
+}
 
-	return
+func hasOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isQuotable(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// quoteValue write scalar as JSON string, the same as `json:",string"` option
+func quoteValue(v reflect.Value) interface{} {
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return v.Interface()
+	}
+
+	return string(b)
+}
+
+func addressable(v reflect.Value) reflect.Value {
+	a := reflect.New(v.Type()).Elem()
+	a.Set(v)
+	return a
+}
+
+// readField returns value of field f of struct v, false when one of embedded pointer is nil.
+// Field which cannot be read using Interface is read through addressable value.
+func readField(v reflect.Value, f jsonField) (reflect.Value, bool) {
+	if f.readOnly && !v.CanAddr() {
+		v = addressable(v)
+	}
+
+	field, ok := fieldByIndex(v, f.index)
+	if !ok || !f.readOnly {
+		return field, ok
+	}
+
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem(), true
 }
 
-func maskMap(elem reflect.Value) (altered reflect.Value) {
-	altered = reflect.MakeMapWithSize(elem.Type(), len(elem.MapKeys()))
-	mapRange := elem.MapRange()
-	for mapRange.Next() {
-		switch mapRange.Value().Kind() {
-		case reflect.Struct, reflect.Map, reflect.Interface, reflect.Slice, reflect.Ptr:
-			// check if value is nil
-			if !isNil(mapRange.Value()) {
-				altered.SetMapIndex(
-					mapRange.Key(),
-					masking(mapRange.Value().Interface()).(reflect.Value),
-				)
+// fieldByIndex returns nested field, false when one of embedded pointer is nil
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
 			}
-		default:
-			altered.SetMapIndex(mapRange.Key(), mapRange.Value())
+
+			v = v.Elem()
 		}
+
+		v = v.Field(x)
 	}
 
-	return
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+
+	return false
 }
 
-func isNil(elem reflect.Value) bool {
-	return elem.Interface() == nil ||
-		(reflect.ValueOf(elem.Interface()).Kind() == reflect.Ptr && reflect.ValueOf(elem.Interface()).IsNil())
+// maskString mask value using masker of `mask` tag value (see RegisterMasker),
+// value of unknown tag is passed to opts.unknown, or returned as is when it is nil
+func maskString(strategy, value string, opts *maskOptions) string {
+	if len(value) <= 0 {
+		return value
+	}
+
+	if opts == nil {
+		opts = &defaultMaskOptions
+	}
+
+	if opts.tokenizer.replaces(strategy) {
+		return MaskToken(opts.tokenizer.secret, strategy, value)
+	}
+
+	// token without secret is just a plain hash which can be brute forced, so it is fully masked
+	if isTokenStrategy(strategy) {
+		return maskString(maskAny, value, nil)
+	}
+
+	if fn, ok := lookupMasker(strategy); ok {
+		return fn(value)
+	}
+
+	if opts.unknown != nil {
+		return opts.unknown(strategy, value)
+	}
+
+	return value
 }
@@ -1,5 +1,17 @@
 package logger
 
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
 type Object struct {
 	FirstName   string `json:"firstName"`
 	LastName    string `json:"lastName"`
@@ -23,3 +35,411 @@ type Main struct {
 	Float              float64            `json:"float64"`
 	Boolean            bool               `json:"boolean"`
 }
+
+type Tagged struct {
+	Account    int64             `json:"account"    mask:"keep=last4"`
+	Amount     float64           `json:"amount"     mask:"any"`
+	Pin        *string           `json:"pin"        mask:"pin"`
+	NilPin     *string           `json:"nilPin"     mask:"pin"`
+	Emails     []string          `json:"emails"     mask:"email"`
+	Phones     [2]string         `json:"phones"     mask:"phone"`
+	Secrets    map[string]string `json:"secrets"    mask:"any"`
+	Any        interface{}       `json:"any"        mask:"any"`
+	File       []byte            `json:"file"       mask:"base64"`
+	Nested     interface{}       `json:"nested"`
+	Time       time.Time         `json:"time"`
+	Skipped    string            `json:"-"`
+	Empty      string            `json:"empty,omitempty" mask:"any"`
+	Quoted     int               `json:"quoted,string"`
+	unexported string
+}
+
+func TestMasking(t *testing.T) {
+	pin := "123456"
+	createdAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	type testCase struct {
+		name     string
+		input    interface{}
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "nil",
+			input:    nil,
+			expected: `null`,
+		},
+		{
+			name:     "nil pointer",
+			input:    (*Object)(nil),
+			expected: `null`,
+		},
+		{
+			name: "embedded struct, map and slice",
+			input: Main{
+				Object:             Object{FirstName: "Johnny", PIN: "123456", PhoneNumber: "6281297191466"},
+				MapString:          map[string]string{"a": "b"},
+				MapInteger:         map[int]int{1: 2},
+				MapObject:          map[string]Object{"o": {PIN: "1234"}},
+				MapObjectPointer:   map[string]*Object{"o": {Address: "street"}, "nil": nil},
+				SliceString:        []string{"a"},
+				SliceObject:        []Object{{PIN: "1234"}},
+				SliceObjectPointer: []*Object{{FullName: "Johnny Depp"}, nil},
+				Integer:            1,
+				Float:              1.5,
+				Boolean:            true,
+			},
+			expected: `{
+				"firstName":"Johnny","lastName":"","pin":"******","fullName":"","phoneNumber":"XXXXXXXXX1466","address":"",
+				"mapString":{"a":"b"},
+				"mapInt":{"1":2},
+				"mapObject":{"o":{"firstName":"","lastName":"","pin":"******","fullName":"","phoneNumber":"","address":""}},
+				"mapObjectPointer":{"o":{"firstName":"","lastName":"","pin":"","fullName":"","phoneNumber":"","address":"******"},"nil":null},
+				"sliceString":["a"],
+				"sliceInteger":null,
+				"sliceObject":[{"firstName":"","lastName":"","pin":"******","fullName":"","phoneNumber":"","address":""}],
+				"sliceObjectPointer":[{"firstName":"","lastName":"","pin":"","fullName":"Jo***y De**","phoneNumber":"","address":""},null],
+				"integer":1,
+				"float64":1.5,
+				"boolean":true
+			}`,
+		},
+		{
+			name: "non string tagged field",
+			input: &Tagged{
+				Account:    1234567890,
+				Amount:     10.5,
+				Pin:        &pin,
+				Emails:     []string{"nur@gmail.com", "matt@gmail.com"},
+				Phones:     [2]string{"081297191466", ""},
+				Secrets:    map[string]string{"key": "value"},
+				Any:        []interface{}{"abc", 123},
+				File:       []byte("file"),
+				Nested:     &Object{PIN: "1234"},
+				Time:       createdAt,
+				Skipped:    "skipped",
+				Quoted:     10,
+				unexported: "unexported",
+			},
+			expected: `{
+				"account":"******7890",
+				"amount":"****",
+				"pin":"******",
+				"nilPin":null,
+				"emails":["n**@gm******m","ma**@gm******m"],
+				"phones":["XXXXXXXXX1466",""],
+				"secrets":{"key":"*****"},
+				"any":["***","***"],
+				"file":"WEBCUTE=",
+				"nested":{"firstName":"","lastName":"","pin":"******","fullName":"","phoneNumber":"","address":""},
+				"time":"2023-01-02T03:04:05Z",
+				"quoted":"10"
+			}`,
+		},
+		{
+			name:     "map of struct",
+			input:    map[string]Object{"o": {PIN: "1234"}},
+			expected: `{"o":{"firstName":"","lastName":"","pin":"******","fullName":"","phoneNumber":"","address":""}}`,
+		},
+		{
+			name:     "array of struct",
+			input:    [1]Object{{Address: "abc"}},
+			expected: `[{"firstName":"","lastName":"","pin":"","fullName":"","phoneNumber":"","address":"***"}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := json.Marshal(masking(tc.input, nil))
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expected, string(actual))
+		})
+	}
+
+	t.Run("same json as original when nothing is masked", func(t *testing.T) {
+		input := Main{
+			Object:      Object{FirstName: "Johnny"},
+			MapInteger:  map[int]int{1: 2},
+			SliceString: []string{"a"},
+		}
+
+		expected, err := json.Marshal(input)
+		require.NoError(t, err)
+
+		actual, err := json.Marshal(masking(input, nil))
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), string(actual))
+	})
+
+	t.Run("original is not modified", func(t *testing.T) {
+		input := &Tagged{Pin: &pin, Emails: []string{"nur@gmail.com"}}
+		masking(input, nil)
+		assert.Equal(t, "123456", *input.Pin)
+		assert.Equal(t, []string{"nur@gmail.com"}, input.Emails)
+	})
+}
+
+func FuzzMasking(f *testing.F) {
+	f.Add("123456", int64(1234567890), 1.5, true, "keep=last4")
+	f.Add("", int64(0), 0.0, false, "")
+	f.Add("nur@gmail.com", int64(-1), -0.1, true, "email")
+	f.Add("Johnny Depp", int64(9), 1e300, false, "keep=first100,last100")
+
+	f.Fuzz(func(t *testing.T, s string, n int64, fl float64, b bool, tag string) {
+		values := []interface{}{
+			s, n, fl, b, &s, []string{s}, map[string]interface{}{s: n},
+			[]interface{}{s, n, nil}, Object{PIN: s, FullName: s, PhoneNumber: s},
+			&Tagged{Pin: &s, Account: n, Amount: fl, Emails: []string{s}, Any: map[string]interface{}{s: []interface{}{s, b}}, Nested: &Object{Address: s}},
+			Main{MapObjectPointer: map[string]*Object{s: nil}, SliceObjectPointer: []*Object{nil, {PIN: s}}},
+		}
+
+		for _, v := range values {
+			masked := masking(v, nil)
+			if _, err := json.Marshal(masked); err != nil && !isUnsupportedFloat(fl) {
+				t.Fatalf("masked value of %T cannot be marshalled: %v", v, err)
+			}
+
+			(&maskWalker{opts: &defaultMaskOptions}).tagged(reflect.ValueOf(v), tag)
+			(&maskWalker{opts: &maskOptions{unknown: func(tag, value string) string { return value }}}).tagged(reflect.ValueOf(v), tag)
+		}
+	})
+}
+
+func isUnsupportedFloat(f float64) bool {
+	_, err := json.Marshal(f)
+	return err != nil
+}
+
+type cyclicNode struct {
+	Pin      string                 `json:"pin" mask:"pin"`
+	Next     *cyclicNode            `json:"next"`
+	Children []*cyclicNode          `json:"children,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+type embeddedSecret struct {
+	Pin  string `json:"pin" mask:"pin"`
+	Note string `json:"note"`
+}
+
+type withUnexported struct {
+	embeddedSecret
+	Name    string `json:"name"`
+	secret  string `mask:"any"`
+	counter int
+	items   []Object
+}
+
+func TestMasking_Graph(t *testing.T) {
+	t.Run("cycle", func(t *testing.T) {
+		node := &cyclicNode{Pin: "1234"}
+		node.Next = node
+		node.Data = map[string]interface{}{}
+		node.Data["self"] = node.Data
+
+		actual, err := json.Marshal(masking(node, nil))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"pin":"******","next":"[cycle]","data":{"self":"[cycle]"}}`, string(actual))
+	})
+
+	t.Run("shared value is not a cycle", func(t *testing.T) {
+		shared := &cyclicNode{Pin: "1234"}
+		node := &cyclicNode{Next: shared, Children: []*cyclicNode{shared, shared}}
+
+		actual, err := json.Marshal(masking(node, nil))
+		require.NoError(t, err)
+
+		sharedJSON := `{"pin":"******","next":null}`
+		assert.JSONEq(t, `{"pin":"","next":`+sharedJSON+`,"children":[`+sharedJSON+`,`+sharedJSON+`]}`, string(actual))
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		node := &cyclicNode{Pin: "1", Next: &cyclicNode{Pin: "2", Next: &cyclicNode{Pin: "3"}}}
+
+		actual, err := json.Marshal(masking(node, &maskOptions{maxDepth: 2}))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"pin":"******","next":{"pin":"******","next":"[max depth]"}}`, string(actual))
+	})
+
+	t.Run("max elements", func(t *testing.T) {
+		input := map[string]interface{}{
+			"slice": []Object{{PIN: "1"}, {PIN: "2"}, {PIN: "3"}},
+			"map":   map[string]Object{"a": {}, "b": {}, "c": {}},
+		}
+
+		masked := masking(input, &maskOptions{maxElems: 2}).(map[string]interface{})
+
+		slice := masked["slice"].([]interface{})
+		assert.Len(t, slice, 3)
+		assert.Equal(t, "[1 more]", slice[2])
+
+		m := masked["map"].(map[string]interface{})
+		assert.Len(t, m, 3)
+		assert.Equal(t, "[1 more]", m[maskMoreKey])
+	})
+
+	t.Run("unexported field", func(t *testing.T) {
+		input := withUnexported{
+			embeddedSecret: embeddedSecret{Pin: "1234", Note: "note"},
+			Name:           "name",
+			secret:         "secret",
+			counter:        10,
+			items:          []Object{{PIN: "1234"}},
+		}
+
+		omitted, err := json.Marshal(masking(input, &maskOptions{unexported: UnexportedOmit}))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"pin":"******","note":"note","name":"name"}`, string(omitted))
+
+		included, err := json.Marshal(masking(input, &maskOptions{unexported: UnexportedInclude}))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"pin":"******","note":"note","name":"name","secret":"******","counter":10,
+			"items":[{"firstName":"","lastName":"","pin":"******","fullName":"","phoneNumber":"","address":""}]
+		}`, string(included))
+	})
+
+	t.Run("logger option", func(t *testing.T) {
+		writer := &testAssertionLogger{}
+		log, err := newLogger(
+			WithCustomWriter(writer),
+			MaskEnabled(),
+			WithUnexportedFields(UnexportedInclude),
+			WithMaskLimits(1, 1),
+		)
+		require.NoError(t, err)
+
+		log.Info(ctx, logMessage, ToField("data", withUnexported{counter: 1, items: []Object{{}, {}}}))
+
+		var record map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(writer.GetActualData(), &record))
+		assert.JSONEq(t, `{"pin":"","note":"","name":"","secret":"","counter":1,"items":"[max depth]"}`, string(record["data"]))
+
+		_, err = newLogger(WithMaskLimits(-1, 0))
+		assert.Error(t, err)
+	})
+}
+
+// propertyValue is generated randomly by testing/quick
+type propertyValue struct {
+	Pin      string            `json:"pin"      mask:"pin"`
+	Account  int64             `json:"account"  mask:"keep=last4"`
+	Emails   []string          `json:"emails"   mask:"email"`
+	Name     string            `json:"name"`
+	Numbers  []int             `json:"numbers"`
+	Labels   map[string]string `json:"labels"`
+	Object   *Object           `json:"object"`
+	Objects  []Object          `json:"objects"`
+	Optional *string           `json:"optional,omitempty" mask:"any"`
+	Array    [2]Object         `json:"array"`
+}
+
+// jsonShape replace every JSON scalar with "scalar", so masked and original value can be compared
+func jsonShape(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		shape := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			shape[k] = jsonShape(v)
+		}
+
+		return shape
+	case []interface{}:
+		shape := make([]interface{}, len(val))
+		for i, v := range val {
+			shape[i] = jsonShape(v)
+		}
+
+		return shape
+	case nil:
+		return nil
+	}
+
+	return "scalar"
+}
+
+func TestMasking_FieldOrder(t *testing.T) {
+	input := Object{FirstName: "John", LastName: "Doe", PIN: "123456", PhoneNumber: "081234567890"}
+
+	expected := `{"firstName":"John","lastName":"Doe","pin":"******","fullName":"","phoneNumber":"XXXXXXXXX7890","address":""}`
+	actual, err := json.Marshal(masking(input, nil))
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(actual))
+
+	allowed, err := json.Marshal(masking(Safe(input), &maskOptions{allowlist: true}))
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(allowed))
+
+	t.Run("html is not escaped", func(t *testing.T) {
+		// the same as zap reflected field encoder
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+
+		require.NoError(t, enc.Encode(masking(Object{FirstName: "<a&b>"}, nil)))
+		assert.Contains(t, buf.String(), `"firstName":"<a&b>"`)
+	})
+}
+
+func TestMasking_Property(t *testing.T) {
+	toJSON := func(v interface{}) (interface{}, error) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		var data interface{}
+		err = json.Unmarshal(b, &data)
+		return data, err
+	}
+
+	t.Run("masked value has the same shape as original", func(t *testing.T) {
+		property := func(v propertyValue) bool {
+			original, err := toJSON(v)
+			if err != nil {
+				return false
+			}
+
+			masked, err := toJSON(masking(v, nil))
+			if err != nil {
+				return false
+			}
+
+			return reflect.DeepEqual(jsonShape(original), jsonShape(masked))
+		}
+
+		require.NoError(t, quick.Check(property, nil))
+	})
+
+	t.Run("masking is idempotent", func(t *testing.T) {
+		property := func(v propertyValue) bool {
+			once, err := toJSON(masking(v, nil))
+			if err != nil {
+				return false
+			}
+
+			twice, err := toJSON(masking(masking(v, nil), nil))
+			return err == nil && reflect.DeepEqual(once, twice)
+		}
+
+		require.NoError(t, quick.Check(property, nil))
+	})
+
+	t.Run("value without mask tag is written as is", func(t *testing.T) {
+		property := func(v Main) bool {
+			v.Object = Object{FirstName: v.FirstName}
+			v.MapObject, v.MapObjectPointer, v.SliceObject, v.SliceObjectPointer = nil, nil, nil, nil
+
+			original, err := toJSON(v)
+			if err != nil {
+				return false
+			}
+
+			masked, err := toJSON(masking(v, nil))
+			return err == nil && reflect.DeepEqual(original, masked)
+		}
+
+		require.NoError(t, quick.Check(property, nil))
+	})
+}